@@ -0,0 +1,150 @@
+package alert
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hurisheng/go-futu-api/pb/qotcommon"
+	"github.com/hurisheng/go-futu-api/pb/qotsetpricereminder"
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+)
+
+func TestParse(t *testing.T) {
+	rules, err := Read(strings.NewReader(`# rules
+ma20: price cross_above ma(day,20) cooldown 30m on HK.00700,HK.09988
+spike: volume(1m) > 3 * avg_volume(1m, 20)
+drop: change_rate < -5 hysteresis 1
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 3 {
+		t.Fatalf("rules %v", rules)
+	}
+	r := rules[0]
+	if r.Name != "ma20" || r.Left != Price || r.Op != CrossAbove || r.Cooldown != 30*time.Minute || len(r.Securities) != 2 ||
+		r.Right != (Indicator{KLType: qotcommon.KLType_KLType_Day, Kind: MA, N: 20}) {
+		t.Errorf("rule %+v", r)
+	}
+	if s, ok := rules[1].Right.(Scaled); !ok || s.Factor != 3 || rules[1].Left != (KLField{KLType: qotcommon.KLType_KLType_1Min, Field: KLVolume}) {
+		t.Errorf("rule %+v", rules[1])
+	}
+	if typ, v, ok := rules[2].Reminder(); !ok || typ != qotcommon.PriceReminderType_PriceReminderType_ChangeRateDown || v != 5 {
+		t.Errorf("reminder %v %v %v", typ, v, ok)
+	}
+	if _, err := ParseRule("bad: price >> 1"); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestEngine(t *testing.T) {
+	sec := &futuapi.Security{Market: qotcommon.QotMarket_QotMarket_HK_Security, Code: "00700"}
+	now := time.Date(2024, 3, 20, 2, 0, 0, 0, time.UTC)
+	ch := make(chan *Alert, 10)
+	e := New(&Options{Sinks: []Sink{ChanSink(ch)}})
+	e.now = func() time.Time { return now }
+	cross, _ := ParseRule("cross: price cross_above 100 hysteresis 1 cooldown 10m")
+	spike, _ := ParseRule("spike: volume(1m) > 3 * avg_volume(1m,2)")
+	e.AddRule(cross, spike)
+
+	price := func(p float64) int {
+		now = now.Add(time.Minute)
+		return len(e.UpdateBasicQot([]*futuapi.BasicQot{{Security: sec, CurPrice: p}}))
+	}
+	// 第一次数据已在上方不算穿越；回到 99 以下后重新上穿才提醒，在 99 到 100 之间来回不重复提醒
+	for i, c := range []struct {
+		price float64
+		n     int
+	}{{101, 0}, {98, 0}, {101, 1}, {99.5, 0}, {100.5, 0}, {98.5, 0}, {101, 0}} {
+		if n := price(c.price); n != c.n {
+			t.Errorf("step %d price %v: %d alerts, want %d", i, c.price, n, c.n)
+		}
+	}
+	// 冷却时间过后再次上穿
+	now = now.Add(10 * time.Minute)
+	price(98)
+	if n := price(101); n != 1 {
+		t.Errorf("after cooldown %d alerts", n)
+	}
+
+	kl := func(tm string, vol int64) int {
+		return len(e.UpdateKLine(&futuapi.RTKLine{Security: sec, KLType: qotcommon.KLType_KLType_1Min,
+			KLines: []*futuapi.KLine{{Time: tm, ClosePrice: 100, Volume: vol}}}))
+	}
+	kl("2024-03-20 10:01:00", 100)
+	kl("2024-03-20 10:02:00", 100)
+	if n := kl("2024-03-20 10:03:00", 200); n != 0 {
+		t.Errorf("no spike: %d alerts", n)
+	}
+	// 同一根 K 线更新后成交量超过前两根均量的3倍
+	if n := kl("2024-03-20 10:03:00", 400); n != 1 {
+		t.Errorf("spike: %d alerts", n)
+	}
+	if len(ch) != 3 {
+		t.Errorf("sink received %d alerts", len(ch))
+	}
+}
+
+type fakeReminders struct {
+	items map[futuapi.Security][]*futuapi.PriceReminderItem
+	next  int64
+	ops   []qotsetpricereminder.SetPriceReminderOp
+}
+
+func (f *fakeReminders) GetPriceReminder(ctx context.Context, security *futuapi.Security, market qotcommon.QotMarket) ([]*futuapi.PriceReminder, error) {
+	return []*futuapi.PriceReminder{{Security: security, ItemList: f.items[*security]}}, nil
+}
+
+func (f *fakeReminders) SetPriceReminder(ctx context.Context, security *futuapi.Security, op qotsetpricereminder.SetPriceReminderOp,
+	key int64, remindType qotcommon.PriceReminderType, freq qotcommon.PriceReminderFreq, value float64, note string) (int64, error) {
+	f.ops = append(f.ops, op)
+	if op == qotsetpricereminder.SetPriceReminderOp_SetPriceReminderOp_Modify {
+		for _, item := range f.items[*security] {
+			if item.Key == key {
+				item.Value, item.Freq = value, freq
+			}
+		}
+		return key, nil
+	}
+	f.next++
+	f.items[*security] = append(f.items[*security], &futuapi.PriceReminderItem{Key: f.next, ItemType: remindType, Value: value, Note: note, Freq: freq})
+	return f.next, nil
+}
+
+func TestMirror(t *testing.T) {
+	sec := futuapi.Security{Market: qotcommon.QotMarket_QotMarket_HK_Security, Code: "00700"}
+	api := &fakeReminders{items: map[futuapi.Security][]*futuapi.PriceReminderItem{
+		// 手动设置的提醒不受影响
+		sec: {{Key: 100, ItemType: qotcommon.PriceReminderType_PriceReminderType_PriceUp, Value: 600, Note: "manual"}},
+	}}
+	freq := qotcommon.PriceReminderFreq_PriceReminderFreq_OnlyOnce
+	add, modify := qotsetpricereminder.SetPriceReminderOp_SetPriceReminderOp_Add, qotsetpricereminder.SetPriceReminderOp_SetPriceReminderOp_Modify
+	e := New(nil)
+	up, _ := ParseRule("up: price > 500 on HK.00700")
+	down, _ := ParseRule("down: price < 300 on HK.00700")
+	e.AddRule(up, down)
+	for i, want := range [][]qotsetpricereminder.SetPriceReminderOp{{add, add}, nil} {
+		api.ops = nil
+		list, err := e.mirror(context.Background(), api, freq)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 2 || list[0].Key != 1 || list[1].Key != 2 || len(api.ops) != len(want) || len(api.items[sec]) != 3 {
+			t.Errorf("call %d: reminders %+v, ops %v, server %d items", i, list, api.ops, len(api.items[sec]))
+		}
+	}
+	// 规则参数变化时修改之前同步的提醒
+	e.RemoveRule("up")
+	up, _ = ParseRule("up: price > 550 on HK.00700")
+	e.AddRule(up)
+	api.ops = nil
+	list, err := e.mirror(context.Background(), api, freq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || len(api.ops) != 1 || api.ops[0] != modify || len(api.items[sec]) != 3 || api.items[sec][1].Value != 550 {
+		t.Errorf("after change reminders %+v, ops %v", list, api.ops)
+	}
+}
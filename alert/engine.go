@@ -0,0 +1,388 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/hurisheng/go-futu-api/pb/qotcommon"
+	"github.com/hurisheng/go-futu-api/pb/qotsetpricereminder"
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+)
+
+// 提醒的输出
+type Sink interface {
+	Send(a *Alert) error
+}
+
+// 用函数作为输出
+type SinkFunc func(a *Alert) error
+
+func (f SinkFunc) Send(a *Alert) error {
+	return f(a)
+}
+
+type writerSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// 把提醒逐行写入 w，如日志文件或标准输出
+func WriterSink(w io.Writer) Sink {
+	return &writerSink{w: w}
+}
+
+func (s *writerSink) Send(a *Alert) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := fmt.Fprintln(s.w, a)
+	return err
+}
+
+// 把提醒发送到通道，通道满时丢弃
+type ChanSink chan<- *Alert
+
+func (ch ChanSink) Send(a *Alert) error {
+	select {
+	case ch <- a:
+	default:
+	}
+	return nil
+}
+
+// 引擎参数
+type Options struct {
+	Sinks   []Sink
+	OnError func(err error) //输出出错时调用，为空时忽略错误
+}
+
+type ruleKey struct {
+	rule *Rule
+	sec  futuapi.Security
+}
+
+// 提醒引擎，可同时处理多只证券
+type Engine struct {
+	mu      sync.Mutex
+	rules   []*Rule
+	inds    map[Indicator]bool
+	states  map[futuapi.Security]*State
+	rs      map[ruleKey]*ruleState
+	sinks   []Sink
+	onError func(err error)
+	now     func() time.Time
+}
+
+// 创建提醒引擎
+func New(opts *Options) *Engine {
+	e := &Engine{
+		inds:   make(map[Indicator]bool),
+		states: make(map[futuapi.Security]*State),
+		rs:     make(map[ruleKey]*ruleState),
+		now:    time.Now,
+	}
+	if opts != nil {
+		e.sinks = append(e.sinks, opts.Sinks...)
+		e.onError = opts.OnError
+	}
+	return e
+}
+
+// 添加规则。规则用到的指标从之后到达的 K 线开始计算，因此应在处理 K 线前添加规则
+func (e *Engine) AddRule(rules ...*Rule) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, r := range rules {
+		e.rules = append(e.rules, r)
+		for _, ind := range append(indicators(r.Left), indicators(r.Right)...) {
+			if e.inds[ind] {
+				continue
+			}
+			e.inds[ind] = true
+			for _, st := range e.states {
+				st.inds[ind] = ind.stream()
+			}
+		}
+	}
+}
+
+// 删除规则
+func (e *Engine) RemoveRule(name string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	rules := e.rules[:0]
+	for _, r := range e.rules {
+		if r.Name == name {
+			for k := range e.rs {
+				if k.rule == r {
+					delete(e.rs, k)
+				}
+			}
+			continue
+		}
+		rules = append(rules, r)
+	}
+	e.rules = rules
+}
+
+// 已添加的规则
+func (e *Engine) Rules() []*Rule {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]*Rule(nil), e.rules...)
+}
+
+// 添加输出
+func (e *Engine) AddSink(s Sink) {
+	e.mu.Lock()
+	e.sinks = append(e.sinks, s)
+	e.mu.Unlock()
+}
+
+// 证券的状态，不存在时创建，调用时需要持有锁
+func (e *Engine) state(sec *futuapi.Security) *State {
+	st := e.states[*sec]
+	if st == nil {
+		st = newState(sec)
+		for ind := range e.inds {
+			st.inds[ind] = ind.stream()
+		}
+		e.states[*sec] = st
+	}
+	return st
+}
+
+// 处理报价
+func (e *Engine) UpdateBasicQot(qots []*futuapi.BasicQot) []*Alert {
+	var alerts []*Alert
+	for _, q := range qots {
+		if q == nil || q.Security == nil {
+			continue
+		}
+		e.mu.Lock()
+		st := e.state(q.Security)
+		st.Qot = q
+		alerts = append(alerts, e.evaluate(st)...)
+		e.mu.Unlock()
+	}
+	e.deliver(alerts)
+	return alerts
+}
+
+// 处理 K 线，同一时间的 K 线重复推送时替换最后一根
+func (e *Engine) UpdateKLine(rt *futuapi.RTKLine) []*Alert {
+	if rt == nil || rt.Security == nil {
+		return nil
+	}
+	e.mu.Lock()
+	st := e.state(rt.Security)
+	for _, k := range rt.KLines {
+		if k != nil && !k.IsBlank {
+			st.addKLine(rt.KLType, k)
+		}
+	}
+	alerts := e.evaluate(st)
+	e.mu.Unlock()
+	e.deliver(alerts)
+	return alerts
+}
+
+// 处理逐笔，只保留最新一笔
+func (e *Engine) UpdateTicker(rt *futuapi.RTTicker) []*Alert {
+	if rt == nil || rt.Security == nil || len(rt.Tickers) == 0 {
+		return nil
+	}
+	e.mu.Lock()
+	st := e.state(rt.Security)
+	for _, t := range rt.Tickers {
+		if t != nil && (st.Ticker == nil || t.Sequence >= st.Ticker.Sequence) {
+			st.Ticker = t
+		}
+	}
+	alerts := e.evaluate(st)
+	e.mu.Unlock()
+	e.deliver(alerts)
+	return alerts
+}
+
+// 处理买卖盘
+func (e *Engine) UpdateOrderBook(rt *futuapi.RTOrderBook) []*Alert {
+	if rt == nil || rt.Security == nil {
+		return nil
+	}
+	e.mu.Lock()
+	st := e.state(rt.Security)
+	st.OrderBook = rt
+	alerts := e.evaluate(st)
+	e.mu.Unlock()
+	e.deliver(alerts)
+	return alerts
+}
+
+// 按证券的最新数据判断所有规则，调用时需要持有锁
+func (e *Engine) evaluate(st *State) []*Alert {
+	var alerts []*Alert
+	now := e.now()
+	for _, r := range e.rules {
+		if !r.applies(st.Security) {
+			continue
+		}
+		left, ok := r.Left.Value(st)
+		if !ok {
+			continue
+		}
+		right, ok := r.Right.Value(st)
+		if !ok {
+			continue
+		}
+		key := ruleKey{rule: r, sec: *st.Security}
+		rs := e.rs[key]
+		if rs == nil {
+			rs = &ruleState{}
+			e.rs[key] = rs
+		}
+		if rs.update(r, left, right, now) {
+			alerts = append(alerts, &Alert{Rule: r, Security: st.Security, Left: left, Right: right, Time: now})
+		}
+	}
+	return alerts
+}
+
+func (e *Engine) deliver(alerts []*Alert) {
+	if len(alerts) == 0 {
+		return
+	}
+	e.mu.Lock()
+	sinks := append([]Sink(nil), e.sinks...)
+	onError := e.onError
+	e.mu.Unlock()
+	for _, a := range alerts {
+		for _, s := range sinks {
+			if err := s.Send(a); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// 从推送通道读取数据，不需要的通道可以为空，调用方需要订阅对应的推送。
+// ctx 结束时返回 ErrInterrupted，所有通道都关闭后返回 ErrChannelClosed。
+func (e *Engine) Run(ctx context.Context, qots <-chan *futuapi.UpdateBasicQotResp, kls <-chan *futuapi.UpdateKLResp,
+	tickers <-chan *futuapi.UpdateTickerResp, books <-chan *futuapi.UpdateOrderBookResp) error {
+	for qots != nil || kls != nil || tickers != nil || books != nil {
+		select {
+		case <-ctx.Done():
+			return futuapi.ErrInterrupted
+		case resp, ok := <-qots:
+			if !ok {
+				qots = nil
+				continue
+			}
+			if resp.Err == nil {
+				e.UpdateBasicQot(resp.BasicQot)
+			}
+		case resp, ok := <-kls:
+			if !ok {
+				kls = nil
+				continue
+			}
+			if resp.Err == nil {
+				e.UpdateKLine(resp.KLine)
+			}
+		case resp, ok := <-tickers:
+			if !ok {
+				tickers = nil
+				continue
+			}
+			if resp.Err == nil {
+				e.UpdateTicker(resp.Ticker)
+			}
+		case resp, ok := <-books:
+			if !ok {
+				books = nil
+				continue
+			}
+			if resp.Err == nil {
+				e.UpdateOrderBook(resp.OrderBook)
+			}
+		}
+	}
+	return futuapi.ErrChannelClosed
+}
+
+// 同步到服务器的到价提醒
+type Reminder struct {
+	Rule     *Rule
+	Security *futuapi.Security
+	Type     qotcommon.PriceReminderType
+	Value    float64
+	Key      int64 //服务器返回的提醒标识
+}
+
+// 服务器到价提醒接口，由 *futuapi.FutuAPI 实现
+type reminderAPI interface {
+	GetPriceReminder(ctx context.Context, security *futuapi.Security, market qotcommon.QotMarket) ([]*futuapi.PriceReminder, error)
+	SetPriceReminder(ctx context.Context, security *futuapi.Security, op qotsetpricereminder.SetPriceReminderOp,
+		key int64, remindType qotcommon.PriceReminderType, freq qotcommon.PriceReminderFreq, value float64, note string) (int64, error)
+}
+
+// 把可以同步的简单规则添加为服务器的到价提醒，规则需要指定证券，备注为规则名称。
+// 先查询证券已有的提醒，备注和类型相同的提醒视为之前同步的结果：参数和频率相同时直接使用，不同时修改，
+// 因此重复调用不会添加重复的提醒。服务器提醒有数量限制，出错时返回已同步的提醒和错误。
+func (e *Engine) Mirror(ctx context.Context, api *futuapi.FutuAPI, freq qotcommon.PriceReminderFreq) ([]*Reminder, error) {
+	return e.mirror(ctx, api, freq)
+}
+
+func (e *Engine) mirror(ctx context.Context, api reminderAPI, freq qotcommon.PriceReminderFreq) ([]*Reminder, error) {
+	type itemKey struct {
+		sec  futuapi.Security
+		note string
+		typ  qotcommon.PriceReminderType
+	}
+	existing := make(map[itemKey]*futuapi.PriceReminderItem)
+	queried := make(map[futuapi.Security]bool)
+	var list []*Reminder
+	for _, r := range e.Rules() {
+		typ, value, ok := r.Reminder()
+		if !ok {
+			continue
+		}
+		for _, sec := range r.Securities {
+			if !queried[*sec] {
+				reminders, err := api.GetPriceReminder(ctx, sec, qotcommon.QotMarket_QotMarket_Unknown)
+				if err != nil {
+					return list, err
+				}
+				queried[*sec] = true
+				for _, rem := range reminders {
+					for _, item := range rem.ItemList {
+						if item != nil && item.Note != "" {
+							existing[itemKey{sec: *sec, note: item.Note, typ: item.ItemType}] = item
+						}
+					}
+				}
+			}
+			k := itemKey{sec: *sec, note: r.Name, typ: typ}
+			op, key := qotsetpricereminder.SetPriceReminderOp_SetPriceReminderOp_Add, int64(0)
+			if item := existing[k]; item != nil {
+				if item.Value == value && item.Freq == freq {
+					list = append(list, &Reminder{Rule: r, Security: sec, Type: typ, Value: value, Key: item.Key})
+					continue
+				}
+				op, key = qotsetpricereminder.SetPriceReminderOp_SetPriceReminderOp_Modify, item.Key
+			}
+			newKey, err := api.SetPriceReminder(ctx, sec, op, key, typ, freq, value, r.Name)
+			if err != nil {
+				return list, err
+			}
+			if newKey != 0 {
+				key = newKey
+			}
+			existing[k] = &futuapi.PriceReminderItem{Key: key, ItemType: typ, Value: value, Note: r.Name, Freq: freq}
+			list = append(list, &Reminder{Rule: r, Security: sec, Type: typ, Value: value, Key: key})
+		}
+	}
+	return list, nil
+}
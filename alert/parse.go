@@ -0,0 +1,288 @@
+package alert
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hurisheng/go-futu-api/pb/qotcommon"
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+)
+
+var quoteFields = map[string]Field{
+	"price":         Price,
+	"open":          Open,
+	"high":          High,
+	"low":           Low,
+	"last_close":    LastClose,
+	"volume":        Volume,
+	"turnover":      Turnover,
+	"turnover_rate": TurnoverRate,
+	"amplitude":     Amplitude,
+	"change_rate":   ChangeRate,
+	"bid":           Bid,
+	"ask":           Ask,
+	"bid_vol":       BidVolume,
+	"ask_vol":       AskVolume,
+	"tick_price":    TickPrice,
+	"tick_volume":   TickVolume,
+	"tick_turnover": TickTurnover,
+}
+
+var klFields = map[string]KLValue{
+	"open":     KLOpen,
+	"high":     KLHigh,
+	"low":      KLLow,
+	"close":    KLClose,
+	"volume":   KLVolume,
+	"turnover": KLTurnover,
+}
+
+var indicatorKinds = map[string]IndicatorKind{
+	"ma":         MA,
+	"ema":        EMA,
+	"rsi":        RSI,
+	"avg_volume": AvgVolume,
+}
+
+var klTypes = map[string]qotcommon.KLType{
+	"1m":      qotcommon.KLType_KLType_1Min,
+	"3m":      qotcommon.KLType_KLType_3Min,
+	"5m":      qotcommon.KLType_KLType_5Min,
+	"15m":     qotcommon.KLType_KLType_15Min,
+	"30m":     qotcommon.KLType_KLType_30Min,
+	"60m":     qotcommon.KLType_KLType_60Min,
+	"day":     qotcommon.KLType_KLType_Day,
+	"week":    qotcommon.KLType_KLType_Week,
+	"month":   qotcommon.KLType_KLType_Month,
+	"quarter": qotcommon.KLType_KLType_Quarter,
+	"year":    qotcommon.KLType_KLType_Year,
+}
+
+var ops = map[string]Op{
+	">":           Above,
+	"<":           Below,
+	"cross_above": CrossAbove,
+	"cross_below": CrossBelow,
+}
+
+// 把一行拆分为单词和符号
+func tokens(line string) []string {
+	var list []string
+	var cur strings.Builder
+	flush := func() {
+		if cur.Len() > 0 {
+			list = append(list, cur.String())
+			cur.Reset()
+		}
+	}
+	for _, r := range line {
+		switch r {
+		case ' ', '\t':
+			flush()
+		case ':', '(', ')', ',', '*', '>', '<':
+			flush()
+			list = append(list, string(r))
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	flush()
+	return list
+}
+
+type parser struct {
+	toks []string
+	pos  int
+}
+
+func (p *parser) peek() string {
+	if p.pos < len(p.toks) {
+		return p.toks[p.pos]
+	}
+	return ""
+}
+
+func (p *parser) next() string {
+	t := p.peek()
+	if p.pos < len(p.toks) {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) expect(t string) error {
+	if got := p.next(); got != t {
+		return fmt.Errorf("expected %q, got %q", t, got)
+	}
+	return nil
+}
+
+func (p *parser) side() (Source, error) {
+	left, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek() != "*" {
+		return left, nil
+	}
+	p.next()
+	right, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	if c, ok := left.(Const); ok {
+		return Scaled{Factor: float64(c), Source: right}, nil
+	}
+	if c, ok := right.(Const); ok {
+		return Scaled{Factor: float64(c), Source: left}, nil
+	}
+	return nil, fmt.Errorf("one side of * must be a number")
+}
+
+func (p *parser) term() (Source, error) {
+	name := p.next()
+	if v, err := strconv.ParseFloat(name, 64); err == nil {
+		return Const(v), nil
+	}
+	name = strings.ToLower(name)
+	if p.peek() != "(" {
+		if f, ok := quoteFields[name]; ok {
+			return f, nil
+		}
+		return nil, fmt.Errorf("unknown field %q", name)
+	}
+	p.next()
+	var args []string
+	for p.peek() != ")" {
+		if p.peek() == "" {
+			return nil, fmt.Errorf("missing )")
+		}
+		if t := p.next(); t != "," {
+			args = append(args, strings.ToLower(t))
+		}
+	}
+	p.next()
+	if len(args) == 0 {
+		return nil, fmt.Errorf("%s needs a K line type", name)
+	}
+	kl, ok := klTypes[args[0]]
+	if !ok {
+		return nil, fmt.Errorf("unknown K line type %q", args[0])
+	}
+	if f, ok := klFields[name]; ok && len(args) == 1 {
+		return KLField{KLType: kl, Field: f}, nil
+	}
+	kind, ok := indicatorKinds[name]
+	if !ok || len(args) != 2 {
+		return nil, fmt.Errorf("unknown term %s(%s)", name, strings.Join(args, ","))
+	}
+	n, err := strconv.Atoi(args[1])
+	if err != nil || n <= 0 {
+		return nil, fmt.Errorf("invalid period %q", args[1])
+	}
+	return Indicator{KLType: kl, Kind: kind, N: n}, nil
+}
+
+// 解析一条规则，例如：
+//
+//	ma20: price cross_above ma(day,20) cooldown 30m on HK.00700,US.AAPL
+//	spike: volume(1m) > 3 * avg_volume(1m,20) cooldown 5m
+//	drop: change_rate < -5 hysteresis 1
+//
+// 语法：
+//
+//	rule   = name ":" side op side {option}
+//	side   = term | number "*" term | term "*" number
+//	term   = number | field | klfield "(" kl ")" | ind "(" kl "," n ")"
+//	op     = ">" | "<" | "cross_above" | "cross_below"
+//	option = "cooldown" duration | "hysteresis" number | "on" code {"," code}
+//
+// field 为 price、open、high、low、last_close、volume、turnover、turnover_rate、amplitude、change_rate、
+// bid、ask、bid_vol、ask_vol、tick_price、tick_volume、tick_turnover；
+// klfield 为 open、high、low、close、volume、turnover，取最新一根 K 线；
+// ind 为 ma、ema、rsi、avg_volume；kl 为 1m、3m、5m、15m、30m、60m、day、week、month、quarter、year。
+func ParseRule(line string) (*Rule, error) {
+	p := &parser{toks: tokens(line)}
+	r := &Rule{Name: p.next()}
+	if r.Name == "" {
+		return nil, fmt.Errorf("missing rule name")
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	var err error
+	if r.Left, err = p.side(); err != nil {
+		return nil, err
+	}
+	op, ok := ops[strings.ToLower(p.peek())]
+	if !ok {
+		return nil, fmt.Errorf("unknown operator %q", p.peek())
+	}
+	p.next()
+	r.Op = op
+	if r.Right, err = p.side(); err != nil {
+		return nil, err
+	}
+	for p.peek() != "" {
+		switch opt := strings.ToLower(p.next()); opt {
+		case "cooldown":
+			if r.Cooldown, err = time.ParseDuration(p.next()); err != nil {
+				return nil, err
+			}
+		case "hysteresis":
+			if r.Hysteresis, err = strconv.ParseFloat(p.next(), 64); err != nil {
+				return nil, err
+			}
+		case "on":
+			for {
+				sec, err := futuapi.ParseSecurity(p.next())
+				if err != nil {
+					return nil, err
+				}
+				r.Securities = append(r.Securities, sec)
+				if p.peek() != "," {
+					break
+				}
+				p.next()
+			}
+		default:
+			return nil, fmt.Errorf("unknown option %q", opt)
+		}
+	}
+	return r, nil
+}
+
+// 读取规则配置，每行一条规则，格式见 ParseRule，# 开头的行为注释
+func Read(r io.Reader) ([]*Rule, error) {
+	var rules []*Rule
+	s := bufio.NewScanner(r)
+	n := 0
+	for s.Scan() {
+		n++
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule, err := ParseRule(line)
+		if err != nil {
+			return nil, fmt.Errorf("alert: line %d: %v", n, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, s.Err()
+}
+
+// 读取规则配置文件
+func Load(path string) ([]*Rule, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
@@ -0,0 +1,149 @@
+package alert
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/hurisheng/go-futu-api/pb/qotcommon"
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+)
+
+// 比较方式
+type Op int
+
+const (
+	Above      Op = iota //左边大于右边时提醒，条件一开始就成立也会提醒
+	Below                //左边小于右边时提醒
+	CrossAbove           //左边从下方上穿右边时提醒，需要先有一次不成立的数据
+	CrossBelow           //左边从上方下穿右边时提醒
+)
+
+func (op Op) String() string {
+	switch op {
+	case Above:
+		return ">"
+	case Below:
+		return "<"
+	case CrossAbove:
+		return "cross_above"
+	case CrossBelow:
+		return "cross_below"
+	}
+	return "unknown"
+}
+
+// 提醒规则
+type Rule struct {
+	Name       string
+	Securities []*futuapi.Security //适用的证券，为空时适用于所有证券
+	Left       Source
+	Op         Op
+	Right      Source
+	Cooldown   time.Duration //同一证券两次提醒的最小间隔，冷却期间条件成立不提醒
+	Hysteresis float64       //回差，提醒后左右两边的差需要向反方向超过该值才会再次提醒，避免在阈值附近反复提醒
+}
+
+func (r *Rule) applies(sec *futuapi.Security) bool {
+	if len(r.Securities) == 0 {
+		return true
+	}
+	for _, s := range r.Securities {
+		if *s == *sec {
+			return true
+		}
+	}
+	return false
+}
+
+// 规则对应的服务器到价提醒类型和值，只有字段与常数比较的简单规则可以同步
+func (r *Rule) Reminder() (qotcommon.PriceReminderType, float64, bool) {
+	f, ok := r.Left.(Field)
+	if !ok {
+		return 0, 0, false
+	}
+	c, ok := r.Right.(Const)
+	if !ok {
+		return 0, 0, false
+	}
+	up := r.Op == Above || r.Op == CrossAbove
+	v := float64(c)
+	switch {
+	case f == Price && up:
+		return qotcommon.PriceReminderType_PriceReminderType_PriceUp, v, true
+	case f == Price:
+		return qotcommon.PriceReminderType_PriceReminderType_PriceDown, v, true
+	case f == ChangeRate && up && v > 0:
+		return qotcommon.PriceReminderType_PriceReminderType_ChangeRateUp, v, true
+	case f == ChangeRate && !up && v < 0:
+		return qotcommon.PriceReminderType_PriceReminderType_ChangeRateDown, -v, true
+	case f == Volume && up:
+		return qotcommon.PriceReminderType_PriceReminderType_VolumeUp, v, true
+	case f == Turnover && up:
+		return qotcommon.PriceReminderType_PriceReminderType_TurnoverUp, v, true
+	case f == TurnoverRate && up:
+		return qotcommon.PriceReminderType_PriceReminderType_TurnoverRateUp, v, true
+	case f == Bid && up:
+		return qotcommon.PriceReminderType_PriceReminderType_BidPriceUp, v, true
+	case f == Ask && !up:
+		return qotcommon.PriceReminderType_PriceReminderType_AskPriceDown, v, true
+	case f == BidVolume && up:
+		return qotcommon.PriceReminderType_PriceReminderType_BidVolUp, v, true
+	case f == AskVolume && up:
+		return qotcommon.PriceReminderType_PriceReminderType_AskVolUp, v, true
+	}
+	return 0, 0, false
+}
+
+// 提醒
+type Alert struct {
+	Rule     *Rule
+	Security *futuapi.Security
+	Left     float64
+	Right    float64
+	Time     time.Time
+}
+
+func (a *Alert) String() string {
+	return fmt.Sprintf("%s %s %s: %g %v %g", a.Time.Format("2006-01-02 15:04:05"), a.Security, a.Rule.Name, a.Left, a.Rule.Op, a.Right)
+}
+
+// 规则在一只证券上的状态
+type ruleState struct {
+	known  bool //是否已有数据
+	active bool //条件是否处于成立状态
+	last   time.Time
+}
+
+// 用新数据更新状态，返回是否提醒
+func (st *ruleState) update(r *Rule, left float64, right float64, now time.Time) bool {
+	d := left - right
+	if math.IsNaN(d) {
+		return false
+	}
+	if r.Op == Below || r.Op == CrossBelow {
+		d = -d
+	}
+	h := math.Abs(r.Hysteresis)
+	known := st.known
+	st.known = true
+	if st.active {
+		if d <= -h {
+			st.active = false
+		}
+		return false
+	}
+	if d <= 0 {
+		return false
+	}
+	st.active = true
+	if !known && (r.Op == CrossAbove || r.Op == CrossBelow) {
+		// 第一次数据条件就成立，不是穿越
+		return false
+	}
+	if r.Cooldown > 0 && !st.last.IsZero() && now.Sub(st.last) < r.Cooldown {
+		return false
+	}
+	st.last = now
+	return true
+}
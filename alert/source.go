@@ -0,0 +1,269 @@
+// Package alert 是本地的规则提醒引擎：在报价、K 线、逐笔和买卖盘推送上按规则判断，
+// 支持指标、冷却时间和回差，把提醒发送到可替换的输出，并可以把简单规则同步为服务器的到价提醒。
+//
+// 规则可以在代码中构造，也可以从配置文件读取，格式见 ParseRule。
+package alert
+
+import (
+	"math"
+
+	"github.com/hurisheng/go-futu-api/pb/qotcommon"
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+	"github.com/woxinyoumeng/go-futu-api/indicator"
+)
+
+// 每种 K 线保留的最大根数
+const maxKLines = 500
+
+// 规则中比较的数值，没有数据时返回 false
+type Source interface {
+	Value(s *State) (float64, bool)
+}
+
+// 一只证券的最新数据
+type State struct {
+	Security  *futuapi.Security
+	Qot       *futuapi.BasicQot
+	Ticker    *futuapi.Ticker
+	OrderBook *futuapi.RTOrderBook
+	klines    map[qotcommon.KLType][]*futuapi.KLine
+	inds      map[Indicator]stream
+}
+
+type stream interface {
+	Push(k *futuapi.KLine)
+	Value() float64
+}
+
+func newState(sec *futuapi.Security) *State {
+	return &State{Security: sec, klines: make(map[qotcommon.KLType][]*futuapi.KLine), inds: make(map[Indicator]stream)}
+}
+
+// 最近的 K 线，按时间排序
+func (s *State) KLines(kl qotcommon.KLType) []*futuapi.KLine {
+	return s.klines[kl]
+}
+
+func (s *State) addKLine(kl qotcommon.KLType, k *futuapi.KLine) {
+	list := s.klines[kl]
+	if n := len(list); n > 0 && list[n-1].Time == k.Time {
+		list[n-1] = k
+	} else {
+		list = append(list, k)
+		if len(list) > maxKLines {
+			list = append([]*futuapi.KLine(nil), list[len(list)-maxKLines:]...)
+		}
+	}
+	s.klines[kl] = list
+	for ind, st := range s.inds {
+		if ind.KLType == kl {
+			st.Push(k)
+		}
+	}
+}
+
+// 常数
+type Const float64
+
+func (c Const) Value(s *State) (float64, bool) {
+	return float64(c), true
+}
+
+// 数值乘以系数，如成交量大于均量的3倍
+type Scaled struct {
+	Factor float64
+	Source Source
+}
+
+func (c Scaled) Value(s *State) (float64, bool) {
+	v, ok := c.Source.Value(s)
+	return c.Factor * v, ok
+}
+
+// 报价、逐笔和买卖盘中的字段
+type Field int
+
+const (
+	Price        Field = iota //最新价
+	Open                      //开盘价
+	High                      //最高价
+	Low                       //最低价
+	LastClose                 //昨收价
+	Volume                    //成交量
+	Turnover                  //成交额
+	TurnoverRate              //换手率，百分比
+	Amplitude                 //振幅，百分比
+	ChangeRate                //涨跌幅，百分比，由最新价和昨收价计算
+	Bid                       //买一价，来自买卖盘
+	Ask                       //卖一价，来自买卖盘
+	BidVolume                 //买一量，来自买卖盘
+	AskVolume                 //卖一量，来自买卖盘
+	TickPrice                 //最新逐笔的价格
+	TickVolume                //最新逐笔的成交量
+	TickTurnover              //最新逐笔的成交额
+)
+
+func (f Field) Value(s *State) (float64, bool) {
+	switch f {
+	case Bid, Ask, BidVolume, AskVolume:
+		if s.OrderBook == nil {
+			return 0, false
+		}
+		side := s.OrderBook.Bids
+		if f == Ask || f == AskVolume {
+			side = s.OrderBook.Asks
+		}
+		if len(side) == 0 || side[0] == nil {
+			return 0, false
+		}
+		if f == Bid || f == Ask {
+			return side[0].Price, true
+		}
+		return float64(side[0].Volume), true
+	case TickPrice, TickVolume, TickTurnover:
+		t := s.Ticker
+		if t == nil {
+			return 0, false
+		}
+		switch f {
+		case TickPrice:
+			return t.Price, true
+		case TickVolume:
+			return float64(t.Volume), true
+		}
+		return t.Turnover, true
+	}
+	q := s.Qot
+	if q == nil {
+		return 0, false
+	}
+	switch f {
+	case Price:
+		return q.CurPrice, true
+	case Open:
+		return q.OpenPrice, true
+	case High:
+		return q.HighPrice, true
+	case Low:
+		return q.LowPrice, true
+	case LastClose:
+		return q.LastClosePrice, true
+	case Volume:
+		return float64(q.Volume), true
+	case Turnover:
+		return q.Turnover, true
+	case TurnoverRate:
+		return q.TurnoverRate, true
+	case Amplitude:
+		return q.Amplitude, true
+	case ChangeRate:
+		if q.LastClosePrice == 0 {
+			return 0, false
+		}
+		return (q.CurPrice - q.LastClosePrice) / q.LastClosePrice * 100, true
+	}
+	return 0, false
+}
+
+// K 线中的字段
+type KLValue int
+
+const (
+	KLClose KLValue = iota
+	KLOpen
+	KLHigh
+	KLLow
+	KLVolume
+	KLTurnover
+)
+
+// 最新一根 K 线的字段
+type KLField struct {
+	KLType qotcommon.KLType
+	Field  KLValue
+}
+
+func (f KLField) Value(s *State) (float64, bool) {
+	list := s.klines[f.KLType]
+	if len(list) == 0 {
+		return 0, false
+	}
+	k := list[len(list)-1]
+	switch f.Field {
+	case KLClose:
+		return k.ClosePrice, true
+	case KLOpen:
+		return k.OpenPrice, true
+	case KLHigh:
+		return k.HighPrice, true
+	case KLLow:
+		return k.LowPrice, true
+	case KLVolume:
+		return float64(k.Volume), true
+	case KLTurnover:
+		return k.Turnover, true
+	}
+	return 0, false
+}
+
+// 指标类型
+type IndicatorKind int
+
+const (
+	MA        IndicatorKind = iota //收盘价的简单移动平均
+	EMA                            //收盘价的指数移动平均
+	RSI                            //相对强弱指标
+	AvgVolume                      //最新一根之前 N 根 K 线的平均成交量，用于判断放量
+)
+
+// K 线指标，需要在 K 线数据到达前通过规则加入引擎，才能从第一根开始计算
+type Indicator struct {
+	KLType qotcommon.KLType
+	Kind   IndicatorKind
+	N      int
+}
+
+func (ind Indicator) Value(s *State) (float64, bool) {
+	if ind.Kind == AvgVolume {
+		list := s.klines[ind.KLType]
+		if ind.N <= 0 || len(list) <= ind.N {
+			return 0, false
+		}
+		var sum float64
+		for _, k := range list[len(list)-1-ind.N : len(list)-1] {
+			sum += float64(k.Volume)
+		}
+		return sum / float64(ind.N), true
+	}
+	st := s.inds[ind]
+	if st == nil {
+		return 0, false
+	}
+	v := st.Value()
+	return v, !math.IsNaN(v)
+}
+
+func (ind Indicator) stream() stream {
+	switch ind.Kind {
+	case MA:
+		return indicator.NewMA(ind.N)
+	case EMA:
+		return indicator.NewEMA(ind.N)
+	case RSI:
+		return indicator.NewRSI(ind.N)
+	}
+	return nil
+}
+
+// 规则中用到的流式指标
+func indicators(src Source) []Indicator {
+	switch v := src.(type) {
+	case Indicator:
+		if v.stream() != nil {
+			return []Indicator{v}
+		}
+	case Scaled:
+		return indicators(v.Source)
+	}
+	return nil
+}
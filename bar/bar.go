@@ -0,0 +1,258 @@
+// Package bar 根据逐笔或分时推送在本地合成任意周期的 K 线，例如10秒、2分钟、90分钟，
+// 不受 SubType_KL_* 固定周期的限制，也不需要额外的 K 线订阅额度。
+package bar
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/hurisheng/go-futu-api/pb/qotcommon"
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+)
+
+// K 线
+type Bar struct {
+	Security *futuapi.Security //证券
+	Begin    time.Time         //开始时间（含）
+	End      time.Time         //结束时间（不含）
+	Open     float64           //开盘价
+	High     float64           //最高价
+	Low      float64           //最低价
+	Close    float64           //收盘价
+	Volume   int64             //成交量
+	Turnover float64           //成交额
+	VWAP     float64           //成交量加权均价
+	Count    int               //成交笔数，分时数据为分钟数
+}
+
+// 事件类型
+type EventType int
+
+const (
+	EventPartial EventType = iota //K 线更新，尚未结束
+	EventClosed                   //K 线结束
+)
+
+// K 线事件
+type Event struct {
+	Type EventType
+	Bar  *Bar
+}
+
+// 合成参数
+type Options struct {
+	Sessions []Session      //交易时段，为空表示全天连续交易
+	Location *time.Location //交易所时区，为空时使用 UTC
+	Buffer   int            //事件通道缓冲大小
+}
+
+// 按市场返回默认的交易时段和时区
+func MarketOptions(market qotcommon.QotMarket) *Options {
+	switch market {
+	case qotcommon.QotMarket_QotMarket_HK_Security:
+		return &Options{Sessions: HKSessions(), Location: futuapi.MarketLocation(market)}
+	case qotcommon.QotMarket_QotMarket_US_Security:
+		return &Options{Sessions: USSessions(), Location: futuapi.MarketLocation(market)}
+	case qotcommon.QotMarket_QotMarket_CNSH_Security, qotcommon.QotMarket_QotMarket_CNSZ_Security:
+		return &Options{Sessions: CNSessions(), Location: futuapi.MarketLocation(market)}
+	default:
+		return &Options{Location: time.UTC}
+	}
+}
+
+// K 线合成器，可同时处理多只证券
+type Builder struct {
+	period   time.Duration
+	sessions []Session
+	loc      *time.Location
+	out      chan *Event
+
+	mu   sync.Mutex
+	bars map[futuapi.Security]*state
+}
+
+type state struct {
+	bar    *Bar
+	closed time.Time //最后一根已结束 K 线的开始时间，迟到的数据不再更新已结束的 K 线
+	// 分时数据同一分钟会多次推送，记录已计入的量和额，只累加增量
+	minutes map[int64]minute //按分钟开始时间的 Unix 秒
+}
+
+type minute struct {
+	volume   int64
+	turnover float64
+}
+
+// 创建合成器，period 为 K 线周期，opts 为空时全天连续交易、使用 UTC
+func NewBuilder(period time.Duration, opts *Options) *Builder {
+	if opts == nil {
+		opts = &Options{}
+	}
+	loc := opts.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	return &Builder{
+		period:   period,
+		sessions: opts.Sessions,
+		loc:      loc,
+		out:      make(chan *Event, opts.Buffer),
+		bars:     make(map[futuapi.Security]*state),
+	}
+}
+
+// K 线事件通道，调用方需要及时读取，否则合成会阻塞
+func (b *Builder) Events() <-chan *Event {
+	return b.out
+}
+
+// 处理逐笔数据
+func (b *Builder) AddTicker(rt *futuapi.RTTicker) {
+	if rt == nil || rt.Security == nil {
+		return
+	}
+	b.mu.Lock()
+	var events []*Event
+	for _, t := range rt.Tickers {
+		events = append(events, b.add(rt.Security, t.TimeIn(b.loc), t.Price, t.Volume, t.Turnover, nil)...)
+	}
+	events = append(events, b.partial(rt.Security))
+	b.mu.Unlock()
+	b.emit(events)
+}
+
+// 处理分时数据，同一分钟的重复推送只累加增量
+func (b *Builder) AddRT(rt *futuapi.RTData) {
+	if rt == nil || rt.Security == nil {
+		return
+	}
+	b.mu.Lock()
+	var events []*Event
+	for _, t := range rt.TimeShares {
+		if t.IsBlank {
+			continue
+		}
+		events = append(events, b.add(rt.Security, t.TimeIn(b.loc), t.Price, t.Volume, t.Turnover, t)...)
+	}
+	events = append(events, b.partial(rt.Security))
+	b.mu.Unlock()
+	b.emit(events)
+}
+
+// 结束所有结束时间不晚于 now 的 K 线
+func (b *Builder) Flush(now time.Time) {
+	b.mu.Lock()
+	var events []*Event
+	for _, s := range b.bars {
+		if s.bar != nil && !s.bar.End.After(now) {
+			events = append(events, &Event{Type: EventClosed, Bar: s.bar})
+			s.closed, s.bar, s.minutes = s.bar.Begin, nil, nil
+		}
+	}
+	b.mu.Unlock()
+	b.emit(events)
+}
+
+// 从推送通道读取数据合成 K 线，并定时结束到期的 K 线。
+// 不需要的通道传 nil，ctx 结束时返回 ErrInterrupted，通道全部关闭时返回 ErrChannelClosed。
+func (b *Builder) Run(ctx context.Context, tickers <-chan *futuapi.UpdateTickerResp, rts <-chan *futuapi.UpdateRTResp) error {
+	timer := time.NewTicker(time.Second)
+	defer timer.Stop()
+	for tickers != nil || rts != nil {
+		select {
+		case <-ctx.Done():
+			return futuapi.ErrInterrupted
+		case now := <-timer.C:
+			b.Flush(now)
+		case resp, ok := <-tickers:
+			if !ok {
+				tickers = nil
+				continue
+			}
+			if resp.Err == nil {
+				b.AddTicker(resp.Ticker)
+			}
+		case resp, ok := <-rts:
+			if !ok {
+				rts = nil
+				continue
+			}
+			if resp.Err == nil {
+				b.AddRT(resp.RT)
+			}
+		}
+	}
+	return futuapi.ErrChannelClosed
+}
+
+func (b *Builder) add(sec *futuapi.Security, t time.Time, price float64, volume int64, turnover float64, ts *futuapi.TimeShare) []*Event {
+	s := b.bars[*sec]
+	if s == nil {
+		s = &state{}
+		b.bars[*sec] = s
+	}
+	begin, end := bucket(t, b.period, b.sessions, b.loc)
+	if !s.closed.IsZero() && !begin.After(s.closed) {
+		// 已结束的 K 线不再更新，分时数据的重复推送也不会重复计入
+		return nil
+	}
+	var events []*Event
+	if s.bar != nil {
+		if begin.Before(s.bar.Begin) {
+			return nil
+		}
+		if !begin.Equal(s.bar.Begin) {
+			events = append(events, &Event{Type: EventClosed, Bar: s.bar})
+			s.closed, s.bar, s.minutes = s.bar.Begin, nil, nil
+		}
+	}
+	count := 1
+	if ts != nil {
+		if s.minutes == nil {
+			s.minutes = make(map[int64]minute)
+		}
+		m, ok := s.minutes[t.Unix()]
+		if ok {
+			count = 0
+		}
+		volume, turnover = volume-m.volume, turnover-m.turnover
+		s.minutes[t.Unix()] = minute{volume: ts.Volume, turnover: ts.Turnover}
+	}
+	if s.bar == nil {
+		c := *sec
+		s.bar = &Bar{Security: &c, Begin: begin, End: end, Open: price, High: price, Low: price}
+	}
+	bar := s.bar
+	if price > bar.High {
+		bar.High = price
+	}
+	if price < bar.Low {
+		bar.Low = price
+	}
+	bar.Close = price
+	bar.Volume += volume
+	bar.Turnover += turnover
+	bar.Count += count
+	if bar.Volume > 0 {
+		bar.VWAP = bar.Turnover / float64(bar.Volume)
+	}
+	return events
+}
+
+func (b *Builder) partial(sec *futuapi.Security) *Event {
+	s := b.bars[*sec]
+	if s == nil || s.bar == nil {
+		return nil
+	}
+	c := *s.bar
+	return &Event{Type: EventPartial, Bar: &c}
+}
+
+func (b *Builder) emit(events []*Event) {
+	for _, e := range events {
+		if e != nil {
+			b.out <- e
+		}
+	}
+}
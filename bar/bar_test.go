@@ -0,0 +1,123 @@
+package bar
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hurisheng/go-futu-api/pb/qotcommon"
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+)
+
+func TestBucket(t *testing.T) {
+	opts := MarketOptions(qotcommon.QotMarket_QotMarket_HK_Security)
+	at := func(h, m int) time.Time { return time.Date(2024, 3, 20, h, m, 0, 0, opts.Location) }
+	for _, c := range []struct {
+		t          time.Time
+		period     time.Duration
+		begin, end time.Time
+	}{
+		{at(9, 20), 30 * time.Minute, at(9, 30), at(10, 0)},   //开市前竞价归入第一根
+		{at(10, 0), 30 * time.Minute, at(10, 0), at(10, 30)},  //区间开始时间包含在内
+		{at(12, 30), 30 * time.Minute, at(11, 30), at(12, 0)}, //午休归入上午最后一根
+		{at(11, 0), 90 * time.Minute, at(11, 0), at(12, 0)},   //时段末尾不足一个周期
+		{at(13, 0), 90 * time.Minute, at(13, 0), at(14, 30)},  //下午时段重新切分
+		{at(16, 5), 90 * time.Minute, at(14, 30), at(16, 0)},  //收市竞价归入最后一根
+		{at(9, 0), 2 * time.Hour, at(9, 30), at(11, 30)},      //第一根
+		{at(15, 59), time.Minute, at(15, 59), at(16, 0)},      //最后一分钟
+		{at(12, 0), 30 * time.Minute, at(11, 30), at(12, 0)},  //上午结束时间不属于上午
+		{at(13, 0).Add(-time.Nanosecond), time.Minute, at(11, 59), at(12, 0)},
+	} {
+		if begin, end := opts.Bucket(c.t, c.period); !begin.Equal(c.begin) || !end.Equal(c.end) {
+			t.Errorf("bucket(%v, %v) = [%v, %v), want [%v, %v)", c.t, c.period, begin, end, c.begin, c.end)
+		}
+	}
+	// 没有交易时段时从0点开始切分
+	utc := &Options{}
+	if begin, end := utc.Bucket(time.Date(2024, 3, 20, 10, 0, 15, 0, time.UTC), 10*time.Second); !begin.Equal(time.Date(2024, 3, 20, 10, 0, 10, 0, time.UTC)) ||
+		end.Sub(begin) != 10*time.Second {
+		t.Errorf("continuous bucket [%v, %v)", begin, end)
+	}
+}
+
+func drain(b *Builder) []*Event {
+	var list []*Event
+	for {
+		select {
+		case e := <-b.Events():
+			list = append(list, e)
+		default:
+			return list
+		}
+	}
+}
+
+func closed(events []*Event) []*Bar {
+	var list []*Bar
+	for _, e := range events {
+		if e.Type == EventClosed {
+			list = append(list, e.Bar)
+		}
+	}
+	return list
+}
+
+func TestBuilderSessionBreak(t *testing.T) {
+	sec := &futuapi.Security{Market: qotcommon.QotMarket_QotMarket_HK_Security, Code: "00700"}
+	opts := MarketOptions(sec.Market)
+	opts.Buffer = 100
+	b := NewBuilder(time.Hour, opts)
+	tick := func(tm string, price float64, vol int64) *futuapi.Ticker {
+		return &futuapi.Ticker{Time: "2024-03-20 " + tm, Price: price, Volume: vol, Turnover: price * float64(vol)}
+	}
+	b.AddTicker(&futuapi.RTTicker{Security: sec, Tickers: []*futuapi.Ticker{
+		tick("11:45:00", 10, 100),
+		tick("11:59:59", 11, 100),
+		tick("13:00:01", 12, 100),
+	}})
+	bars := closed(drain(b))
+	// 11:30 开始的一根在午休截断，下午从 13:00 重新开始
+	if len(bars) != 1 || bars[0].Begin.Hour() != 11 || bars[0].Begin.Minute() != 30 || bars[0].End.Hour() != 12 ||
+		bars[0].Volume != 200 || bars[0].Close != 11 || bars[0].Count != 2 {
+		t.Errorf("closed bars %+v", bars)
+	}
+}
+
+func TestBuilderRTAndLateData(t *testing.T) {
+	sec := &futuapi.Security{Market: qotcommon.QotMarket_QotMarket_HK_Security, Code: "00700"}
+	opts := MarketOptions(sec.Market)
+	opts.Buffer = 100
+	b := NewBuilder(5*time.Minute, opts)
+	rt := func(tm string, price float64, vol int64) {
+		b.AddRT(&futuapi.RTData{Security: sec, TimeShares: []*futuapi.TimeShare{
+			{Time: "2024-03-20 " + tm, Price: price, Volume: vol, Turnover: price * float64(vol)},
+		}})
+	}
+	// 同一分钟重复推送只累加增量
+	rt("10:01:00", 10, 100)
+	rt("10:01:00", 10.5, 150)
+	rt("10:02:00", 11, 50)
+	events := drain(b)
+	last := events[len(events)-1].Bar
+	if last.Volume != 200 || last.Count != 2 || last.High != 11 || last.Close != 11 {
+		t.Errorf("partial bar %+v", last)
+	}
+
+	end := last.End
+	b.Flush(end)
+	bars := closed(drain(b))
+	if len(bars) != 1 || bars[0].Volume != 200 {
+		t.Fatalf("flushed bars %+v", bars)
+	}
+	// 已结束区间的迟到数据不会生成第二根，也不会重复计入成交量
+	rt("10:02:00", 11, 80)
+	b.AddTicker(&futuapi.RTTicker{Security: sec, Tickers: []*futuapi.Ticker{{Time: "2024-03-20 10:04:59", Price: 9, Volume: 10}}})
+	b.Flush(end.Add(time.Hour))
+	if events := drain(b); len(events) != 0 {
+		t.Errorf("late data produced events %+v", events[0].Bar)
+	}
+	rt("10:06:00", 12, 30)
+	events = drain(b)
+	if len(events) != 1 || events[0].Type != EventPartial || !events[0].Bar.Begin.Equal(end) || events[0].Bar.Volume != 30 {
+		t.Errorf("next bar events %+v", events)
+	}
+}
@@ -0,0 +1,75 @@
+package bar
+
+import (
+	"time"
+)
+
+// 交易时段，以当地时间距离0点的时长表示，区间为 [Begin, End)
+type Session struct {
+	Begin time.Duration
+	End   time.Duration
+}
+
+func hm(h, m int) time.Duration {
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute
+}
+
+// 港股交易时段
+func HKSessions() []Session {
+	return []Session{{hm(9, 30), hm(12, 0)}, {hm(13, 0), hm(16, 0)}}
+}
+
+// 美股常规交易时段
+func USSessions() []Session {
+	return []Session{{hm(9, 30), hm(16, 0)}}
+}
+
+// A股交易时段
+func CNSessions() []Session {
+	return []Session{{hm(9, 30), hm(11, 30)}, {hm(13, 0), hm(15, 0)}}
+}
+
+// 计算时间所属的 K 线区间。
+// 没有交易时段时从当地0点开始按周期切分；有交易时段时每个时段内从开始时间切分，
+// 时段末尾不足一个周期的部分单独成一根。时段外的成交（如开市前竞价、收市竞价）
+// 归入之后最近时段的第一根或之前最近时段的最后一根。
+func bucket(t time.Time, period time.Duration, sessions []Session, loc *time.Location) (time.Time, time.Time) {
+	t = t.In(loc)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	tod := t.Sub(day)
+	if len(sessions) == 0 {
+		begin := tod / period * period
+		return day.Add(begin), day.Add(begin + period)
+	}
+	span := func(s Session, tod time.Duration) (time.Time, time.Time) {
+		begin := s.Begin + (tod-s.Begin)/period*period
+		end := begin + period
+		if end > s.End {
+			end = s.End
+		}
+		return day.Add(begin), day.Add(end)
+	}
+	for i, s := range sessions {
+		if tod < s.Begin {
+			if i == 0 {
+				return span(s, s.Begin)
+			}
+			prev := sessions[i-1]
+			return span(prev, prev.End-1)
+		}
+		if tod < s.End {
+			return span(s, tod)
+		}
+	}
+	last := sessions[len(sessions)-1]
+	return span(last, last.End-1)
+}
+
+// 计算时间 t 所属周期为 period 的 K 线区间 [begin, end)，规则同合成器
+func (o *Options) Bucket(t time.Time, period time.Duration) (time.Time, time.Time) {
+	loc := o.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	return bucket(t, period, o.Sessions, loc)
+}
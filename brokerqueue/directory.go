@@ -0,0 +1,260 @@
+package brokerqueue
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 经纪商目录，把经纪 ID 映射到所属的机构集团。
+// 先按配置的 ID 查找，找不到时按经纪名称前缀匹配，再按之前名称匹配时记下的 ID 查找，都找不到时以经纪名称作为集团。
+// 名称匹配时记下的 ID 映射可以用 Write 保存，下次用 Read 读取，之后没有名称的数据也能按 ID 归类。
+type Directory struct {
+	mu       sync.Mutex
+	ids      map[int64]string
+	learned  map[int64]string //名称匹配时记下的 ID
+	prefixes []prefix         //按前缀长度降序
+}
+
+type prefix struct {
+	name  string //小写
+	group string
+}
+
+// 内置的名称前缀，覆盖主要的国际投行、中资券商和零售券商，英文、简体和繁体中文名称都可以匹配。
+// 前缀使用完整的机构名称，避免匹配到名称相近的其他机构，如中信证券和中信建投
+var defaultPrefixes = map[string]string{
+	"Goldman Sachs":               "Goldman Sachs",
+	"高盛":                          "Goldman Sachs",
+	"Morgan Stanley":              "Morgan Stanley",
+	"摩根士丹利":                       "Morgan Stanley",
+	"J.P. Morgan":                 "J.P. Morgan",
+	"JPMorgan":                    "J.P. Morgan",
+	"摩根大通":                        "J.P. Morgan",
+	"UBS":                         "UBS",
+	"瑞银":                          "UBS",
+	"Credit Suisse":               "Credit Suisse",
+	"瑞信":                          "Credit Suisse",
+	"Citigroup":                   "Citigroup",
+	"花旗":                          "Citigroup",
+	"Merrill Lynch":               "Merrill Lynch",
+	"美林":                          "Merrill Lynch",
+	"HSBC":                        "HSBC",
+	"汇丰":                          "HSBC",
+	"Barclays":                    "Barclays",
+	"巴克莱":                         "Barclays",
+	"BNP Paribas":                 "BNP Paribas",
+	"法国巴黎":                        "BNP Paribas",
+	"Deutsche Bank":               "Deutsche Bank",
+	"德意志":                         "Deutsche Bank",
+	"Macquarie":                   "Macquarie",
+	"麦格理":                         "Macquarie",
+	"Nomura":                      "Nomura",
+	"野村":                          "Nomura",
+	"Daiwa":                       "Daiwa",
+	"大和":                          "Daiwa",
+	"Mizuho":                      "Mizuho",
+	"瑞穗":                          "Mizuho",
+	"CLSA":                        "CLSA",
+	"里昂":                          "CLSA",
+	"BOCI":                        "BOCI",
+	"中银国际":                        "BOCI",
+	"China International Capital": "CICC",
+	"CICC":                        "CICC",
+	"中国国际金融":                      "CICC",
+	"中金公司":                        "CICC",
+	"CITIC Securities":            "CITIC",
+	"中信证券":                        "CITIC",
+	"CITIC CLSA":                  "CLSA",
+	"中信里昂":                        "CLSA",
+	"China Securities":            "China Securities",
+	"中信建投":                        "China Securities",
+	"Haitong":                     "Haitong",
+	"海通":                          "Haitong",
+	"Guotai Junan":                "Guotai Junan",
+	"国泰君安":                        "Guotai Junan",
+	"Huatai":                      "Huatai",
+	"华泰":                          "Huatai",
+	"China Merchants Securities":  "China Merchants",
+	"招商证券":                        "China Merchants",
+	"GF ":                         "GF",
+	"广发":                          "GF",
+	"Futu":                        "Futu",
+	"富途":                          "Futu",
+	"Interactive Brokers":         "Interactive Brokers",
+	"盈透":                          "Interactive Brokers",
+	"Phillip":                     "Phillip",
+	"辉立":                          "Phillip",
+	"Bright Smart":                "Bright Smart",
+	"耀才":                          "Bright Smart",
+	"Hang Seng":                   "Hang Seng",
+	"恒生":                          "Hang Seng",
+	"Bank of China International": "BOCI",
+	// 港股经纪队列返回的繁体中文名称，与简体相同的不重复列出
+	"瑞銀":     "UBS",
+	"花旗環球":   "Citigroup",
+	"滙豐":     "HSBC",
+	"匯豐":     "HSBC",
+	"巴克萊":    "Barclays",
+	"法國巴黎":   "BNP Paribas",
+	"麥格理":    "Macquarie",
+	"中銀國際":   "BOCI",
+	"中國國際金融": "CICC",
+	"中信證券":   "CITIC",
+	"國泰君安":   "Guotai Junan",
+	"華泰":     "Huatai",
+	"招商證券":   "China Merchants",
+	"廣發":     "GF",
+	"輝立":     "Phillip",
+	"恆生":     "Hang Seng",
+}
+
+// 创建使用内置前缀的经纪商目录
+func NewDirectory() *Directory {
+	d := &Directory{ids: make(map[int64]string), learned: make(map[int64]string)}
+	for p, g := range defaultPrefixes {
+		d.SetPrefix(p, g)
+	}
+	return d
+}
+
+// 设置经纪 ID 所属的集团，覆盖前缀匹配的结果
+func (d *Directory) Set(id int64, group string) {
+	d.mu.Lock()
+	d.ids[id] = group
+	d.mu.Unlock()
+}
+
+// 设置名称前缀所属的集团，前缀不区分大小写，较长的前缀优先
+func (d *Directory) SetPrefix(name string, group string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	name = strings.ToLower(name)
+	for i, p := range d.prefixes {
+		if p.name == name {
+			d.prefixes[i].group = group
+			return
+		}
+	}
+	d.prefixes = append(d.prefixes, prefix{name: name, group: group})
+	sort.SliceStable(d.prefixes, func(i, j int) bool { return len(d.prefixes[i].name) > len(d.prefixes[j].name) })
+}
+
+// 经纪所属的集团，name 为经纪队列中的经纪名称
+func (d *Directory) Group(id int64, name string) string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if g, ok := d.ids[id]; ok {
+		return g
+	}
+	lower := strings.ToLower(strings.TrimSpace(name))
+	for _, p := range d.prefixes {
+		if strings.HasPrefix(lower, p.name) {
+			d.learned[id] = p.group
+			return p.group
+		}
+	}
+	if g, ok := d.learned[id]; ok {
+		return g
+	}
+	return strings.TrimSpace(name)
+}
+
+// 写入所有 ID 映射，包括配置的和名称匹配时记下的，格式同 Read，按 ID 升序
+func (d *Directory) Write(w io.Writer) error {
+	d.mu.Lock()
+	ids := make(map[int64]string, len(d.ids)+len(d.learned))
+	for id, g := range d.learned {
+		ids[id] = g
+	}
+	for id, g := range d.ids {
+		ids[id] = g
+	}
+	d.mu.Unlock()
+	list := make([]int64, 0, len(ids))
+	for id := range ids {
+		list = append(list, id)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i] < list[j] })
+	bw := bufio.NewWriter(w)
+	for _, id := range list {
+		if _, err := fmt.Fprintf(bw, "%d,%s\n", id, ids[id]); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// 读取覆盖配置，每行为“ID,集团”或“前缀,集团”，ID 也可以是“起始ID-结束ID”的范围，# 开头的行为注释。
+// 格式错误时返回包含行号的错误，出错之前的行已经生效。
+func (d *Directory) Read(r io.Reader) error {
+	s := bufio.NewScanner(r)
+	n := 0
+	for s.Scan() {
+		n++
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.LastIndex(line, ",")
+		if i < 0 {
+			return fmt.Errorf("brokerqueue: line %d: missing group", n)
+		}
+		key, group := strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+		if key == "" || group == "" {
+			return fmt.Errorf("brokerqueue: line %d: empty broker or group", n)
+		}
+		lo, hi, ok, err := idRange(key)
+		if err != nil {
+			return fmt.Errorf("brokerqueue: line %d: %v", n, err)
+		}
+		if ok {
+			for id := lo; id <= hi; id++ {
+				d.Set(id, group)
+			}
+			continue
+		}
+		d.SetPrefix(key, group)
+	}
+	return s.Err()
+}
+
+// 读取覆盖配置文件，格式同 Read
+func (d *Directory) Load(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return d.Read(f)
+}
+
+// ID 范围最多包含的 ID 个数，港股经纪 ID 为4位数
+const maxIDRange = 10000
+
+// 解析 ID 或 ID 范围，不以数字开头时不是 ID，返回 false
+func idRange(s string) (int64, int64, bool, error) {
+	if s[0] < '0' || s[0] > '9' {
+		return 0, 0, false, nil
+	}
+	parts := strings.SplitN(s, "-", 2)
+	lo, err := strconv.ParseInt(strings.TrimSpace(parts[0]), 10, 64)
+	if err != nil {
+		return 0, 0, false, fmt.Errorf("invalid broker ID %q", s)
+	}
+	hi := lo
+	if len(parts) == 2 {
+		if hi, err = strconv.ParseInt(strings.TrimSpace(parts[1]), 10, 64); err != nil || hi < lo {
+			return 0, 0, false, fmt.Errorf("invalid broker ID range %q", s)
+		}
+		if hi-lo >= maxIDRange {
+			return 0, 0, false, fmt.Errorf("broker ID range %q too large", s)
+		}
+	}
+	return lo, hi, true, nil
+}
@@ -0,0 +1,296 @@
+// Package brokerqueue 跟踪港股经纪队列，记录每个经纪在买卖盘上的出现和消失，
+// 通过可覆盖的经纪商目录把经纪 ID 归入机构集团，并在关注的经纪出现或消失时发送事件。
+package brokerqueue
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+	"github.com/woxinyoumeng/go-futu-api/orderbook"
+)
+
+// 事件类型
+type EventType int
+
+const (
+	EventAppear    EventType = iota //经纪出现在队列中
+	EventDisappear                  //经纪从队列中消失
+)
+
+// 关注的经纪出现或消失的事件
+type Event struct {
+	Type     EventType
+	Security *futuapi.Security
+	Side     orderbook.Side
+	ID       int64
+	Name     string
+	Group    string
+	Pos      int32 //出现时的最好档位，消失时为消失前的最好档位
+	Time     time.Time
+}
+
+// 经纪在一个方向上的出现情况
+type Presence struct {
+	ID          int64
+	Name        string
+	Group       string
+	Count       int           //当前在队列中的条目数，0 表示已消失
+	BestPos     int32         //当前的最好档位
+	Since       time.Time     //本次出现的时间
+	LastSeen    time.Time     //最后一次在队列中的时间
+	Appearances int           //出现次数
+	total       time.Duration //已结束的出现累计时长
+}
+
+// 是否在队列中
+func (p *Presence) Present() bool {
+	return p.Count > 0
+}
+
+// 到 now 为止在队列中的累计时长
+func (p *Presence) Duration(now time.Time) time.Duration {
+	if p.Present() {
+		return p.total + now.Sub(p.Since)
+	}
+	return p.total
+}
+
+// 跟踪参数
+type Options struct {
+	Directory *Directory //经纪商目录，为空时使用内置目录
+	Buffer    int        //事件通道的缓冲大小
+}
+
+// 经纪队列跟踪器，可同时处理多只证券
+type Tracker struct {
+	mu     sync.RWMutex
+	dir    *Directory
+	out    chan *Event
+	ids    map[int64]bool
+	groups map[string]bool
+	states map[futuapi.Security]*state
+}
+
+type state struct {
+	queue    *futuapi.BrokerQueue
+	updated  time.Time
+	presence [2]map[int64]*Presence //按 orderbook.Side 索引
+}
+
+// 创建跟踪器
+func NewTracker(opts *Options) *Tracker {
+	if opts == nil {
+		opts = &Options{}
+	}
+	dir := opts.Directory
+	if dir == nil {
+		dir = NewDirectory()
+	}
+	return &Tracker{
+		dir:    dir,
+		out:    make(chan *Event, opts.Buffer),
+		ids:    make(map[int64]bool),
+		groups: make(map[string]bool),
+		states: make(map[futuapi.Security]*state),
+	}
+}
+
+// 经纪商目录
+func (t *Tracker) Directory() *Directory {
+	return t.dir
+}
+
+// 事件通道，有关注的经纪时调用方需要及时读取，否则更新会阻塞
+func (t *Tracker) Events() <-chan *Event {
+	return t.out
+}
+
+// 关注经纪 ID，出现或消失时发送事件
+func (t *Tracker) Watch(ids ...int64) {
+	t.mu.Lock()
+	for _, id := range ids {
+		t.ids[id] = true
+	}
+	t.mu.Unlock()
+}
+
+// 关注集团，集团内任一经纪出现或消失时发送事件
+func (t *Tracker) WatchGroups(groups ...string) {
+	t.mu.Lock()
+	for _, g := range groups {
+		t.groups[g] = true
+	}
+	t.mu.Unlock()
+}
+
+// 处理完整的经纪队列，at 为收到数据的时间
+func (t *Tracker) Update(q *futuapi.BrokerQueue, at time.Time) {
+	if q == nil || q.Security == nil {
+		return
+	}
+	t.mu.Lock()
+	s := t.states[*q.Security]
+	if s == nil {
+		s = &state{presence: [2]map[int64]*Presence{make(map[int64]*Presence), make(map[int64]*Presence)}}
+		t.states[*q.Security] = s
+	}
+	s.queue, s.updated = q, at
+	var events []*Event
+	events = append(events, t.update(q.Security, s.presence[orderbook.Bid], orderbook.Bid, q.Bids, at)...)
+	events = append(events, t.update(q.Security, s.presence[orderbook.Ask], orderbook.Ask, q.Asks, at)...)
+	t.mu.Unlock()
+	for _, e := range events {
+		t.out <- e
+	}
+}
+
+func (t *Tracker) update(sec *futuapi.Security, presence map[int64]*Presence, side orderbook.Side, list []*futuapi.Broker, at time.Time) []*Event {
+	type entry struct {
+		name  string
+		count int
+		pos   int32
+	}
+	current := make(map[int64]*entry)
+	for _, b := range list {
+		if b == nil {
+			continue
+		}
+		e := current[b.ID]
+		if e == nil {
+			e = &entry{name: b.Name, pos: b.Pos}
+			current[b.ID] = e
+		}
+		e.count++
+		if b.Pos < e.pos {
+			e.pos = b.Pos
+		}
+	}
+	var events []*Event
+	event := func(typ EventType, p *Presence) {
+		if t.ids[p.ID] || t.groups[p.Group] {
+			events = append(events, &Event{Type: typ, Security: sec, Side: side, ID: p.ID, Name: p.Name, Group: p.Group, Pos: p.BestPos, Time: at})
+		}
+	}
+	for id, e := range current {
+		p := presence[id]
+		if p == nil {
+			p = &Presence{ID: id, Name: e.name, Group: t.dir.Group(id, e.name)}
+			presence[id] = p
+		}
+		appeared := !p.Present()
+		p.Count, p.BestPos, p.LastSeen = e.count, e.pos, at
+		if appeared {
+			p.Since = at
+			p.Appearances++
+			event(EventAppear, p)
+		}
+	}
+	for id, p := range presence {
+		if p.Present() && current[id] == nil {
+			p.total += at.Sub(p.Since)
+			p.Count = 0
+			event(EventDisappear, p)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Pos < events[j].Pos })
+	return events
+}
+
+// 证券最新的经纪队列，没有数据时返回空
+func (t *Tracker) Queue(sec *futuapi.Security) *futuapi.BrokerQueue {
+	if sec == nil {
+		return nil
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if s := t.states[*sec]; s != nil {
+		return s.queue
+	}
+	return nil
+}
+
+// 证券一个方向上出现过的所有经纪，当前在队列中的按档位在前，已消失的按最后出现时间在后
+func (t *Tracker) Presence(sec *futuapi.Security, side orderbook.Side) []Presence {
+	if sec == nil {
+		return nil
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	s := t.states[*sec]
+	if s == nil {
+		return nil
+	}
+	list := make([]Presence, 0, len(s.presence[side]))
+	for _, p := range s.presence[side] {
+		list = append(list, *p)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if a.Present() != b.Present() {
+			return a.Present()
+		}
+		if a.Present() {
+			if a.BestPos != b.BestPos {
+				return a.BestPos < b.BestPos
+			}
+		} else if !a.LastSeen.Equal(b.LastSeen) {
+			return a.LastSeen.After(b.LastSeen)
+		}
+		return a.ID < b.ID
+	})
+	return list
+}
+
+// 证券一个方向上当前各集团的队列条目数
+func (t *Tracker) Groups(sec *futuapi.Security, side orderbook.Side) map[string]int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	groups := make(map[string]int)
+	if sec == nil {
+		return groups
+	}
+	if s := t.states[*sec]; s != nil {
+		for _, p := range s.presence[side] {
+			if p.Present() {
+				groups[p.Group] += p.Count
+			}
+		}
+	}
+	return groups
+}
+
+// 通过 GetBrokerQueue 获取证券的经纪队列。接口调用遵守频率限制。
+func (t *Tracker) Load(ctx context.Context, api *futuapi.FutuAPI, securities []*futuapi.Security) error {
+	for _, sec := range securities {
+		if err := api.WaitRateLimit(ctx, futuapi.ProtoIDQotGetBroker); err != nil {
+			return err
+		}
+		q, err := api.GetBrokerQueue(ctx, sec)
+		if err != nil {
+			return err
+		}
+		t.Update(q, time.Now())
+	}
+	return nil
+}
+
+// 从推送通道读取经纪队列，调用方需要订阅经纪队列推送。
+// ctx 结束时返回 ErrInterrupted，通道关闭时返回 ErrChannelClosed。
+func (t *Tracker) Run(ctx context.Context, ch <-chan *futuapi.UpdateBrokerResp) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return futuapi.ErrInterrupted
+		case resp, ok := <-ch:
+			if !ok {
+				return futuapi.ErrChannelClosed
+			}
+			if resp.Err == nil {
+				t.Update(resp.BrokerQueue, time.Now())
+			}
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package brokerqueue
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hurisheng/go-futu-api/pb/qotcommon"
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+	"github.com/woxinyoumeng/go-futu-api/orderbook"
+)
+
+func TestDirectory(t *testing.T) {
+	d := NewDirectory()
+	if g := d.Group(1, "高盛(亚洲)证券"); g != "Goldman Sachs" {
+		t.Errorf("group = %q", g)
+	}
+	if g := d.Group(2, "Some Local Broker"); g != "Some Local Broker" {
+		t.Errorf("unknown group = %q", g)
+	}
+	if err := d.Read(strings.NewReader("# overrides\n6996-6998,Retail\nsome local,Local\n")); err != nil {
+		t.Fatal(err)
+	}
+	if g := d.Group(6997, "高盛(亚洲)证券"); g != "Retail" {
+		t.Errorf("id override = %q", g)
+	}
+	if g := d.Group(2, "Some Local Broker"); g != "Local" {
+		t.Errorf("prefix override = %q", g)
+	}
+	// 名称相近的机构分别匹配
+	for name, want := range map[string]string{"中信证券经纪(香港)": "CITIC", "中信建投(国际)证券": "China Securities", "中信里昂证券": "CLSA"} {
+		if g := d.Group(3, name); g != want {
+			t.Errorf("group of %s = %q, want %q", name, g, want)
+		}
+	}
+	// 繁体名称，匹配过的 ID 之后没有名称也能归类，并可以保存后读取
+	d = NewDirectory()
+	for name, want := range map[string]string{"滙豐證券經紀(香港)": "HSBC", "中信證券經紀(香港)": "CITIC", "國泰君安證券(香港)": "Guotai Junan"} {
+		if g := d.Group(4, name); g != want {
+			t.Errorf("group of %s = %q, want %q", name, g, want)
+		}
+	}
+	if g := d.Group(5, "摩根士丹利亚洲"); g != "Morgan Stanley" {
+		t.Errorf("group = %q", g)
+	}
+	if g := d.Group(5, ""); g != "Morgan Stanley" {
+		t.Errorf("learned group = %q", g)
+	}
+	var buf strings.Builder
+	if err := d.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	d = NewDirectory()
+	if err := d.Read(strings.NewReader(buf.String())); err != nil {
+		t.Fatal(err)
+	}
+	if g := d.Group(4, ""); g != "Guotai Junan" {
+		t.Errorf("read group = %q, written %q", g, buf.String())
+	}
+	for _, c := range []struct {
+		text string
+		line string
+	}{
+		{"# comment\n\n1234 Retail\n", "line 3"},
+		{"1000,Retail\n20-10,Retail\n", "line 2"},
+		{"1000,Retail\n0-9223372036854775806,Retail\n", "line 2"},
+		{"12x,Retail\n", "line 1"},
+		{" ,Retail\n", "line 1"},
+	} {
+		if err := NewDirectory().Read(strings.NewReader(c.text)); err == nil || !strings.Contains(err.Error(), c.line) {
+			t.Errorf("Read(%q) = %v, want error at %s", c.text, err, c.line)
+		}
+	}
+}
+
+func TestTracker(t *testing.T) {
+	sec := &futuapi.Security{Market: qotcommon.QotMarket_QotMarket_HK_Security, Code: "00700"}
+	tr := NewTracker(&Options{Buffer: 10})
+	tr.WatchGroups("Morgan Stanley")
+	t0 := time.Date(2024, 3, 20, 10, 0, 0, 0, time.UTC)
+	tr.Update(&futuapi.BrokerQueue{
+		Security: sec,
+		Bids:     []*futuapi.Broker{{ID: 100, Name: "Morgan Stanley HK", Pos: 1}, {ID: 200, Name: "Futu", Pos: 1}, {ID: 100, Name: "Morgan Stanley HK", Pos: 2}},
+	}, t0)
+	tr.Update(&futuapi.BrokerQueue{
+		Security: sec,
+		Bids:     []*futuapi.Broker{{ID: 200, Name: "Futu", Pos: 1}},
+	}, t0.Add(time.Minute))
+
+	if e := <-tr.Events(); e.Type != EventAppear || e.ID != 100 || e.Side != orderbook.Bid || e.Pos != 1 {
+		t.Errorf("appear event %+v", e)
+	}
+	if e := <-tr.Events(); e.Type != EventDisappear || e.ID != 100 || !e.Time.Equal(t0.Add(time.Minute)) {
+		t.Errorf("disappear event %+v", e)
+	}
+	select {
+	case e := <-tr.Events():
+		t.Errorf("unexpected event %+v", e)
+	default:
+	}
+
+	if tr.Queue(nil) != nil || tr.Presence(nil, orderbook.Bid) != nil || len(tr.Groups(nil, orderbook.Bid)) != 0 {
+		t.Error("nil security")
+	}
+	list := tr.Presence(sec, orderbook.Bid)
+	if len(list) != 2 || list[0].ID != 200 || list[1].Present() || list[1].Duration(t0.Add(time.Hour)) != time.Minute {
+		t.Errorf("presence %+v", list)
+	}
+	if g := tr.Groups(sec, orderbook.Bid); len(g) != 1 || g["Futu"] != 1 {
+		t.Errorf("groups %v", g)
+	}
+}
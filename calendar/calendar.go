@@ -0,0 +1,293 @@
+// Package calendar 根据 RequestTradingDays 返回的交易日和各市场的交易时段提供交易日历，
+// 可以判断某个时间是否开市、下一次开市或收市的时间、是否半日市，以及期货合约的交易时段，
+// 并在开市、收市时发出事件，便于围绕港股、美股、A股的交易时段安排任务。
+package calendar
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/hurisheng/go-futu-api/pb/qotcommon"
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+	"github.com/woxinyoumeng/go-futu-api/bar"
+)
+
+var (
+	// 在查找范围内没有交易时段
+	ErrNoSession = errors.New("calendar: no session")
+)
+
+// 向前或向后查找交易时段的最大天数
+const maxSearchDays = 400
+
+const dateLayout = "2006-01-02"
+
+// 一个交易时段的开市和收市时间，区间为 [Open, Close)
+type Period struct {
+	Open  time.Time
+	Close time.Time
+}
+
+// 是否包含时间 t
+func (p Period) Contains(t time.Time) bool {
+	return !t.Before(p.Open) && t.Before(p.Close)
+}
+
+// 市场的时区和常规交易时段
+type Market struct {
+	Location *time.Location
+	Sessions []bar.Session
+}
+
+// 交易日市场的默认设置，深沪股通按A股时段，港股通按港股时段
+func DefaultMarket(market qotcommon.TradeDateMarket) *Market {
+	var m *bar.Options
+	switch market {
+	case qotcommon.TradeDateMarket_TradeDateMarket_HK, qotcommon.TradeDateMarket_TradeDateMarket_ST:
+		m = bar.MarketOptions(qotcommon.QotMarket_QotMarket_HK_Security)
+	case qotcommon.TradeDateMarket_TradeDateMarket_US:
+		m = bar.MarketOptions(qotcommon.QotMarket_QotMarket_US_Security)
+	case qotcommon.TradeDateMarket_TradeDateMarket_CN, qotcommon.TradeDateMarket_TradeDateMarket_NT:
+		m = bar.MarketOptions(qotcommon.QotMarket_QotMarket_CNSH_Security)
+	default:
+		m = bar.MarketOptions(qotcommon.QotMarket_QotMarket_Unknown)
+	}
+	return &Market{Location: m.Location, Sessions: m.Sessions}
+}
+
+// 交易日历，按交易日市场和年份缓存交易日，可并发使用
+type Calendar struct {
+	api *futuapi.FutuAPI
+
+	mu      sync.Mutex
+	markets map[qotcommon.TradeDateMarket]*Market
+	days    map[qotcommon.TradeDateMarket]map[string]qotcommon.TradeDateType
+	years   map[qotcommon.TradeDateMarket]map[int]bool
+}
+
+// 创建交易日历，交易日在第一次用到时按年请求
+func New(api *futuapi.FutuAPI) *Calendar {
+	return &Calendar{
+		api:     api,
+		markets: make(map[qotcommon.TradeDateMarket]*Market),
+		days:    make(map[qotcommon.TradeDateMarket]map[string]qotcommon.TradeDateType),
+		years:   make(map[qotcommon.TradeDateMarket]map[int]bool),
+	}
+}
+
+// 修改市场的时区和交易时段，例如包含美股盘前盘后
+func (c *Calendar) SetMarket(market qotcommon.TradeDateMarket, m *Market) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.markets[market] = m
+}
+
+// 市场的时区和交易时段
+func (c *Calendar) Market(market qotcommon.TradeDateMarket) *Market {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.market(market)
+}
+
+func (c *Calendar) market(market qotcommon.TradeDateMarket) *Market {
+	m, ok := c.markets[market]
+	if !ok {
+		m = DefaultMarket(market)
+		c.markets[market] = m
+	}
+	return m
+}
+
+// 直接设置交易日，用于离线使用或测试，设置后对应年份不再请求
+func (c *Calendar) SetTradingDays(market qotcommon.TradeDateMarket, year int, days []*futuapi.TradeDate) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setDays(market, year, days)
+}
+
+func (c *Calendar) setDays(market qotcommon.TradeDateMarket, year int, days []*futuapi.TradeDate) {
+	m, ok := c.days[market]
+	if !ok {
+		m = make(map[string]qotcommon.TradeDateType)
+		c.days[market] = m
+	}
+	for _, d := range days {
+		if d != nil && len(d.Time) >= len(dateLayout) {
+			m[d.Time[:len(dateLayout)]] = d.TradeDateType
+		}
+	}
+	y, ok := c.years[market]
+	if !ok {
+		y = make(map[int]bool)
+		c.years[market] = y
+	}
+	y[year] = true
+}
+
+// 确保年份的交易日已缓存
+func (c *Calendar) load(ctx context.Context, market qotcommon.TradeDateMarket, year int) error {
+	c.mu.Lock()
+	loaded := c.years[market][year]
+	c.mu.Unlock()
+	if loaded {
+		return nil
+	}
+	if err := c.api.WaitRateLimit(ctx, futuapi.ProtoIDQotRequestTradeDate); err != nil {
+		return err
+	}
+	begin := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC)
+	days, err := c.api.RequestTradingDays(ctx, market, begin.Format(dateLayout), end.Format(dateLayout))
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.setDays(market, year, days)
+	c.mu.Unlock()
+	return nil
+}
+
+// 日期 day 按市场当地日期是否为交易日，以及交易日类型（全天、上午、下午）
+func (c *Calendar) TradingDay(ctx context.Context, market qotcommon.TradeDateMarket, day time.Time) (qotcommon.TradeDateType, bool, error) {
+	day = day.In(c.Market(market).Location)
+	if err := c.load(ctx, market, day.Year()); err != nil {
+		return 0, false, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t, ok := c.days[market][day.Format(dateLayout)]
+	return t, ok, nil
+}
+
+// 是否为半日市
+func (c *Calendar) IsHalfDay(ctx context.Context, market qotcommon.TradeDateMarket, day time.Time) (bool, error) {
+	t, ok, err := c.TradingDay(ctx, market, day)
+	if err != nil || !ok {
+		return false, err
+	}
+	return t != qotcommon.TradeDateType_TradeDateType_Whole, nil
+}
+
+// 日期 day 按市场当地日期的交易时段，非交易日返回空。
+// 半日市只保留上午（开始时间在12点之前）或下午的时段。
+func (c *Calendar) Sessions(ctx context.Context, market qotcommon.TradeDateMarket, day time.Time) ([]Period, error) {
+	t, ok, err := c.TradingDay(ctx, market, day)
+	if err != nil || !ok {
+		return nil, err
+	}
+	m := c.Market(market)
+	day = day.In(m.Location)
+	midnight := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, m.Location)
+	var list []Period
+	for _, s := range m.Sessions {
+		switch t {
+		case qotcommon.TradeDateType_TradeDateType_Morning:
+			if s.Begin >= 12*time.Hour {
+				continue
+			}
+		case qotcommon.TradeDateType_TradeDateType_Afternoon:
+			if s.Begin < 12*time.Hour {
+				continue
+			}
+		}
+		list = append(list, Period{Open: clock(midnight, s.Begin), Close: clock(midnight, s.End)})
+	}
+	return list, nil
+}
+
+// 当天0点之后 d 的当地时间，按日期和时钟计算，不受夏令时切换影响
+func clock(midnight time.Time, d time.Duration) time.Time {
+	h, m, s := int(d/time.Hour), int(d%time.Hour/time.Minute), int(d%time.Minute/time.Second)
+	return time.Date(midnight.Year(), midnight.Month(), midnight.Day(), h, m, s, int(d%time.Second), midnight.Location())
+}
+
+// 时间 t 所在的交易时段，不在交易时段内时 ok 为 false
+func (c *Calendar) Session(ctx context.Context, market qotcommon.TradeDateMarket, t time.Time) (Period, bool, error) {
+	list, err := c.Sessions(ctx, market, t)
+	if err != nil {
+		return Period{}, false, err
+	}
+	for _, p := range list {
+		if p.Contains(t) {
+			return p, true, nil
+		}
+	}
+	return Period{}, false, nil
+}
+
+// 时间 t 是否开市
+func (c *Calendar) IsOpen(ctx context.Context, market qotcommon.TradeDateMarket, t time.Time) (bool, error) {
+	_, ok, err := c.Session(ctx, market, t)
+	return ok, err
+}
+
+// 时间 t 之后（不含）第一个开市的交易时段
+func (c *Calendar) NextOpen(ctx context.Context, market qotcommon.TradeDateMarket, t time.Time) (Period, error) {
+	return c.next(ctx, market, t, func(p Period) bool { return p.Open.After(t) })
+}
+
+// 时间 t 之后（不含）第一个收市的交易时段，t 在交易时段内时为当前时段
+func (c *Calendar) NextClose(ctx context.Context, market qotcommon.TradeDateMarket, t time.Time) (Period, error) {
+	return c.next(ctx, market, t, func(p Period) bool { return p.Close.After(t) })
+}
+
+func (c *Calendar) next(ctx context.Context, market qotcommon.TradeDateMarket, t time.Time, match func(Period) bool) (Period, error) {
+	day := t.In(c.Market(market).Location)
+	for i := 0; i < maxSearchDays; i++ {
+		list, err := c.Sessions(ctx, market, day.AddDate(0, 0, i))
+		if err != nil {
+			return Period{}, err
+		}
+		for _, p := range list {
+			if match(p) {
+				return p, nil
+			}
+		}
+	}
+	return Period{}, ErrNoSession
+}
+
+// 全局状态中交易日市场对应的市场状态，深沪股通取上海市场，港股通取港股市场
+func MarketState(s *futuapi.GlobalState, market qotcommon.TradeDateMarket) qotcommon.QotMarketState {
+	if s == nil {
+		return qotcommon.QotMarketState_QotMarketState_None
+	}
+	switch market {
+	case qotcommon.TradeDateMarket_TradeDateMarket_HK, qotcommon.TradeDateMarket_TradeDateMarket_ST:
+		return s.MarketHK
+	case qotcommon.TradeDateMarket_TradeDateMarket_US:
+		return s.MarketUS
+	case qotcommon.TradeDateMarket_TradeDateMarket_CN, qotcommon.TradeDateMarket_TradeDateMarket_NT:
+		return s.MarketSH
+	}
+	return qotcommon.QotMarketState_QotMarketState_None
+}
+
+// 通过 GetGlobalState 获取市场当前的状态
+func (c *Calendar) State(ctx context.Context, market qotcommon.TradeDateMarket) (qotcommon.QotMarketState, error) {
+	s, err := c.api.GetGlobalState(ctx)
+	if err != nil {
+		return qotcommon.QotMarketState_QotMarketState_None, err
+	}
+	return MarketState(s, market), nil
+}
+
+// 通过 GetMarketState 获取证券所在市场的当前状态，遵守频率限制
+func (c *Calendar) SecurityStates(ctx context.Context, securities []*futuapi.Security) (map[futuapi.Security]qotcommon.QotMarketState, error) {
+	if err := c.api.WaitRateLimit(ctx, futuapi.ProtoIDQotGetMarketState); err != nil {
+		return nil, err
+	}
+	list, err := c.api.GetMarketState(ctx, securities)
+	if err != nil {
+		return nil, err
+	}
+	states := make(map[futuapi.Security]qotcommon.QotMarketState, len(list))
+	for _, m := range list {
+		if m != nil && m.Security != nil {
+			states[*m.Security] = m.MarketState
+		}
+	}
+	return states, nil
+}
@@ -0,0 +1,79 @@
+package calendar
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hurisheng/go-futu-api/pb/qotcommon"
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+)
+
+func TestSessions(t *testing.T) {
+	hk := qotcommon.TradeDateMarket_TradeDateMarket_HK
+	c := New(nil)
+	c.SetTradingDays(hk, 2021, []*futuapi.TradeDate{
+		{Time: "2021-12-23", TradeDateType: qotcommon.TradeDateType_TradeDateType_Whole},
+		{Time: "2021-12-24", TradeDateType: qotcommon.TradeDateType_TradeDateType_Morning},
+		{Time: "2021-12-29", TradeDateType: qotcommon.TradeDateType_TradeDateType_Whole},
+	})
+	c.SetTradingDays(hk, 2022, nil)
+	loc := c.Market(hk).Location
+	ctx := context.Background()
+	at := func(d, h, m int) time.Time { return time.Date(2021, 12, d, h, m, 0, 0, loc) }
+
+	if ok, _ := c.IsOpen(ctx, hk, at(23, 10, 0)); !ok {
+		t.Error("expected open at 10:00")
+	}
+	if ok, _ := c.IsOpen(ctx, hk, at(23, 12, 30)); ok {
+		t.Error("expected closed at lunch")
+	}
+	if half, _ := c.IsHalfDay(ctx, hk, at(24, 0, 0)); !half {
+		t.Error("expected half day")
+	}
+	if ok, _ := c.IsOpen(ctx, hk, at(24, 14, 0)); ok {
+		t.Error("expected closed in the afternoon of a half day")
+	}
+	p, err := c.NextOpen(ctx, hk, at(24, 10, 0))
+	if err != nil || !p.Open.Equal(at(29, 9, 30)) {
+		t.Errorf("NextOpen = %v, %v", p.Open, err)
+	}
+	p, err = c.NextClose(ctx, hk, at(24, 10, 0))
+	if err != nil || !p.Close.Equal(at(24, 12, 0)) {
+		t.Errorf("NextClose = %v, %v", p.Close, err)
+	}
+}
+
+func TestFutureSession(t *testing.T) {
+	hk := qotcommon.TradeDateMarket_TradeDateMarket_HK
+	c := New(nil)
+	c.SetTradingDays(hk, 2021, []*futuapi.TradeDate{{Time: "2021-12-23"}})
+	info := &futuapi.FutureInfo{
+		TimeZone:  "UTC+8",
+		TradeTime: []*futuapi.TradeTime{{Begin: 555, End: 720}, {Begin: 780, End: 990}, {Begin: 1035, End: 180}},
+	}
+	loc := FutureLocation(info)
+	p, ok, err := c.FutureSession(context.Background(), hk, info, time.Date(2021, 12, 24, 2, 0, 0, 0, loc))
+	if err != nil || !ok || !p.Close.Equal(time.Date(2021, 12, 24, 3, 0, 0, 0, loc)) {
+		t.Errorf("FutureSession = %v, %v, %v", p, ok, err)
+	}
+}
+
+func TestWatchSameTime(t *testing.T) {
+	hk, cn := qotcommon.TradeDateMarket_TradeDateMarket_HK, qotcommon.TradeDateMarket_TradeDateMarket_CN
+	c := New(nil)
+	for _, m := range []qotcommon.TradeDateMarket{hk, cn} {
+		c.SetTradingDays(m, 2021, []*futuapi.TradeDate{{Time: "2021-12-23", TradeDateType: qotcommon.TradeDateType_TradeDateType_Whole}})
+		c.SetTradingDays(m, 2022, nil)
+	}
+	loc := c.Market(hk).Location
+	ctx := context.Background()
+	// 港股和A股都在 09:30 开市、13:00 开始午后交易，两个市场的事件都要发送
+	for _, at := range []time.Time{time.Date(2021, 12, 23, 9, 0, 0, 0, loc), time.Date(2021, 12, 23, 12, 0, 0, 0, loc)} {
+		events := c.nextEvents(ctx, at, []qotcommon.TradeDateMarket{hk, cn})
+		if len(events) != 2 || events[0].Market != hk || events[1].Market != cn || !events[0].Time.Equal(events[1].Time) ||
+			events[0].Type != EventOpen || events[1].Type != EventOpen {
+			t.Errorf("events at %v: %+v", at, events)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package calendar
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hurisheng/go-futu-api/pb/qotcommon"
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+	"github.com/woxinyoumeng/go-futu-api/bar"
+)
+
+// 期货合约的时区，TimeZone 可以是时区名称或 UTC+8、GMT-5 这样的偏移，无法识别时为 UTC
+func FutureLocation(info *futuapi.FutureInfo) *time.Location {
+	if info == nil || info.TimeZone == "" {
+		return time.UTC
+	}
+	name := strings.TrimSpace(info.TimeZone)
+	if loc, err := time.LoadLocation(name); err == nil {
+		return loc
+	}
+	for _, prefix := range []string{"UTC", "GMT"} {
+		if !strings.HasPrefix(strings.ToUpper(name), prefix) {
+			continue
+		}
+		offset := name[len(prefix):]
+		if offset == "" {
+			return time.UTC
+		}
+		h, err := strconv.ParseFloat(offset, 64)
+		if err != nil {
+			break
+		}
+		return time.FixedZone(name, int(h*3600))
+	}
+	return time.UTC
+}
+
+// 期货合约的交易时段，结束时间不大于开始时间的时段跨过0点，结束时间加一天
+func FutureSessions(info *futuapi.FutureInfo) []bar.Session {
+	if info == nil {
+		return nil
+	}
+	list := make([]bar.Session, 0, len(info.TradeTime))
+	for _, t := range info.TradeTime {
+		if t == nil {
+			continue
+		}
+		s := bar.Session{
+			Begin: time.Duration(t.Begin * float64(time.Minute)),
+			End:   time.Duration(t.End * float64(time.Minute)),
+		}
+		if s.End <= s.Begin {
+			s.End += 24 * time.Hour
+		}
+		list = append(list, s)
+	}
+	return list
+}
+
+// 期货合约在交易日 day 的交易时段，交易日按 market 的交易日历判断，日期按合约时区计算。
+// 夜盘可能在第二天收市。
+func (c *Calendar) FuturePeriods(ctx context.Context, market qotcommon.TradeDateMarket, info *futuapi.FutureInfo, day time.Time) ([]Period, error) {
+	loc := FutureLocation(info)
+	day = day.In(loc)
+	midnight := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, loc)
+	if _, ok, err := c.TradingDay(ctx, market, midnight); err != nil || !ok {
+		return nil, err
+	}
+	var list []Period
+	for _, s := range FutureSessions(info) {
+		list = append(list, Period{Open: midnight.Add(s.Begin), Close: midnight.Add(s.End)})
+	}
+	return list, nil
+}
+
+// 时间 t 所在的期货交易时段，包括前一交易日跨过0点的夜盘
+func (c *Calendar) FutureSession(ctx context.Context, market qotcommon.TradeDateMarket, info *futuapi.FutureInfo, t time.Time) (Period, bool, error) {
+	for _, day := range []time.Time{t.AddDate(0, 0, -1), t} {
+		list, err := c.FuturePeriods(ctx, market, info, day)
+		if err != nil {
+			return Period{}, false, err
+		}
+		for _, p := range list {
+			if p.Contains(t) {
+				return p, true, nil
+			}
+		}
+	}
+	return Period{}, false, nil
+}
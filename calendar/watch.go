@@ -0,0 +1,117 @@
+package calendar
+
+import (
+	"context"
+	"time"
+
+	"github.com/hurisheng/go-futu-api/pb/qotcommon"
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+)
+
+// 事件类型
+type EventType int
+
+const (
+	EventOpen  EventType = iota //交易时段开始
+	EventClose                  //交易时段结束
+	EventState                  //GetGlobalState 返回的市场状态变化
+)
+
+// 交易时段事件
+type Event struct {
+	Type   EventType
+	Market qotcommon.TradeDateMarket
+	Time   time.Time                //事件时间
+	Period Period                   //开市、收市事件对应的交易时段
+	State  qotcommon.QotMarketState //市场状态变化事件的新状态
+	Prev   qotcommon.QotMarketState //市场状态变化事件的原状态
+}
+
+// 获取交易日出错后的重试间隔
+const retryInterval = time.Minute
+
+// 按交易日历在各市场开市、收市时向 ch 发送事件，阻塞直到 ctx 结束，返回 ErrInterrupted。
+// 同一时刻的多个事件（如港股和A股同时开市）都会发送。事件时间按本地时钟计算，不依赖服务器推送。
+func (c *Calendar) Watch(ctx context.Context, ch chan<- *Event, markets ...qotcommon.TradeDateMarket) error {
+	for {
+		now := time.Now()
+		next := c.nextEvents(ctx, now, markets)
+		wait := retryInterval
+		if len(next) > 0 {
+			wait = next[0].Time.Sub(now)
+		}
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return futuapi.ErrInterrupted
+		case <-t.C:
+		}
+		for _, e := range next {
+			select {
+			case <-ctx.Done():
+				return futuapi.ErrInterrupted
+			case ch <- e:
+			}
+		}
+	}
+}
+
+// 各市场在 now 之后最早发生的事件，同一时刻的事件全部返回，按市场参数的顺序排列
+func (c *Calendar) nextEvents(ctx context.Context, now time.Time, markets []qotcommon.TradeDateMarket) []*Event {
+	var next []*Event
+	for _, m := range markets {
+		for _, e := range c.upcoming(ctx, m, now) {
+			switch {
+			case len(next) == 0 || e.Time.Before(next[0].Time):
+				next = []*Event{e}
+			case e.Time.Equal(next[0].Time):
+				next = append(next, e)
+			}
+		}
+	}
+	return next
+}
+
+// 市场在 now 之后的下一个开市和收市事件，出错时返回空
+func (c *Calendar) upcoming(ctx context.Context, market qotcommon.TradeDateMarket, now time.Time) []*Event {
+	var list []*Event
+	if p, err := c.NextOpen(ctx, market, now); err == nil {
+		list = append(list, &Event{Type: EventOpen, Market: market, Time: p.Open, Period: p})
+	}
+	if p, err := c.NextClose(ctx, market, now); err == nil {
+		list = append(list, &Event{Type: EventClose, Market: market, Time: p.Close, Period: p})
+	}
+	return list
+}
+
+// 每隔 interval 通过 GetGlobalState 查询市场状态，状态变化时向 ch 发送 EventState 事件，
+// 第一次查询的状态也会发送。阻塞直到 ctx 结束，返回 ErrInterrupted。
+func (c *Calendar) WatchState(ctx context.Context, interval time.Duration, ch chan<- *Event, markets ...qotcommon.TradeDateMarket) error {
+	states := make(map[qotcommon.TradeDateMarket]qotcommon.QotMarketState)
+	timer := time.NewTicker(interval)
+	defer timer.Stop()
+	for {
+		if s, err := c.api.GetGlobalState(ctx); err == nil {
+			now := time.Now()
+			for _, m := range markets {
+				state := MarketState(s, m)
+				prev, ok := states[m]
+				if ok && prev == state {
+					continue
+				}
+				states[m] = state
+				select {
+				case <-ctx.Done():
+					return futuapi.ErrInterrupted
+				case ch <- &Event{Type: EventState, Market: m, Time: now, State: state, Prev: prev}:
+				}
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return futuapi.ErrInterrupted
+		case <-timer.C:
+		}
+	}
+}
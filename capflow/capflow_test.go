@@ -0,0 +1,95 @@
+package capflow
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hurisheng/go-futu-api/pb/qotcommon"
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+)
+
+func TestCollector(t *testing.T) {
+	sec := &futuapi.Security{Market: qotcommon.QotMarket_QotMarket_HK_Security, Code: "00700"}
+	t0 := time.Date(2024, 3, 20, 2, 0, 0, 0, time.UTC)
+	sample := func(min int, inflow float64, big float64) *Sample {
+		flow := &futuapi.CapitalFlow{LastValidTIme: "2024-03-20 10:00:00", FlowItems: []*futuapi.CapitalFlowItem{{InFlow: inflow}}}
+		dist := &futuapi.CapitalDistribution{InBig: big}
+		return NewSample(sec, t0.Add(time.Duration(min)*time.Minute), flow, dist)
+	}
+	c := New(nil, &Options{Buffer: 10})
+	rule := &Rule{Name: "inflow", Field: FieldNetInflow, Window: 5 * time.Minute, Threshold: 5e6}
+	c.AddRule(rule)
+
+	if _, alerts := c.Add(sample(0, 1e6, 1e6)); len(alerts) != 0 {
+		t.Errorf("first sample alerts %v", alerts)
+	}
+	change, alerts := c.Add(sample(1, 4e6, 3e6))
+	if change == nil || change.NetInflow != 3e6 || change.Big != 2e6 || len(alerts) != 0 {
+		t.Errorf("change %+v alerts %v", change, alerts)
+	}
+	// 5 分钟内净流入 6 百万，触发一次，条件仍满足时不重复提醒
+	if _, alerts := c.Add(sample(3, 7e6, 3e6)); len(alerts) != 1 || alerts[0].Value != 6e6 {
+		t.Errorf("alerts %v", alerts)
+	}
+	if _, alerts := c.Add(sample(4, 8e6, 3e6)); len(alerts) != 0 {
+		t.Errorf("repeated alerts %v", alerts)
+	}
+	if a := <-c.Alerts(); a.Rule != rule {
+		t.Errorf("alert %+v", a)
+	}
+	// 窗口外的采样不参与比较
+	if ch := c.Change(sec, 2*time.Minute); ch.From.Time != t0.Add(3*time.Minute) || ch.NetInflow != 1e6 {
+		t.Errorf("window change %+v", ch)
+	}
+	if n := len(c.Samples(sec)); n != 4 {
+		t.Errorf("samples %d", n)
+	}
+}
+
+func TestCollectorFirstSample(t *testing.T) {
+	sec := &futuapi.Security{Market: qotcommon.QotMarket_QotMarket_HK_Security, Code: "00700"}
+	t0 := time.Date(2024, 3, 20, 2, 0, 0, 0, time.UTC)
+	sample := func(min int, inflow float64) *Sample {
+		flow := &futuapi.CapitalFlow{LastValidTIme: "2024-03-20 10:00:00", FlowItems: []*futuapi.CapitalFlowItem{{InFlow: inflow}}}
+		return NewSample(sec, t0.Add(time.Duration(min)*time.Minute), flow, nil)
+	}
+	c := New(nil, nil)
+	c.AddRule(&Rule{Name: "inflow", Field: FieldNetInflow, Window: 5 * time.Minute, Threshold: 5e6})
+	// 启动后第一次采样的当日累计值已超过阈值，不是窗口内的变化，不提醒
+	if _, alerts := c.Add(sample(0, 2e7)); len(alerts) != 0 {
+		t.Errorf("first sample alerts %v", alerts)
+	}
+	if ch := c.Change(sec, 5*time.Minute); ch != nil {
+		t.Errorf("change without baseline %+v", ch)
+	}
+	// 上一次采样在窗口外时也没有起点
+	if _, alerts := c.Add(sample(10, 3e7)); len(alerts) != 0 {
+		t.Errorf("alerts across window %v", alerts)
+	}
+	if _, alerts := c.Add(sample(12, 3.6e7)); len(alerts) != 1 || alerts[0].Value != 6e6 {
+		t.Errorf("alerts %v", alerts)
+	}
+}
+
+func TestCollectorPollErrors(t *testing.T) {
+	good := &futuapi.Security{Market: qotcommon.QotMarket_QotMarket_HK_Security, Code: "00700"}
+	bad := &futuapi.Security{Market: qotcommon.QotMarket_QotMarket_HK_Security, Code: "99999"}
+	c := New(nil, nil)
+	c.Watch(bad, good)
+	c.fetch = func(ctx context.Context, sec *futuapi.Security) (*Sample, error) {
+		if *sec == *bad {
+			return nil, errors.New("unknown security")
+		}
+		return NewSample(sec, time.Now(), nil, &futuapi.CapitalDistribution{InBig: 1}), nil
+	}
+	err := c.Poll(context.Background())
+	errs, ok := err.(Errors)
+	if !ok || len(errs) != 1 || *errs[0].Security != *bad {
+		t.Fatalf("poll error %v", err)
+	}
+	if c.Latest(good) == nil || c.LastError(good) != nil || c.LastError(bad) == nil {
+		t.Errorf("good security not polled after error")
+	}
+}
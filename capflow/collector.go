@@ -0,0 +1,312 @@
+package capflow
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+)
+
+// 默认轮询间隔
+const DefaultInterval = time.Minute
+
+// 采集参数
+type Options struct {
+	Interval   time.Duration //轮询间隔，0 为 DefaultInterval
+	MaxSamples int           //每只证券保留的最大采样数，0 为不限制
+	Buffer     int           //提醒通道的缓冲大小，0 为不发送提醒
+	OnError    func(*Error)  //Run 中采集证券出错时调用，为空时只记录在 LastError
+}
+
+// 采集一只证券出错
+type Error struct {
+	Security *futuapi.Security
+	Err      error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("capflow: %v: %v", e.Security, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// 一次采集中部分证券出错时返回的错误，其他证券的采样仍然保存
+type Errors []*Error
+
+func (e Errors) Error() string {
+	list := make([]string, len(e))
+	for i, err := range e {
+		list[i] = err.Error()
+	}
+	return strings.Join(list, "; ")
+}
+
+type ruleKey struct {
+	rule *Rule
+	sec  futuapi.Security
+}
+
+// 资金流向采集器，可同时采集多只证券
+type Collector struct {
+	api     *futuapi.FutuAPI
+	opts    Options
+	mu      sync.RWMutex
+	secs    []*futuapi.Security
+	samples map[futuapi.Security][]*Sample
+	rules   []*Rule
+	fired   map[ruleKey]bool //已触发且条件仍满足的规则，条件不再满足后才会再次触发
+	errs    map[futuapi.Security]error
+	alerts  chan *Alert
+	fetch   func(ctx context.Context, sec *futuapi.Security) (*Sample, error)
+}
+
+// 创建采集器，api 用于轮询
+func New(api *futuapi.FutuAPI, opts *Options) *Collector {
+	c := &Collector{
+		api:     api,
+		samples: make(map[futuapi.Security][]*Sample),
+		fired:   make(map[ruleKey]bool),
+		errs:    make(map[futuapi.Security]error),
+	}
+	if opts != nil {
+		c.opts = *opts
+	}
+	c.fetch = c.Fetch
+	if c.opts.Interval <= 0 {
+		c.opts.Interval = DefaultInterval
+	}
+	if c.opts.Buffer > 0 {
+		c.alerts = make(chan *Alert, c.opts.Buffer)
+	}
+	return c
+}
+
+// 提醒通道，Buffer 为0时为空，通道满时丢弃
+func (c *Collector) Alerts() <-chan *Alert {
+	return c.alerts
+}
+
+// 添加采集的证券，已存在的忽略
+func (c *Collector) Watch(securities ...*futuapi.Security) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, sec := range securities {
+		found := false
+		for _, s := range c.secs {
+			if *s == *sec {
+				found = true
+				break
+			}
+		}
+		if !found {
+			c.secs = append(c.secs, sec)
+		}
+	}
+}
+
+// 停止采集证券并删除已保存的采样
+func (c *Collector) Unwatch(securities ...*futuapi.Security) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, sec := range securities {
+		for i, s := range c.secs {
+			if *s == *sec {
+				c.secs = append(c.secs[:i], c.secs[i+1:]...)
+				break
+			}
+		}
+		delete(c.samples, *sec)
+		delete(c.errs, *sec)
+	}
+}
+
+// 采集的证券
+func (c *Collector) Securities() []*futuapi.Security {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]*futuapi.Security(nil), c.secs...)
+}
+
+// 添加阈值规则
+func (c *Collector) AddRule(rules ...*Rule) {
+	c.mu.Lock()
+	c.rules = append(c.rules, rules...)
+	c.mu.Unlock()
+}
+
+// 保存一次采样，返回与上一次采样的变化和触发的提醒
+func (c *Collector) Add(s *Sample) (*Change, []*Alert) {
+	if s == nil || s.Security == nil {
+		return nil, nil
+	}
+	sec := *s.Security
+	c.mu.Lock()
+	list := c.samples[sec]
+	var prev *Sample
+	if len(list) > 0 {
+		prev = list[len(list)-1]
+	}
+	list = append(list, s)
+	if c.opts.MaxSamples > 0 && len(list) > c.opts.MaxSamples {
+		list = append([]*Sample(nil), list[len(list)-c.opts.MaxSamples:]...)
+	}
+	c.samples[sec] = list
+	var alerts []*Alert
+	for _, r := range c.rules {
+		ch := c.window(list, r.Window)
+		if ch == nil {
+			// 窗口内没有同一交易日的起点，无法判断窗口内的变化
+			continue
+		}
+		key := ruleKey{rule: r, sec: sec}
+		v := ch.Value(r.Field)
+		if !r.match(v) {
+			delete(c.fired, key)
+			continue
+		}
+		if c.fired[key] {
+			continue
+		}
+		c.fired[key] = true
+		alerts = append(alerts, &Alert{Rule: r, Change: ch, Value: v})
+	}
+	c.mu.Unlock()
+	if c.alerts != nil {
+		for _, a := range alerts {
+			select {
+			case c.alerts <- a:
+			default:
+			}
+		}
+	}
+	var change *Change
+	if prev != nil {
+		change = NewChange(prev, s)
+	}
+	return change, alerts
+}
+
+// 最新采样与窗口内最早的同一交易日采样之间的变化，窗口内没有更早的同一交易日采样时返回空
+func (c *Collector) window(list []*Sample, window time.Duration) *Change {
+	last := list[len(list)-1]
+	var from *Sample
+	for i := len(list) - 2; i >= 0; i-- {
+		s := list[i]
+		if s.Date != last.Date || last.Time.Sub(s.Time) > window {
+			break
+		}
+		from = s
+	}
+	if from == nil {
+		return nil
+	}
+	return NewChange(from, last)
+}
+
+// 证券的采样，按采样时间排序
+func (c *Collector) Samples(sec *futuapi.Security) []*Sample {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]*Sample(nil), c.samples[*sec]...)
+}
+
+// 证券的最新采样，没有数据时返回空
+func (c *Collector) Latest(sec *futuapi.Security) *Sample {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if list := c.samples[*sec]; len(list) > 0 {
+		return list[len(list)-1]
+	}
+	return nil
+}
+
+// 证券最新采样在 window 时间内的变化，窗口内没有同一交易日的起点时返回空
+func (c *Collector) Change(sec *futuapi.Security, window time.Duration) *Change {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if list := c.samples[*sec]; len(list) > 0 {
+		return c.window(list, window)
+	}
+	return nil
+}
+
+// 采集一只证券的资金流向和资金分布。接口调用遵守频率限制。
+func (c *Collector) Fetch(ctx context.Context, sec *futuapi.Security) (*Sample, error) {
+	if err := c.api.WaitRateLimit(ctx, futuapi.ProtoIDQotGetCapitalFlow); err != nil {
+		return nil, err
+	}
+	flow, err := c.api.GetCapitalFlow(ctx, sec)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.api.WaitRateLimit(ctx, futuapi.ProtoIDQotGetCapitalDistribution); err != nil {
+		return nil, err
+	}
+	dist, err := c.api.GetCapitalDistribution(ctx, sec)
+	if err != nil {
+		return nil, err
+	}
+	return NewSample(sec, time.Now(), flow, dist), nil
+}
+
+// 证券最近一次采集的错误，成功时为空
+func (c *Collector) LastError(sec *futuapi.Security) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.errs[*sec]
+}
+
+// 采集所有证券一次，某只证券出错时继续采集其他证券，最后返回 Errors。ctx 结束时返回 ErrInterrupted。
+func (c *Collector) Poll(ctx context.Context) error {
+	var errs Errors
+	for _, sec := range c.Securities() {
+		s, err := c.fetch(ctx, sec)
+		if err == futuapi.ErrInterrupted {
+			return err
+		}
+		c.mu.Lock()
+		if err != nil {
+			c.errs[*sec] = err
+		} else {
+			delete(c.errs, *sec)
+		}
+		c.mu.Unlock()
+		if err != nil {
+			errs = append(errs, &Error{Security: sec, Err: err})
+			continue
+		}
+		c.Add(s)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// 立即采集一次，然后按轮询间隔定时采集。证券出错不会停止采集，错误交给 OnError 并记录在 LastError。
+// ctx 结束时返回 ErrInterrupted。
+func (c *Collector) Run(ctx context.Context) error {
+	timer := time.NewTicker(c.opts.Interval)
+	defer timer.Stop()
+	for {
+		err := c.Poll(ctx)
+		if err == futuapi.ErrInterrupted {
+			return err
+		}
+		if errs, ok := err.(Errors); ok && c.opts.OnError != nil {
+			for _, e := range errs {
+				c.opts.OnError(e)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return futuapi.ErrInterrupted
+		case <-timer.C:
+		}
+	}
+}
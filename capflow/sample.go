@@ -0,0 +1,149 @@
+// Package capflow 定时轮询资金流向和资金分布，按证券保存为时间序列，
+// 计算相邻两次采样和指定时间窗口内的变化，并在变化超过阈值时提醒。
+// 资金流向和资金分布没有推送，只能在频率限制内轮询。
+package capflow
+
+import (
+	"time"
+
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+)
+
+// 采样的数值字段
+type Field int
+
+const (
+	FieldNetInflow Field = iota //当日累计净流入
+	FieldBig                    //大单净流入，流入减流出
+	FieldMid                    //中单净流入
+	FieldSmall                  //小单净流入
+)
+
+func (f Field) String() string {
+	switch f {
+	case FieldNetInflow:
+		return "NetInflow"
+	case FieldBig:
+		return "Big"
+	case FieldMid:
+		return "Mid"
+	case FieldSmall:
+		return "Small"
+	}
+	return "Unknown"
+}
+
+// 一次采样
+type Sample struct {
+	Security     *futuapi.Security
+	Time         time.Time //采样时间
+	Date         string    //数据所属交易日，YYYY-MM-DD，累计值在交易日之间不连续
+	Flow         *futuapi.CapitalFlow
+	Distribution *futuapi.CapitalDistribution
+}
+
+// 创建采样，交易日取资金流向的最后有效时间，没有时取采样时间在证券所在交易所的日期
+func NewSample(sec *futuapi.Security, at time.Time, flow *futuapi.CapitalFlow, dist *futuapi.CapitalDistribution) *Sample {
+	s := &Sample{Security: sec, Time: at, Flow: flow, Distribution: dist}
+	if flow != nil && len(flow.LastValidTIme) >= 10 {
+		s.Date = flow.LastValidTIme[:10]
+	} else {
+		s.Date = at.In(sec.Location()).Format("2006-01-02")
+	}
+	return s
+}
+
+// 字段的值，没有对应数据时为0。资金流向中每分钟的 InFlow 是当日截至该分钟的累计净流入，取最后一分钟的值。
+func (s *Sample) Value(f Field) float64 {
+	switch f {
+	case FieldNetInflow:
+		if s.Flow != nil && len(s.Flow.FlowItems) > 0 {
+			if item := s.Flow.FlowItems[len(s.Flow.FlowItems)-1]; item != nil {
+				return item.InFlow
+			}
+		}
+	case FieldBig:
+		if d := s.Distribution; d != nil {
+			return d.InBig - d.OutBig
+		}
+	case FieldMid:
+		if d := s.Distribution; d != nil {
+			return d.InMid - d.OutMid
+		}
+	case FieldSmall:
+		if d := s.Distribution; d != nil {
+			return d.InSmall - d.OutSmall
+		}
+	}
+	return 0
+}
+
+// 两次采样之间的变化
+type Change struct {
+	Security  *futuapi.Security
+	From      *Sample
+	To        *Sample
+	NetInflow float64
+	Big       float64
+	Mid       float64
+	Small     float64
+}
+
+// 计算两次采样之间的变化，交易日不同时 from 为空，变化为 to 的当日累计值
+func NewChange(from *Sample, to *Sample) *Change {
+	if from != nil && from.Date != to.Date {
+		from = nil
+	}
+	c := &Change{Security: to.Security, From: from, To: to}
+	diff := func(f Field) float64 {
+		if from == nil {
+			return to.Value(f)
+		}
+		return to.Value(f) - from.Value(f)
+	}
+	c.NetInflow = diff(FieldNetInflow)
+	c.Big = diff(FieldBig)
+	c.Mid = diff(FieldMid)
+	c.Small = diff(FieldSmall)
+	return c
+}
+
+// 字段的变化
+func (c *Change) Value(f Field) float64 {
+	switch f {
+	case FieldNetInflow:
+		return c.NetInflow
+	case FieldBig:
+		return c.Big
+	case FieldMid:
+		return c.Mid
+	case FieldSmall:
+		return c.Small
+	}
+	return 0
+}
+
+// 阈值规则，例如 M 分钟内净流入超过 N 百万
+type Rule struct {
+	Name      string
+	Field     Field
+	Window    time.Duration //时间窗口，与窗口内最早的同一交易日采样比较
+	Threshold float64       //大于0时变化不小于该值触发，小于0时变化不大于该值触发
+}
+
+func (r *Rule) match(v float64) bool {
+	if r.Threshold > 0 {
+		return v >= r.Threshold
+	}
+	if r.Threshold < 0 {
+		return v <= r.Threshold
+	}
+	return false
+}
+
+// 阈值提醒
+type Alert struct {
+	Rule   *Rule
+	Change *Change
+	Value  float64 //规则字段在窗口内的变化
+}
@@ -40,6 +40,8 @@ type FutuAPI struct {
 	reg *protocol.Registry
 
 	serial uint32
+	// 协议频率限制，按需创建
+	limits map[uint32]*rateLimit
 	mu     sync.Mutex
 	// 发送心跳的定时器，连接后设置
 	ticker *time.Ticker
@@ -0,0 +1,185 @@
+package futures
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/hurisheng/go-futu-api/pb/qotcommon"
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+)
+
+// 复权方式
+type Adjust int
+
+const (
+	AdjustNone  Adjust = iota //不复权，直接拼接
+	AdjustRatio               //比例复权，移仓前的价格乘以新旧合约价格之比
+	AdjustDiff                //差价复权，移仓前的价格加上新旧合约价格之差
+)
+
+// 连续合约中的一段，Contract 在 [Begin, End) 内为近月合约
+type Segment struct {
+	Contract *Contract
+	Begin    time.Time
+	End      time.Time
+}
+
+// 时间 [begin, end) 内依次使用的近月合约
+func (s *Series) Schedule(begin time.Time, end time.Time, rollDays int) []Segment {
+	var list []Segment
+	for _, c := range s.Contracts {
+		roll := c.RollDate(rollDays)
+		if !roll.After(begin) {
+			continue
+		}
+		seg := Segment{Contract: c, Begin: begin, End: roll}
+		if len(list) > 0 {
+			seg.Begin = list[len(list)-1].End
+		}
+		if seg.End.After(end) {
+			seg.End = end
+		}
+		if seg.Begin.Before(seg.End) {
+			list = append(list, seg)
+		}
+		if !roll.Before(end) {
+			break
+		}
+	}
+	return list
+}
+
+// 移仓记录
+type Roll struct {
+	Time  time.Time         //新合约第一根 K 线的时间
+	From  *futuapi.Security //旧合约
+	To    *futuapi.Security //新合约
+	Ratio float64           //移仓时新旧合约收盘价之比
+	Diff  float64           //移仓时新旧合约收盘价之差
+}
+
+// 连续合约 K 线
+type Continuous struct {
+	KLines    []*futuapi.KLine    //复权后的 K 线，价格字段为复权价
+	Contracts []*futuapi.Security //每根 K 线所属的合约，与 KLines 对应
+	Rolls     []*Roll
+}
+
+// 拼接各段合约的 K 线，klines 为每个合约的 K 线（按时间升序，可以包含段外的数据），loc 为 K 线时间的时区。
+// 复权以最后一段合约为基准向前调整，新旧合约的价差取旧合约最后一根 K 线时间及之前新合约最近一根 K 线的收盘价。
+func Stitch(segments []Segment, klines map[futuapi.Security][]*futuapi.KLine, loc *time.Location, adjust Adjust) *Continuous {
+	type part struct {
+		sec   *futuapi.Security
+		lines []*futuapi.KLine
+	}
+	var parts []part
+	for _, seg := range segments {
+		p := part{sec: seg.Contract.Security}
+		for _, k := range klines[*seg.Contract.Security] {
+			if k.IsBlank {
+				continue
+			}
+			t := k.TimeIn(loc)
+			if !t.Before(seg.Begin) && t.Before(seg.End) {
+				p.lines = append(p.lines, k)
+			}
+		}
+		if len(p.lines) > 0 {
+			parts = append(parts, p)
+		}
+	}
+	c := &Continuous{}
+	if len(parts) == 0 {
+		return c
+	}
+	// 从后向前计算每段的累计调整
+	ratios := make([]float64, len(parts))
+	diffs := make([]float64, len(parts))
+	ratios[len(parts)-1] = 1
+	rolls := make([]*Roll, len(parts)-1)
+	for i := len(parts) - 2; i >= 0; i-- {
+		ratios[i], diffs[i] = ratios[i+1], diffs[i+1]
+		last := parts[i].lines[len(parts[i].lines)-1]
+		r := &Roll{Time: parts[i+1].lines[0].TimeIn(loc), From: parts[i].sec, To: parts[i+1].sec, Ratio: 1}
+		if k := closeAt(klines[*parts[i+1].sec], last.TimeIn(loc), loc); k != nil && last.ClosePrice > 0 {
+			r.Ratio = k.ClosePrice / last.ClosePrice
+			r.Diff = k.ClosePrice - last.ClosePrice
+		}
+		ratios[i] *= r.Ratio
+		diffs[i] += r.Diff
+		rolls[i] = r
+	}
+	c.Rolls = rolls
+	for i, p := range parts {
+		for _, k := range p.lines {
+			adj := *k
+			switch adjust {
+			case AdjustRatio:
+				adjustPrices(&adj, func(v float64) float64 { return v * ratios[i] })
+			case AdjustDiff:
+				adjustPrices(&adj, func(v float64) float64 { return v + diffs[i] })
+			}
+			c.KLines = append(c.KLines, &adj)
+			c.Contracts = append(c.Contracts, p.sec)
+		}
+	}
+	return c
+}
+
+// 时间不晚于 t 的最后一根 K 线
+func closeAt(lines []*futuapi.KLine, t time.Time, loc *time.Location) *futuapi.KLine {
+	i := sort.Search(len(lines), func(i int) bool { return lines[i].TimeIn(loc).After(t) })
+	for i--; i >= 0; i-- {
+		if !lines[i].IsBlank {
+			return lines[i]
+		}
+	}
+	return nil
+}
+
+func adjustPrices(k *futuapi.KLine, f func(float64) float64) {
+	k.OpenPrice = f(k.OpenPrice)
+	k.HighPrice = f(k.HighPrice)
+	k.LowPrice = f(k.LowPrice)
+	k.ClosePrice = f(k.ClosePrice)
+	if k.LastClosePrice != 0 {
+		k.LastClosePrice = f(k.LastClosePrice)
+	}
+}
+
+// 连续合约历史 K 线请求参数
+type ContinuousRequest struct {
+	Begin    time.Time
+	End      time.Time
+	KLType   qotcommon.KLType
+	RollDays int    //最后交易日前几天移仓
+	Adjust   Adjust //复权方式
+}
+
+// 移仓时计算价差向前多取的天数，保证新合约在旧合约最后一根 K 线之前有数据
+const rollLookback = 7
+
+// 按移仓计划获取各合约的历史 K 线并拼接为连续合约，已到期合约需要接口仍提供其历史数据。接口调用遵守频率限制。
+func (s *Series) HistoryKLine(ctx context.Context, api *futuapi.FutuAPI, req *ContinuousRequest) (*Continuous, error) {
+	segments := s.Schedule(req.Begin, req.End, req.RollDays)
+	klines := make(map[futuapi.Security][]*futuapi.KLine, len(segments))
+	for _, seg := range segments {
+		r := &futuapi.HistoryKLineRequest{
+			Security:  seg.Contract.Security,
+			KLType:    req.KLType,
+			RehabType: qotcommon.RehabType_RehabType_None,
+		}
+		r.SetTimeRange(seg.Begin.AddDate(0, 0, -rollLookback), seg.End)
+		lines, err := api.RequestAllHistoryKLine(ctx, r)
+		if err != nil {
+			return nil, err
+		}
+		klines[*seg.Contract.Security] = lines
+	}
+	loc := time.UTC
+	if len(segments) > 0 {
+		loc = segments[0].Contract.Security.Location()
+	}
+	return Stitch(segments, klines, loc, req.Adjust), nil
+}
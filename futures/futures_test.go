@@ -0,0 +1,77 @@
+package futures
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/hurisheng/go-futu-api/pb/qotcommon"
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+)
+
+func testSeries() *Series {
+	info := func(code string, lastTrade string, main bool) *futuapi.SecurityStaticInfo {
+		return &futuapi.SecurityStaticInfo{
+			Basic:        &futuapi.SecurityStaticBasic{Security: &futuapi.Security{Market: qotcommon.QotMarket_QotMarket_HK_Security, Code: code}},
+			FutureExData: &futuapi.FutureStaticExData{LastTradeTime: lastTrade, IsMainContract: main},
+		}
+	}
+	return NewSeries("HSI", []*futuapi.SecurityStaticInfo{
+		info("HSI2406", "2024-06-27", false),
+		info("HSImain", "", true),
+		info("HSI2405", "2024-05-30", false),
+		info("MHI2405", "2024-05-30", false),
+	})
+}
+
+func TestSeries(t *testing.T) {
+	s := testSeries()
+	if s.Main == nil || len(s.Contracts) != 2 || s.Contracts[0].Security.Code != "HSI2405" {
+		t.Fatalf("series %+v", s)
+	}
+	loc := futuapi.MarketLocation(qotcommon.QotMarket_QotMarket_HK_Security)
+	for day, want := range map[int]string{27: "HSI2405", 28: "HSI2405", 29: "HSI2406"} {
+		if c := s.Front(time.Date(2024, 5, day, 10, 0, 0, 0, loc), 2); c == nil || c.Security.Code != want {
+			t.Errorf("front on day %v = %v", day, c)
+		}
+	}
+	if c := s.Front(time.Date(2024, 5, 30, 15, 0, 0, 0, loc), 0); c == nil || c.Security.Code != "HSI2405" {
+		t.Errorf("front on last trade day = %v", c)
+	}
+
+	alerts := s.RollAlerts(time.Date(2024, 5, 27, 9, 0, 0, 0, loc), 3)
+	if len(alerts) != 1 || alerts[0].DaysLeft != 3 || alerts[0].Next != s.Contracts[1] {
+		t.Errorf("alerts %+v", alerts)
+	}
+	if alerts := s.RollAlerts(time.Date(2024, 5, 31, 9, 0, 0, 0, loc), 3); len(alerts) != 0 {
+		t.Errorf("alerts after expiry %+v", alerts)
+	}
+}
+
+func TestStitch(t *testing.T) {
+	s := testSeries()
+	loc := futuapi.MarketLocation(qotcommon.QotMarket_QotMarket_HK_Security)
+	begin, end := time.Date(2024, 5, 27, 0, 0, 0, 0, loc), time.Date(2024, 5, 31, 0, 0, 0, 0, loc)
+	segments := s.Schedule(begin, end, 2)
+	if len(segments) != 2 || !segments[0].End.Equal(time.Date(2024, 5, 29, 0, 0, 0, 0, loc)) {
+		t.Fatalf("segments %+v", segments)
+	}
+	day := func(d int, close float64) *futuapi.KLine {
+		return &futuapi.KLine{Time: time.Date(2024, 5, d, 0, 0, 0, 0, loc).Format(futuapi.TimeLayout), ClosePrice: close, OpenPrice: close, HighPrice: close, LowPrice: close}
+	}
+	klines := map[futuapi.Security][]*futuapi.KLine{
+		*s.Contracts[0].Security: {day(27, 100), day(28, 110), day(29, 111)},
+		*s.Contracts[1].Security: {day(27, 101), day(28, 121), day(29, 125), day(30, 130)},
+	}
+	c := Stitch(segments, klines, loc, AdjustDiff)
+	if len(c.KLines) != 4 || len(c.Rolls) != 1 || c.Rolls[0].Diff != 11 || c.Contracts[2] != s.Contracts[1].Security {
+		t.Fatalf("continuous %+v", c)
+	}
+	if c.KLines[0].ClosePrice != 111 || c.KLines[1].ClosePrice != 121 || c.KLines[3].ClosePrice != 130 {
+		t.Errorf("diff adjusted %v %v %v", c.KLines[0].ClosePrice, c.KLines[1].ClosePrice, c.KLines[3].ClosePrice)
+	}
+	c = Stitch(segments, klines, loc, AdjustRatio)
+	if math.Abs(c.KLines[0].ClosePrice-110) > 1e-9 || klines[*s.Contracts[0].Security][0].ClosePrice != 100 {
+		t.Errorf("ratio adjusted %v", c.KLines[0].ClosePrice)
+	}
+}
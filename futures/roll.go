@@ -0,0 +1,81 @@
+package futures
+
+import (
+	"context"
+	"math"
+	"time"
+
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+)
+
+// 移仓提醒
+type RollAlert struct {
+	Contract  *Contract
+	Next      *Contract //下一个合约，没有时为空
+	LastTrade time.Time //最后交易日
+	DaysLeft  int       //距离最后交易日的自然日天数，0 为当天
+}
+
+// 距离最后交易日的自然日天数，按合约时区的日期计算
+func daysLeft(c *Contract, now time.Time) int {
+	t := now.In(c.LastTrade.Location())
+	today := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	last := time.Date(c.LastTrade.Year(), c.LastTrade.Month(), c.LastTrade.Day(), 0, 0, 0, 0, t.Location())
+	return int(math.Round(last.Sub(today).Hours() / 24))
+}
+
+// 最后交易日在 days 天内（包括当天）且尚未过去的合约的移仓提醒
+func (s *Series) RollAlerts(now time.Time, days int) []*RollAlert {
+	var list []*RollAlert
+	for _, c := range s.Contracts {
+		n := daysLeft(c, now)
+		if n < 0 {
+			continue
+		}
+		if n > days {
+			break
+		}
+		list = append(list, &RollAlert{Contract: c, Next: s.Next(c), LastTrade: c.LastTrade, DaysLeft: n})
+	}
+	return list
+}
+
+// 定时检查持有的合约，最后交易日在 days 天内时向 ch 发送移仓提醒，每个合约每天只提醒一次。
+// held 返回当前持有的合约代码，为空时检查所有合约。阻塞直到 ctx 结束，返回 ErrInterrupted。
+func WatchRoll(ctx context.Context, series []*Series, held func() []*futuapi.Security, days int, interval time.Duration, ch chan<- *RollAlert) error {
+	sent := make(map[futuapi.Security]int)
+	timer := time.NewTicker(interval)
+	defer timer.Stop()
+	for {
+		now := time.Now()
+		var filter map[futuapi.Security]bool
+		if held != nil {
+			filter = make(map[futuapi.Security]bool)
+			for _, sec := range held() {
+				filter[*sec] = true
+			}
+		}
+		for _, s := range series {
+			for _, a := range s.RollAlerts(now, days) {
+				sec := *a.Contract.Security
+				if filter != nil && !filter[sec] {
+					continue
+				}
+				if n, ok := sent[sec]; ok && n == a.DaysLeft {
+					continue
+				}
+				select {
+				case <-ctx.Done():
+					return futuapi.ErrInterrupted
+				case ch <- a:
+					sent[sec] = a.DaysLeft
+				}
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return futuapi.ErrInterrupted
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,147 @@
+// Package futures 管理期货品种的合约序列：按日期选择主力（近月）合约、
+// 拼接连续合约的历史 K 线并做比例或差价复权，以及在最后交易日前提醒移仓。
+package futures
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hurisheng/go-futu-api/pb/qotcommon"
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+)
+
+// 期货合约
+type Contract struct {
+	Security  *futuapi.Security
+	Name      string
+	LastTrade time.Time           //最后交易日，主连合约为零值
+	Main      bool                //是否主连合约
+	Info      *futuapi.FutureInfo //合约资料，没有获取时为空
+}
+
+// 合约序列，Contracts 为到期的普通合约，按最后交易日升序
+type Series struct {
+	Product   string    //品种代码，如 HSI、MHI
+	Main      *Contract //主连合约，没有时为空
+	Contracts []*Contract
+}
+
+// 代码中的品种部分，去掉末尾的合约月份或 main、current、next 等后缀，如 HSI2406、HSImain 都是 HSI
+func ProductCode(code string) string {
+	lower := strings.ToLower(code)
+	for _, suffix := range []string{"main", "current", "next"} {
+		if strings.HasSuffix(lower, suffix) {
+			return code[:len(code)-len(suffix)]
+		}
+	}
+	return strings.TrimRight(code, "0123456789")
+}
+
+// 由静态信息创建品种的合约序列，不属于该品种或不是期货的证券忽略
+func NewSeries(product string, infos []*futuapi.SecurityStaticInfo) *Series {
+	s := &Series{Product: product}
+	for _, info := range infos {
+		if info == nil || info.Basic == nil || info.Basic.Security == nil || info.FutureExData == nil {
+			continue
+		}
+		sec := info.Basic.Security
+		if !strings.EqualFold(ProductCode(sec.Code), product) {
+			continue
+		}
+		c := &Contract{Security: sec, Name: info.Basic.Name, Main: info.FutureExData.IsMainContract}
+		if c.Main {
+			if s.Main == nil {
+				s.Main = c
+			}
+			continue
+		}
+		day, err := futuapi.ParseTime(info.FutureExData.LastTradeTime, sec.Location())
+		if err != nil {
+			continue
+		}
+		c.LastTrade = day
+		s.Contracts = append(s.Contracts, c)
+	}
+	sort.Slice(s.Contracts, func(i, j int) bool { return s.Contracts[i].LastTrade.Before(s.Contracts[j].LastTrade) })
+	return s
+}
+
+// 获取市场的全部期货静态信息，创建品种的合约序列，并获取合约资料。接口调用遵守频率限制。
+func LoadSeries(ctx context.Context, api *futuapi.FutuAPI, market qotcommon.QotMarket, product string) (*Series, error) {
+	if err := api.WaitRateLimit(ctx, futuapi.ProtoIDQotGetStaticInfo); err != nil {
+		return nil, err
+	}
+	infos, err := api.GetStockBasicInfo(ctx, market, qotcommon.SecurityType_SecurityType_Future, nil)
+	if err != nil {
+		return nil, err
+	}
+	s := NewSeries(product, infos)
+	var list []*futuapi.Security
+	for _, c := range s.all() {
+		list = append(list, c.Security)
+	}
+	details, err := api.GetFutureInfoBatch(ctx, list, nil)
+	s.SetInfo(details)
+	return s, err
+}
+
+// 主连合约和全部普通合约
+func (s *Series) all() []*Contract {
+	list := s.Contracts
+	if s.Main != nil {
+		list = append([]*Contract{s.Main}, list...)
+	}
+	return list
+}
+
+// 设置合约资料
+func (s *Series) SetInfo(list []*futuapi.FutureInfo) {
+	bySecurity := make(map[futuapi.Security]*futuapi.FutureInfo, len(list))
+	for _, info := range list {
+		if info != nil && info.Security != nil {
+			bySecurity[*info.Security] = info
+		}
+	}
+	for _, c := range s.all() {
+		if info := bySecurity[*c.Security]; info != nil {
+			c.Info = info
+		}
+	}
+}
+
+// 查找合约，不存在时返回空
+func (s *Series) Contract(sec *futuapi.Security) *Contract {
+	for _, c := range s.all() {
+		if *c.Security == *sec {
+			return c
+		}
+	}
+	return nil
+}
+
+// 合约的移仓时间：最后交易日前 rollDays 天（自然日）收市后，即该日次日的0点，rollDays 为0时持有到最后交易日
+func (c *Contract) RollDate(rollDays int) time.Time {
+	return c.LastTrade.AddDate(0, 0, 1-rollDays)
+}
+
+// 时间 t 的近月合约：移仓时间晚于 t 的第一个合约，没有时返回空
+func (s *Series) Front(t time.Time, rollDays int) *Contract {
+	for _, c := range s.Contracts {
+		if t.Before(c.RollDate(rollDays)) {
+			return c
+		}
+	}
+	return nil
+}
+
+// 合约的下一个合约，没有时返回空
+func (s *Series) Next(c *Contract) *Contract {
+	for i, x := range s.Contracts {
+		if x == c && i+1 < len(s.Contracts) {
+			return s.Contracts[i+1]
+		}
+	}
+	return nil
+}
@@ -1,6 +1,6 @@
 module github.com/woxinyoumeng/go-futu-api
 
-go 1.14
+go 1.15
 
 require (
 	github.com/astaxie/beego v1.12.3
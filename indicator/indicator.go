@@ -0,0 +1,163 @@
+// Package indicator 在 K 线序列上计算技术指标，包括 MA、EMA、MACD、RSI、KDJ、BOLL、ATR、OBV 和 VWAP。
+//
+// 每个指标都有流式和批量两种形式。批量形式内部逐根调用流式形式，结果完全一致。
+// 流式形式可以直接处理 UpdateKL 推送：同一时间戳的 K 线重复推送时替换最后一根重新计算，
+// 而不是当作新的一根。空内容点不参与计算，返回上一次的值。公式与富途客户端一致（通达信写法）。
+package indicator
+
+import (
+	"math"
+
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+)
+
+// 可以逐根处理 K 线的指标
+type Indicator interface {
+	Push(k *futuapi.KLine)
+}
+
+// 把一次 K 线推送中的 K 线依次交给指标
+func Feed(rt *futuapi.RTKLine, inds ...Indicator) {
+	if rt == nil {
+		return
+	}
+	for _, k := range rt.KLines {
+		for _, ind := range inds {
+			ind.Push(k)
+		}
+	}
+}
+
+// 指标的计算状态
+type state interface {
+	step(k *futuapi.KLine)
+	clone() state
+}
+
+// 流式计算的公共部分，保存最后一根 K 线之前的状态，以便同一根 K 线更新时重新计算
+type stream struct {
+	cur  state
+	prev state
+	ts   float64
+	n    int
+}
+
+// 处理一根 K 线
+func (s *stream) Push(k *futuapi.KLine) {
+	if k == nil || k.IsBlank {
+		return
+	}
+	if s.n > 0 {
+		if k.Timestamp < s.ts {
+			return
+		}
+		if k.Timestamp == s.ts {
+			s.cur = s.prev.clone()
+			s.cur.step(k)
+			return
+		}
+	}
+	s.prev = s.cur.clone()
+	s.ts = k.Timestamp
+	s.n++
+	s.cur.step(k)
+}
+
+// 固定长度的滑动窗口
+type window struct {
+	n int
+	v []float64
+}
+
+func (w *window) push(x float64) {
+	w.v = append(w.v, x)
+	if len(w.v) > w.n {
+		w.v = append(w.v[:0], w.v[1:]...)
+	}
+}
+
+func (w window) clone() window {
+	return window{n: w.n, v: append([]float64(nil), w.v...)}
+}
+
+func (w *window) full() bool {
+	return len(w.v) >= w.n
+}
+
+func (w *window) mean() float64 {
+	var sum float64
+	for _, x := range w.v {
+		sum += x
+	}
+	return sum / float64(len(w.v))
+}
+
+// 样本标准差，与通达信 STD 一致
+func (w *window) std() float64 {
+	if len(w.v) < 2 {
+		return 0
+	}
+	m := w.mean()
+	var sum float64
+	for _, x := range w.v {
+		sum += (x - m) * (x - m)
+	}
+	return math.Sqrt(sum / float64(len(w.v)-1))
+}
+
+func (w *window) max() float64 {
+	m := math.Inf(-1)
+	for _, x := range w.v {
+		if x > m {
+			m = x
+		}
+	}
+	return m
+}
+
+func (w *window) min() float64 {
+	m := math.Inf(1)
+	for _, x := range w.v {
+		if x < m {
+			m = x
+		}
+	}
+	return m
+}
+
+// 通达信的 SMA(X,N,M)，第一个值为 X 本身
+type sma struct {
+	n, m  float64
+	value float64
+	ok    bool
+}
+
+func (s *sma) push(x float64) float64 {
+	if !s.ok {
+		s.value, s.ok = x, true
+	} else {
+		s.value = (s.m*x + (s.n-s.m)*s.value) / s.n
+	}
+	return s.value
+}
+
+// 指数移动平均，第一个值为 X 本身
+type ema struct {
+	n     int
+	value float64
+	ok    bool
+}
+
+func (e *ema) push(x float64) float64 {
+	if !e.ok {
+		e.value, e.ok = x, true
+	} else {
+		a := 2 / float64(e.n+1)
+		e.value = a*x + (1-a)*e.value
+	}
+	return e.value
+}
+
+func nan() float64 {
+	return math.NaN()
+}
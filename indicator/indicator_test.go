@@ -0,0 +1,82 @@
+package indicator
+
+import (
+	"math"
+	"testing"
+
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+)
+
+func series() []*futuapi.KLine {
+	var list []*futuapi.KLine
+	for i := 0; i < 60; i++ {
+		c := 100 + 10*math.Sin(float64(i)/5) + float64(i%7)
+		list = append(list, &futuapi.KLine{
+			Time:       "2021-01-04 10:00:00",
+			Timestamp:  float64(i * 60),
+			OpenPrice:  c - 1,
+			HighPrice:  c + 2,
+			LowPrice:   c - 3,
+			ClosePrice: c,
+			Volume:     int64(1000 + i),
+			Turnover:   c * float64(1000+i),
+		})
+	}
+	list[10].IsBlank = true
+	return list
+}
+
+func same(a, b float64) bool {
+	return a == b || (math.IsNaN(a) && math.IsNaN(b))
+}
+
+// 模拟 UpdateKL：每根 K 线先推送两次未完成的数据，再推送最终数据
+func partials(k *futuapi.KLine) []*futuapi.KLine {
+	p1, p2 := *k, *k
+	p1.ClosePrice, p1.HighPrice = k.OpenPrice, k.OpenPrice+5
+	p2.ClosePrice, p2.LowPrice, p2.Volume = k.ClosePrice+1, k.LowPrice-5, k.Volume/2
+	return []*futuapi.KLine{&p1, &p2, k}
+}
+
+func TestStreamMatchesBatch(t *testing.T) {
+	list := series()
+	ma, ema, rsi, atr, obv, vwap := MASeries(list, 5), EMASeries(list, 12), RSISeries(list, 6), ATRSeries(list, 14), OBVSeries(list), VWAPSeries(list, true)
+	macd, kdj, boll := MACDSeries(list, 12, 26, 9), KDJSeries(list, 9, 3, 3), BOLLSeries(list, 20, 2)
+
+	sMA, sEMA, sRSI, sATR, sOBV, sVWAP := NewMA(5), NewEMA(12), NewRSI(6), NewATR(14), NewOBV(), NewVWAP(true)
+	sMACD, sKDJ, sBOLL := NewMACD(12, 26, 9), NewKDJ(9, 3, 3), NewBOLL(20, 2)
+	for i, k := range list {
+		Feed(&futuapi.RTKLine{KLines: partials(k)}, sMA, sEMA, sRSI, sATR, sOBV, sVWAP, sMACD, sKDJ, sBOLL)
+		for name, v := range map[string][2]float64{
+			"MA":        {ma[i], sMA.Value()},
+			"EMA":       {ema[i], sEMA.Value()},
+			"RSI":       {rsi[i], sRSI.Value()},
+			"ATR":       {atr[i], sATR.Value()},
+			"OBV":       {obv[i], sOBV.Value()},
+			"VWAP":      {vwap[i], sVWAP.Value()},
+			"MACD.DIF":  {macd[i].DIF, sMACD.Value().DIF},
+			"MACD.MACD": {macd[i].MACD, sMACD.Value().MACD},
+			"KDJ.K":     {kdj[i].K, sKDJ.Value().K},
+			"KDJ.J":     {kdj[i].J, sKDJ.Value().J},
+			"BOLL.UP":   {boll[i].Upper, sBOLL.Value().Upper},
+		} {
+			if !same(v[0], v[1]) {
+				t.Errorf("%v[%v]: batch %v, stream %v", name, i, v[0], v[1])
+			}
+		}
+	}
+}
+
+func TestMA(t *testing.T) {
+	var list []*futuapi.KLine
+	for i := 1; i <= 4; i++ {
+		list = append(list, &futuapi.KLine{Timestamp: float64(i), ClosePrice: float64(i)})
+	}
+	got := MASeries(list, 3)
+	if !math.IsNaN(got[1]) || got[2] != 2 || got[3] != 3 {
+		t.Errorf("MA = %v", got)
+	}
+	if e := EMASeries(list, 3); e[0] != 1 || e[1] != 1.5 {
+		t.Errorf("EMA = %v", e)
+	}
+}
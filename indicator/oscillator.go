@@ -0,0 +1,133 @@
+package indicator
+
+import (
+	"math"
+
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+)
+
+// 相对强弱指标 RSI(N)，常用参数为6,12,24。
+// RSI = SMA(MAX(C-LC,0),N,1) / SMA(ABS(C-LC),N,1) * 100，第一根没有昨收时为 NaN
+type RSI struct {
+	stream
+}
+
+type rsiState struct {
+	up, all   sma
+	lastClose float64
+	ok        bool
+	value     float64
+}
+
+func (s *rsiState) step(k *futuapi.KLine) {
+	if !s.ok {
+		s.lastClose, s.ok = k.ClosePrice, true
+		s.value = nan()
+		return
+	}
+	d := k.ClosePrice - s.lastClose
+	s.lastClose = k.ClosePrice
+	up, all := s.up.push(math.Max(d, 0)), s.all.push(math.Abs(d))
+	if all == 0 {
+		s.value = 50
+		return
+	}
+	s.value = up / all * 100
+}
+
+func (s *rsiState) clone() state {
+	c := *s
+	return &c
+}
+
+func NewRSI(n int) *RSI {
+	return &RSI{stream{cur: &rsiState{up: sma{n: float64(n), m: 1}, all: sma{n: float64(n), m: 1}, value: nan()}}}
+}
+
+func (r *RSI) Update(k *futuapi.KLine) float64 {
+	r.Push(k)
+	return r.Value()
+}
+
+func (r *RSI) Value() float64 {
+	return r.cur.(*rsiState).value
+}
+
+// 批量计算 RSI
+func RSISeries(list []*futuapi.KLine, n int) []float64 {
+	r := NewRSI(n)
+	out := make([]float64, len(list))
+	for i, k := range list {
+		out[i] = r.Update(k)
+	}
+	return out
+}
+
+// KDJ 指标值
+type KDJValue struct {
+	K float64
+	D float64
+	J float64
+}
+
+// 随机指标 KDJ(N,M1,M2)，常用参数为9,3,3。
+// RSV = (C-LLV(L,N)) / (HHV(H,N)-LLV(L,N)) * 100，K = SMA(RSV,M1,1)，D = SMA(K,M2,1)，J = 3K-2D。
+// K、D 初始值为50，区间最高价等于最低价时 RSV 取50。
+type KDJ struct {
+	stream
+}
+
+type kdjState struct {
+	high, low window
+	k, d      sma
+	value     KDJValue
+}
+
+func (s *kdjState) step(k *futuapi.KLine) {
+	s.high.push(k.HighPrice)
+	s.low.push(k.LowPrice)
+	h, l := s.high.max(), s.low.min()
+	rsv := 50.0
+	if h != l {
+		rsv = (k.ClosePrice - l) / (h - l) * 100
+	}
+	kv := s.k.push(rsv)
+	dv := s.d.push(kv)
+	s.value = KDJValue{K: kv, D: dv, J: 3*kv - 2*dv}
+}
+
+func (s *kdjState) clone() state {
+	c := *s
+	c.high = s.high.clone()
+	c.low = s.low.clone()
+	return &c
+}
+
+func NewKDJ(n, m1, m2 int) *KDJ {
+	return &KDJ{stream{cur: &kdjState{
+		high:  window{n: n},
+		low:   window{n: n},
+		k:     sma{n: float64(m1), m: 1, value: 50, ok: true},
+		d:     sma{n: float64(m2), m: 1, value: 50, ok: true},
+		value: KDJValue{K: nan(), D: nan(), J: nan()},
+	}}}
+}
+
+func (k *KDJ) Update(kl *futuapi.KLine) KDJValue {
+	k.Push(kl)
+	return k.Value()
+}
+
+func (k *KDJ) Value() KDJValue {
+	return k.cur.(*kdjState).value
+}
+
+// 批量计算 KDJ
+func KDJSeries(list []*futuapi.KLine, n, m1, m2 int) []KDJValue {
+	ind := NewKDJ(n, m1, m2)
+	out := make([]KDJValue, len(list))
+	for i, k := range list {
+		out[i] = ind.Update(k)
+	}
+	return out
+}
@@ -0,0 +1,206 @@
+package indicator
+
+import (
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+)
+
+// 简单移动平均 MA(N)，不足 N 根时为 NaN
+type MA struct {
+	stream
+}
+
+type maState struct {
+	w     window
+	value float64
+}
+
+func (s *maState) step(k *futuapi.KLine) {
+	s.w.push(k.ClosePrice)
+	if s.w.full() {
+		s.value = s.w.mean()
+	} else {
+		s.value = nan()
+	}
+}
+
+func (s *maState) clone() state {
+	c := *s
+	c.w = s.w.clone()
+	return &c
+}
+
+func NewMA(n int) *MA {
+	return &MA{stream{cur: &maState{w: window{n: n}, value: nan()}}}
+}
+
+func (m *MA) Update(k *futuapi.KLine) float64 {
+	m.Push(k)
+	return m.Value()
+}
+
+func (m *MA) Value() float64 {
+	return m.cur.(*maState).value
+}
+
+// 批量计算 MA
+func MASeries(list []*futuapi.KLine, n int) []float64 {
+	m := NewMA(n)
+	out := make([]float64, len(list))
+	for i, k := range list {
+		out[i] = m.Update(k)
+	}
+	return out
+}
+
+// 指数移动平均 EMA(N)，从第一根开始有值
+type EMA struct {
+	stream
+}
+
+type emaState struct {
+	e ema
+}
+
+func (s *emaState) step(k *futuapi.KLine) {
+	s.e.push(k.ClosePrice)
+}
+
+func (s *emaState) clone() state {
+	c := *s
+	return &c
+}
+
+func NewEMA(n int) *EMA {
+	return &EMA{stream{cur: &emaState{e: ema{n: n}}}}
+}
+
+func (e *EMA) Update(k *futuapi.KLine) float64 {
+	e.Push(k)
+	return e.Value()
+}
+
+func (e *EMA) Value() float64 {
+	s := e.cur.(*emaState)
+	if !s.e.ok {
+		return nan()
+	}
+	return s.e.value
+}
+
+// 批量计算 EMA
+func EMASeries(list []*futuapi.KLine, n int) []float64 {
+	e := NewEMA(n)
+	out := make([]float64, len(list))
+	for i, k := range list {
+		out[i] = e.Update(k)
+	}
+	return out
+}
+
+// MACD 指标值
+type MACDValue struct {
+	DIF  float64 //快线 EMA 与慢线 EMA 的差
+	DEA  float64 //DIF 的 EMA
+	MACD float64 //(DIF-DEA)*2
+}
+
+// MACD(SHORT,LONG,M)，常用参数为12,26,9
+type MACD struct {
+	stream
+}
+
+type macdState struct {
+	short, long, dea ema
+	value            MACDValue
+}
+
+func (s *macdState) step(k *futuapi.KLine) {
+	dif := s.short.push(k.ClosePrice) - s.long.push(k.ClosePrice)
+	dea := s.dea.push(dif)
+	s.value = MACDValue{DIF: dif, DEA: dea, MACD: (dif - dea) * 2}
+}
+
+func (s *macdState) clone() state {
+	c := *s
+	return &c
+}
+
+func NewMACD(short, long, m int) *MACD {
+	return &MACD{stream{cur: &macdState{short: ema{n: short}, long: ema{n: long}, dea: ema{n: m}}}}
+}
+
+func (m *MACD) Update(k *futuapi.KLine) MACDValue {
+	m.Push(k)
+	return m.Value()
+}
+
+func (m *MACD) Value() MACDValue {
+	return m.cur.(*macdState).value
+}
+
+// 批量计算 MACD
+func MACDSeries(list []*futuapi.KLine, short, long, m int) []MACDValue {
+	ind := NewMACD(short, long, m)
+	out := make([]MACDValue, len(list))
+	for i, k := range list {
+		out[i] = ind.Update(k)
+	}
+	return out
+}
+
+// BOLL 指标值
+type BOLLValue struct {
+	Upper float64 //上轨
+	Mid   float64 //中轨
+	Lower float64 //下轨
+}
+
+// 布林线 BOLL(N,P)，常用参数为20,2，标准差与通达信 STD 一致为样本标准差，不足 N 根时为 NaN
+type BOLL struct {
+	stream
+}
+
+type bollState struct {
+	w     window
+	p     float64
+	value BOLLValue
+}
+
+func (s *bollState) step(k *futuapi.KLine) {
+	s.w.push(k.ClosePrice)
+	if !s.w.full() {
+		s.value = BOLLValue{Upper: nan(), Mid: nan(), Lower: nan()}
+		return
+	}
+	mid, std := s.w.mean(), s.w.std()
+	s.value = BOLLValue{Upper: mid + s.p*std, Mid: mid, Lower: mid - s.p*std}
+}
+
+func (s *bollState) clone() state {
+	c := *s
+	c.w = s.w.clone()
+	return &c
+}
+
+func NewBOLL(n int, p float64) *BOLL {
+	return &BOLL{stream{cur: &bollState{w: window{n: n}, p: p, value: BOLLValue{Upper: nan(), Mid: nan(), Lower: nan()}}}}
+}
+
+func (b *BOLL) Update(k *futuapi.KLine) BOLLValue {
+	b.Push(k)
+	return b.Value()
+}
+
+func (b *BOLL) Value() BOLLValue {
+	return b.cur.(*bollState).value
+}
+
+// 批量计算 BOLL
+func BOLLSeries(list []*futuapi.KLine, n int, p float64) []BOLLValue {
+	b := NewBOLL(n, p)
+	out := make([]BOLLValue, len(list))
+	for i, k := range list {
+		out[i] = b.Update(k)
+	}
+	return out
+}
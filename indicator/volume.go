@@ -0,0 +1,175 @@
+package indicator
+
+import (
+	"math"
+
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+)
+
+// 平均真实波幅 ATR(N)，常用参数为14。
+// TR = MAX(H-L, ABS(H-LC), ABS(L-LC))，ATR = MA(TR,N)，第一根的 TR 为 H-L，不足 N 根时为 NaN
+type ATR struct {
+	stream
+}
+
+type atrState struct {
+	w         window
+	lastClose float64
+	ok        bool
+	value     float64
+}
+
+func (s *atrState) step(k *futuapi.KLine) {
+	tr := k.HighPrice - k.LowPrice
+	if s.ok {
+		tr = math.Max(tr, math.Max(math.Abs(k.HighPrice-s.lastClose), math.Abs(k.LowPrice-s.lastClose)))
+	}
+	s.lastClose, s.ok = k.ClosePrice, true
+	s.w.push(tr)
+	if s.w.full() {
+		s.value = s.w.mean()
+	} else {
+		s.value = nan()
+	}
+}
+
+func (s *atrState) clone() state {
+	c := *s
+	c.w = s.w.clone()
+	return &c
+}
+
+func NewATR(n int) *ATR {
+	return &ATR{stream{cur: &atrState{w: window{n: n}, value: nan()}}}
+}
+
+func (a *ATR) Update(k *futuapi.KLine) float64 {
+	a.Push(k)
+	return a.Value()
+}
+
+func (a *ATR) Value() float64 {
+	return a.cur.(*atrState).value
+}
+
+// 批量计算 ATR
+func ATRSeries(list []*futuapi.KLine, n int) []float64 {
+	a := NewATR(n)
+	out := make([]float64, len(list))
+	for i, k := range list {
+		out[i] = a.Update(k)
+	}
+	return out
+}
+
+// 能量潮 OBV，收盘价上涨累加成交量，下跌减去成交量，第一根为0
+type OBV struct {
+	stream
+}
+
+type obvState struct {
+	lastClose float64
+	ok        bool
+	value     float64
+}
+
+func (s *obvState) step(k *futuapi.KLine) {
+	if s.ok {
+		switch {
+		case k.ClosePrice > s.lastClose:
+			s.value += float64(k.Volume)
+		case k.ClosePrice < s.lastClose:
+			s.value -= float64(k.Volume)
+		}
+	}
+	s.lastClose, s.ok = k.ClosePrice, true
+}
+
+func (s *obvState) clone() state {
+	c := *s
+	return &c
+}
+
+func NewOBV() *OBV {
+	return &OBV{stream{cur: &obvState{}}}
+}
+
+func (o *OBV) Update(k *futuapi.KLine) float64 {
+	o.Push(k)
+	return o.Value()
+}
+
+func (o *OBV) Value() float64 {
+	return o.cur.(*obvState).value
+}
+
+// 批量计算 OBV
+func OBVSeries(list []*futuapi.KLine) []float64 {
+	o := NewOBV()
+	out := make([]float64, len(list))
+	for i, k := range list {
+		out[i] = o.Update(k)
+	}
+	return out
+}
+
+// 成交量加权均价 VWAP，累计成交额除以累计成交量，按交易日（时间字符串的日期部分）重新累计。
+// 日线及以上周期不需要按日重置时传 daily 为 false。
+type VWAP struct {
+	stream
+}
+
+type vwapState struct {
+	daily    bool
+	day      string
+	volume   float64
+	turnover float64
+	value    float64
+}
+
+func (s *vwapState) step(k *futuapi.KLine) {
+	if s.daily {
+		day := k.Time
+		if len(day) > 10 {
+			day = day[:10]
+		}
+		if day != s.day {
+			s.day, s.volume, s.turnover = day, 0, 0
+		}
+	}
+	s.volume += float64(k.Volume)
+	s.turnover += k.Turnover
+	if s.volume > 0 {
+		s.value = s.turnover / s.volume
+	} else {
+		s.value = nan()
+	}
+}
+
+func (s *vwapState) clone() state {
+	c := *s
+	return &c
+}
+
+func NewVWAP(daily bool) *VWAP {
+	return &VWAP{stream{cur: &vwapState{daily: daily, value: nan()}}}
+}
+
+func (v *VWAP) Update(k *futuapi.KLine) float64 {
+	v.Push(k)
+	return v.Value()
+}
+
+func (v *VWAP) Value() float64 {
+	return v.cur.(*vwapState).value
+}
+
+// 批量计算 VWAP
+func VWAPSeries(list []*futuapi.KLine, daily bool) []float64 {
+	v := NewVWAP(daily)
+	out := make([]float64, len(list))
+	for i, k := range list {
+		out[i] = v.Update(k)
+	}
+	return out
+}
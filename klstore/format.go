@@ -0,0 +1,260 @@
+package klstore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+)
+
+// 文件格式：固定长度文件头，之后是按 Timestamp 升序排列的定长记录，
+// 定长记录可以直接按下标计算偏移，区间查询用二分查找定位，不需要读取整个文件。
+
+var magic = [4]byte{'F', 'T', 'K', 'L'}
+
+const version = 1
+
+var (
+	ErrBadFormat = errors.New("klstore: bad file format")
+)
+
+type fileHeader struct {
+	Magic   [4]byte
+	Version uint32
+	RecSize uint32
+	_       uint32
+}
+
+const (
+	flagBlank uint32 = 1 << iota
+)
+
+// K 线的定长记录
+type record struct {
+	Timestamp      float64
+	Time           [24]byte
+	Flags          uint32
+	_              uint32
+	OpenPrice      float64
+	HighPrice      float64
+	LowPrice       float64
+	ClosePrice     float64
+	LastClosePrice float64
+	Volume         int64
+	Turnover       float64
+	TurnoverRate   float64
+	PE             float64
+	ChangeRate     float64
+}
+
+var (
+	headerSize = int64(binary.Size(fileHeader{}))
+	recordSize = int64(binary.Size(record{}))
+)
+
+func recordFromKLine(k *futuapi.KLine) *record {
+	r := record{
+		Timestamp:      k.Timestamp,
+		OpenPrice:      k.OpenPrice,
+		HighPrice:      k.HighPrice,
+		LowPrice:       k.LowPrice,
+		ClosePrice:     k.ClosePrice,
+		LastClosePrice: k.LastClosePrice,
+		Volume:         k.Volume,
+		Turnover:       k.Turnover,
+		TurnoverRate:   k.TurnoverRate,
+		PE:             k.PE,
+		ChangeRate:     k.ChangeRate,
+	}
+	copy(r.Time[:], k.Time)
+	if k.IsBlank {
+		r.Flags |= flagBlank
+	}
+	return &r
+}
+
+func (r *record) kLine() *futuapi.KLine {
+	return &futuapi.KLine{
+		Time:           string(bytes.TrimRight(r.Time[:], "\x00")),
+		IsBlank:        r.Flags&flagBlank != 0,
+		HighPrice:      r.HighPrice,
+		OpenPrice:      r.OpenPrice,
+		LowPrice:       r.LowPrice,
+		ClosePrice:     r.ClosePrice,
+		LastClosePrice: r.LastClosePrice,
+		Volume:         r.Volume,
+		Turnover:       r.Turnover,
+		TurnoverRate:   r.TurnoverRate,
+		PE:             r.PE,
+		ChangeRate:     r.ChangeRate,
+		Timestamp:      r.Timestamp,
+	}
+}
+
+// 单个 K 线文件
+type file struct {
+	f *os.File
+	n int64 //记录条数
+}
+
+// 打开 K 线文件，文件不存在时返回空文件。
+// 写入中断时末尾可能留下不完整的记录，读取时忽略，下次追加时截断
+func openFile(name string) (*file, error) {
+	f, err := os.Open(name)
+	if os.IsNotExist(err) {
+		return &file{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var h fileHeader
+	if err := binary.Read(f, binary.LittleEndian, &h); err != nil {
+		f.Close()
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, ErrBadFormat
+		}
+		return nil, err
+	}
+	if h.Magic != magic || h.Version != version || int64(h.RecSize) != recordSize {
+		f.Close()
+		return nil, ErrBadFormat
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &file{f: f, n: (st.Size() - headerSize) / recordSize}, nil
+}
+
+// 关闭文件，可以重复调用
+func (f *file) Close() error {
+	if f.f == nil {
+		return nil
+	}
+	err := f.f.Close()
+	f.f = nil
+	return err
+}
+
+func (f *file) read(i int64) (*record, error) {
+	var r record
+	sr := io.NewSectionReader(f.f, headerSize+i*recordSize, recordSize)
+	if err := binary.Read(sr, binary.LittleEndian, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (f *file) timestamp(i int64) (float64, error) {
+	var ts float64
+	sr := io.NewSectionReader(f.f, headerSize+i*recordSize, 8)
+	if err := binary.Read(sr, binary.LittleEndian, &ts); err != nil {
+		return 0, err
+	}
+	return ts, nil
+}
+
+// 第一条 Timestamp 不小于 ts 的记录下标，after 为 true 时为第一条大于 ts 的记录下标
+func (f *file) search(ts float64, after bool) (int64, error) {
+	lo, hi := int64(0), f.n
+	for lo < hi {
+		mid := int64(uint64(lo+hi) >> 1)
+		v, err := f.timestamp(mid)
+		if err != nil {
+			return 0, err
+		}
+		if v < ts || (after && v == ts) {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	return lo, nil
+}
+
+// 读取下标 [from, to) 的记录
+func (f *file) readRange(from, to int64) ([]*futuapi.KLine, error) {
+	if from >= to {
+		return nil, nil
+	}
+	sr := io.NewSectionReader(f.f, headerSize+from*recordSize, (to-from)*recordSize)
+	list := make([]*futuapi.KLine, 0, to-from)
+	for i := from; i < to; i++ {
+		var r record
+		if err := binary.Read(sr, binary.LittleEndian, &r); err != nil {
+			return nil, err
+		}
+		list = append(list, r.kLine())
+	}
+	return list, nil
+}
+
+func marshal(r *record) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, binary.LittleEndian, r); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// 写入完整文件，先写临时文件再替换，避免写入中断损坏已有数据
+func writeFile(name string, list []*futuapi.KLine) error {
+	tmp := name + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	h := fileHeader{Magic: magic, Version: version, RecSize: uint32(recordSize)}
+	if err := binary.Write(&buf, binary.LittleEndian, &h); err != nil {
+		f.Close()
+		return err
+	}
+	for _, k := range list {
+		if err := binary.Write(&buf, binary.LittleEndian, recordFromKLine(k)); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if _, err := buf.WriteTo(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, name)
+}
+
+// 在已有的 n 条记录之后追加记录，先截断末尾不完整的记录，避免之后的记录错位
+func appendFile(name string, n int64, list []*futuapi.KLine) error {
+	f, err := os.OpenFile(name, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	off := headerSize + n*recordSize
+	if err := f.Truncate(off); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Seek(off, io.SeekStart); err != nil {
+		f.Close()
+		return err
+	}
+	var buf bytes.Buffer
+	for _, k := range list {
+		if err := binary.Write(&buf, binary.LittleEndian, recordFromKLine(k)); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if _, err := buf.WriteTo(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
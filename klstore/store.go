@@ -0,0 +1,360 @@
+// Package klstore 是基于本地文件的 K 线存储，不依赖外部数据库。
+//
+// 数据按证券、K 线类型和复权类型分文件保存，记录按 Timestamp 去重并升序排列，
+// 可以从最后一根 K 线开始增量同步历史 K 线，避免每次回测都重新拉取。
+package klstore
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hurisheng/go-futu-api/pb/qotcommon"
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+)
+
+// 存储的键
+type Key struct {
+	Security  futuapi.Security    //证券
+	KLType    qotcommon.KLType    //Qot_Common.KLType,K 线类型
+	RehabType qotcommon.RehabType //Qot_Common.RehabType,复权类型
+}
+
+// K 线存储
+type Store struct {
+	dir      string
+	mu       sync.Mutex
+	iterator func(api *futuapi.FutuAPI, req *futuapi.HistoryKLineRequest) klineIterator
+}
+
+// 历史 K 线迭代器，见 futuapi.HistoryKLineIterator
+type klineIterator interface {
+	Next(ctx context.Context) bool
+	KLine() *futuapi.KLine
+	Err() error
+}
+
+// 打开目录作为 K 线存储，目录不存在时自动创建
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Store{
+		dir: dir,
+		iterator: func(api *futuapi.FutuAPI, req *futuapi.HistoryKLineRequest) klineIterator {
+			return api.HistoryKLineIterator(req)
+		},
+	}, nil
+}
+
+func (s *Store) path(key Key) string {
+	return filepath.Join(s.dir, fmt.Sprint(int32(key.Security.Market)), url.PathEscape(key.Security.Code),
+		fmt.Sprintf("%d_%d.kl", key.KLType, key.RehabType))
+}
+
+// 已保存的 K 线根数
+func (s *Store) Len(key Key) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := openFile(s.path(key))
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return int(f.n), nil
+}
+
+// 最后一根 K 线，没有数据时返回 nil
+func (s *Store) Last(key Key) (*futuapi.KLine, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := openFile(s.path(key))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if f.n == 0 {
+		return nil, nil
+	}
+	r, err := f.read(f.n - 1)
+	if err != nil {
+		return nil, err
+	}
+	return r.kLine(), nil
+}
+
+// 查询时间区间 [begin, end] 内的 K 线
+func (s *Store) Range(key Key, begin time.Time, end time.Time) ([]*futuapi.KLine, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := openFile(s.path(key))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if f.n == 0 {
+		return nil, nil
+	}
+	from, err := f.search(timestamp(begin), false)
+	if err != nil {
+		return nil, err
+	}
+	to, err := f.search(timestamp(end), true)
+	if err != nil {
+		return nil, err
+	}
+	return f.readRange(from, to)
+}
+
+// 全部 K 线
+func (s *Store) All(key Key) ([]*futuapi.KLine, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := openFile(s.path(key))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return f.readRange(0, f.n)
+}
+
+// 保存 K 线，按 Timestamp 去重，返回新增的根数。
+// 相同 Timestamp 的记录以新数据为准，但空内容的点不会覆盖已有的非空 K 线。
+func (s *Store) Put(key Key, list []*futuapi.KLine) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	in := normalize(list)
+	if len(in) == 0 {
+		return 0, nil
+	}
+	name := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return 0, err
+	}
+	f, err := openFile(name)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	if f.n == 0 {
+		f.Close()
+		return len(in), writeFile(name, in)
+	}
+	lastTS, err := f.timestamp(f.n - 1)
+	if err != nil {
+		return 0, err
+	}
+	// 新数据分为三类：追加到末尾、原位替换、插入中间（需要重写文件）
+	var (
+		tail    []*futuapi.KLine
+		replace = make(map[int64]*futuapi.KLine)
+		insert  bool
+	)
+	for _, k := range in {
+		if k.Timestamp > lastTS {
+			tail = append(tail, k)
+			continue
+		}
+		i, err := f.search(k.Timestamp, false)
+		if err != nil {
+			return 0, err
+		}
+		if ts, err := f.timestamp(i); err != nil {
+			return 0, err
+		} else if ts != k.Timestamp {
+			insert = true
+			break
+		}
+		old, err := f.read(i)
+		if err != nil {
+			return 0, err
+		}
+		if k.IsBlank && old.Flags&flagBlank == 0 {
+			continue
+		}
+		replace[i] = k
+	}
+	if insert {
+		all, err := f.readRange(0, f.n)
+		if err != nil {
+			return 0, err
+		}
+		merged := merge(all, in)
+		// 替换文件前关闭旧文件
+		f.Close()
+		return len(merged) - len(all), writeFile(name, merged)
+	}
+	f.Close()
+	if len(replace) > 0 {
+		if err := replaceFile(name, replace); err != nil {
+			return 0, err
+		}
+	}
+	if len(tail) > 0 {
+		if err := appendFile(name, f.n, tail); err != nil {
+			return 0, err
+		}
+	}
+	return len(tail), nil
+}
+
+// 连续空内容点组成的缺口
+type Gap struct {
+	Begin *futuapi.KLine //缺口第一根
+	End   *futuapi.KLine //缺口最后一根
+	Count int            //缺口根数
+}
+
+// 查询所有由空内容点组成的缺口
+func (s *Store) Gaps(key Key) ([]*Gap, error) {
+	list, err := s.All(key)
+	if err != nil {
+		return nil, err
+	}
+	var gaps []*Gap
+	var cur *Gap
+	for _, k := range list {
+		if !k.IsBlank {
+			cur = nil
+			continue
+		}
+		if cur == nil {
+			cur = &Gap{Begin: k}
+			gaps = append(gaps, cur)
+		}
+		cur.End = k
+		cur.Count++
+	}
+	return gaps, nil
+}
+
+// 从最后保存的 K 线开始增量同步到 end，没有数据时从 begin 开始，返回新增的根数。
+// 末尾连续的空内容点会重新拉取，以便补上之后才有数据的 K 线。
+func (s *Store) Sync(ctx context.Context, api *futuapi.FutuAPI, key Key, begin string, end string) (int, error) {
+	start, err := s.syncBegin(key)
+	if err != nil {
+		return 0, err
+	}
+	if start == "" {
+		start = begin
+	}
+	return s.Fill(ctx, api, key, start, end)
+}
+
+// 拉取区间 [begin, end] 的 K 线并合并保存，可用于补齐缺口，返回新增的根数
+func (s *Store) Fill(ctx context.Context, api *futuapi.FutuAPI, key Key, begin string, end string) (int, error) {
+	sec := key.Security
+	it := s.iterator(api, &futuapi.HistoryKLineRequest{
+		Security:  &sec,
+		Begin:     begin,
+		End:       end,
+		KLType:    key.KLType,
+		RehabType: key.RehabType,
+	})
+	var (
+		page  []*futuapi.KLine
+		added int
+	)
+	flush := func() error {
+		n, err := s.Put(key, page)
+		added += n
+		page = page[:0]
+		return err
+	}
+	for it.Next(ctx) {
+		page = append(page, it.KLine())
+		if len(page) >= 1000 {
+			if err := flush(); err != nil {
+				return added, err
+			}
+		}
+	}
+	if err := it.Err(); err != nil {
+		return added, err
+	}
+	return added, flush()
+}
+
+// 增量同步的开始时间：最后一根非空 K 线，末尾的空内容点会重新拉取；只有空内容点时为第一根
+func (s *Store) syncBegin(key Key) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := openFile(s.path(key))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	var start string
+	for i := f.n - 1; i >= 0; i-- {
+		r, err := f.read(i)
+		if err != nil {
+			return "", err
+		}
+		start = r.kLine().Time
+		if r.Flags&flagBlank == 0 {
+			break
+		}
+	}
+	return start, nil
+}
+
+// 原位替换记录
+func replaceFile(name string, replace map[int64]*futuapi.KLine) error {
+	f, err := os.OpenFile(name, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	for i, k := range replace {
+		b, err := marshal(recordFromKLine(k))
+		if err != nil {
+			f.Close()
+			return err
+		}
+		if _, err := f.WriteAt(b, headerSize+i*recordSize); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	return f.Close()
+}
+
+// 排序并去重，相同 Timestamp 时非空的点优先，其次以后出现的为准
+func normalize(list []*futuapi.KLine) []*futuapi.KLine {
+	in := make([]*futuapi.KLine, 0, len(list))
+	for _, k := range list {
+		if k != nil {
+			in = append(in, k)
+		}
+	}
+	sort.SliceStable(in, func(i, j int) bool {
+		return in[i].Timestamp < in[j].Timestamp
+	})
+	out := in[:0]
+	for _, k := range in {
+		if n := len(out); n > 0 && out[n-1].Timestamp == k.Timestamp {
+			if !k.IsBlank || out[n-1].IsBlank {
+				out[n-1] = k
+			}
+			continue
+		}
+		out = append(out, k)
+	}
+	return out
+}
+
+// 合并两个有序列表，规则同 Put
+func merge(old []*futuapi.KLine, in []*futuapi.KLine) []*futuapi.KLine {
+	list := make([]*futuapi.KLine, 0, len(old)+len(in))
+	list = append(list, old...)
+	list = append(list, in...)
+	return normalize(list)
+}
+
+func timestamp(t time.Time) float64 {
+	return float64(t.UnixNano()) / 1e9
+}
@@ -0,0 +1,200 @@
+package klstore
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hurisheng/go-futu-api/pb/qotcommon"
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+)
+
+func TestStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "klstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := Key{
+		Security:  futuapi.Security{Market: qotcommon.QotMarket_QotMarket_HK_Security, Code: "00700"},
+		KLType:    qotcommon.KLType_KLType_Day,
+		RehabType: qotcommon.RehabType_RehabType_None,
+	}
+	kl := func(ts float64, close float64, blank bool) *futuapi.KLine {
+		return &futuapi.KLine{Time: time.Unix(int64(ts), 0).UTC().Format("2006-01-02 15:04:05"), Timestamp: ts, ClosePrice: close, IsBlank: blank}
+	}
+
+	if n, err := s.Put(key, []*futuapi.KLine{kl(100, 1, false), kl(300, 3, false), kl(200, 2, false), kl(300, 3.5, false)}); err != nil || n != 3 {
+		t.Fatalf("put: n = %v, err = %v", n, err)
+	}
+	// 追加、原位替换，空内容点不覆盖已有数据
+	if n, err := s.Put(key, []*futuapi.KLine{kl(300, 0, true), kl(400, 0, true), kl(500, 5, false)}); err != nil || n != 2 {
+		t.Fatalf("append: n = %v, err = %v", n, err)
+	}
+	// 空内容点被后来的数据替换，插入中间的数据触发重写
+	if n, err := s.Put(key, []*futuapi.KLine{kl(400, 4, false), kl(150, 1.5, false)}); err != nil || n != 1 {
+		t.Fatalf("insert: n = %v, err = %v", n, err)
+	}
+
+	all, err := s.All(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []float64{1, 1.5, 2, 3.5, 4, 5}
+	if len(all) != len(want) {
+		t.Fatalf("len = %v, want %v", len(all), len(want))
+	}
+	for i, k := range all {
+		if k.ClosePrice != want[i] || k.IsBlank {
+			t.Errorf("kline %v = %+v, want close %v", i, k, want[i])
+		}
+	}
+
+	r, err := s.Range(key, time.Unix(150, 0), time.Unix(400, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r) != 4 || r[0].Timestamp != 150 || r[3].Timestamp != 400 {
+		t.Errorf("range = %v", r)
+	}
+	if last, err := s.Last(key); err != nil || last.Timestamp != 500 || last.Time != all[5].Time {
+		t.Errorf("last = %+v, err = %v", last, err)
+	}
+}
+
+func testStore(t *testing.T) (*Store, Key, func()) {
+	dir, err := ioutil.TempDir("", "klstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := Open(dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	key := Key{
+		Security:  futuapi.Security{Market: qotcommon.QotMarket_QotMarket_HK_Security, Code: "00700"},
+		KLType:    qotcommon.KLType_KLType_Day,
+		RehabType: qotcommon.RehabType_RehabType_None,
+	}
+	return s, key, func() { os.RemoveAll(dir) }
+}
+
+func testKLine(ts float64, close float64, blank bool) *futuapi.KLine {
+	return &futuapi.KLine{Time: time.Unix(int64(ts), 0).UTC().Format("2006-01-02 15:04:05"), Timestamp: ts, ClosePrice: close, IsBlank: blank}
+}
+
+func TestStoreTornWrite(t *testing.T) {
+	s, key, cleanup := testStore(t)
+	defer cleanup()
+	if _, err := s.Put(key, []*futuapi.KLine{testKLine(100, 1, false), testKLine(200, 2, false)}); err != nil {
+		t.Fatal(err)
+	}
+	// 模拟追加时中断，末尾留下半条记录
+	f, err := os.OpenFile(s.path(key), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write(make([]byte, recordSize/2)); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if n, err := s.Len(key); err != nil || n != 2 {
+		t.Fatalf("len = %v, err = %v", n, err)
+	}
+	if n, err := s.Put(key, []*futuapi.KLine{testKLine(300, 3, false)}); err != nil || n != 1 {
+		t.Fatalf("append: n = %v, err = %v", n, err)
+	}
+	all, err := s.All(key)
+	if err != nil || len(all) != 3 {
+		t.Fatalf("all = %v, err = %v", all, err)
+	}
+	for i, k := range all {
+		if k.Timestamp != float64(100*(i+1)) || k.ClosePrice != float64(i+1) {
+			t.Errorf("kline %d = %+v", i, k)
+		}
+	}
+	if st, err := os.Stat(s.path(key)); err != nil || st.Size() != headerSize+3*recordSize {
+		t.Errorf("file size %v, err = %v", st.Size(), err)
+	}
+}
+
+// 按请求区间返回数据的迭代器
+type testIterator struct {
+	list []*futuapi.KLine
+	cur  *futuapi.KLine
+}
+
+func (it *testIterator) Next(ctx context.Context) bool {
+	if len(it.list) == 0 {
+		it.cur = nil
+		return false
+	}
+	it.cur, it.list = it.list[0], it.list[1:]
+	return true
+}
+
+func (it *testIterator) KLine() *futuapi.KLine {
+	return it.cur
+}
+
+func (it *testIterator) Err() error {
+	return nil
+}
+
+func TestStoreSync(t *testing.T) {
+	s, key, cleanup := testStore(t)
+	defer cleanup()
+	// 服务器上的数据，400 之后才有内容
+	server := []*futuapi.KLine{testKLine(100, 1, false), testKLine(200, 2, false), testKLine(300, 3, false),
+		testKLine(400, 4, false), testKLine(500, 5, false)}
+	var begins []string
+	s.iterator = func(api *futuapi.FutuAPI, req *futuapi.HistoryKLineRequest) klineIterator {
+		begins = append(begins, req.Begin)
+		it := &testIterator{}
+		for _, k := range server {
+			if k.Time >= req.Begin && k.Time <= req.End {
+				it.list = append(it.list, k)
+			}
+		}
+		return it
+	}
+	end := server[4].Time
+	if _, err := s.Put(key, []*futuapi.KLine{testKLine(100, 1, false), testKLine(300, 3, false), testKLine(400, 0, true)}); err != nil {
+		t.Fatal(err)
+	}
+	// 从末尾空内容点之前的最后一根开始同步
+	if n, err := s.Sync(context.Background(), nil, key, server[0].Time, end); err != nil || n != 1 {
+		t.Fatalf("sync: n = %v, err = %v", n, err)
+	}
+	if begins[0] != server[2].Time {
+		t.Errorf("sync begin %v", begins)
+	}
+	// 补齐中间的缺口
+	if n, err := s.Fill(context.Background(), nil, key, server[0].Time, server[2].Time); err != nil || n != 1 {
+		t.Fatalf("fill: n = %v, err = %v", n, err)
+	}
+	all, err := s.All(key)
+	if err != nil || len(all) != 5 {
+		t.Fatalf("all = %v, err = %v", all, err)
+	}
+	for i, k := range all {
+		if k.ClosePrice != float64(i+1) || k.IsBlank {
+			t.Errorf("kline %d = %+v", i, k)
+		}
+	}
+
+	// 没有数据时从 begin 开始
+	key.KLType = qotcommon.KLType_KLType_Week
+	begins = nil
+	if n, err := s.Sync(context.Background(), nil, key, server[1].Time, end); err != nil || n != 4 || begins[0] != server[1].Time {
+		t.Errorf("sync empty: n = %v, err = %v, begins %v", n, err, begins)
+	}
+}
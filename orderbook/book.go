@@ -0,0 +1,168 @@
+// Package orderbook 维护每只证券最新的买卖盘，并计算价差、中间价、微观价格、
+// 深度加权的买卖不平衡度和累计深度等微观结构指标。
+package orderbook
+
+import (
+	"time"
+
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+)
+
+// 买卖方向
+type Side int
+
+const (
+	Bid Side = iota //买盘
+	Ask             //卖盘
+)
+
+// 一档买卖盘
+type Level struct {
+	Price       float64
+	Volume      int64
+	OrderCount  int32
+	Details     []*futuapi.OrderBookDetail //逐笔委托，订阅了 SF 行情时才有
+	SvrRecvTime time.Time                  //富途服务器从交易所收到该方向数据的时间，没有时为零值
+}
+
+// 一只证券某一时刻的买卖盘，创建后不再修改，可以在多个 goroutine 中读取
+type Book struct {
+	Security *futuapi.Security
+	Bids     []Level   //买盘，价格从高到低
+	Asks     []Level   //卖盘，价格从低到高
+	Received time.Time //本地收到数据的时间
+}
+
+func levels(list []*futuapi.OrderBook, t time.Time) []Level {
+	out := make([]Level, 0, len(list))
+	for _, o := range list {
+		if o == nil {
+			continue
+		}
+		out = append(out, Level{Price: o.Price, Volume: o.Volume, OrderCount: o.OrderCount, Details: o.Details, SvrRecvTime: t})
+	}
+	return out
+}
+
+// 由买卖盘数据创建，received 为本地收到数据的时间
+func New(rt *futuapi.RTOrderBook, received time.Time) *Book {
+	loc := rt.Security.Location()
+	return &Book{
+		Security: rt.Security,
+		Bids:     levels(rt.Bids, futuapi.TimeOf(rt.SvrRecvTimeBid, rt.SvrRecvTimeBidTimestamp, loc)),
+		Asks:     levels(rt.Asks, futuapi.TimeOf(rt.SvrRecvTimeAsk, rt.SvrRecvTimeAskTimestamp, loc)),
+		Received: received,
+	}
+}
+
+// 一个方向的买卖盘
+func (b *Book) Side(side Side) []Level {
+	if side == Bid {
+		return b.Bids
+	}
+	return b.Asks
+}
+
+// 买一，没有买盘时返回 false
+func (b *Book) BestBid() (Level, bool) {
+	if len(b.Bids) == 0 {
+		return Level{}, false
+	}
+	return b.Bids[0], true
+}
+
+// 卖一，没有卖盘时返回 false
+func (b *Book) BestAsk() (Level, bool) {
+	if len(b.Asks) == 0 {
+		return Level{}, false
+	}
+	return b.Asks[0], true
+}
+
+// 买卖价差，缺少一方时为0
+func (b *Book) Spread() float64 {
+	bid, ok1 := b.BestBid()
+	ask, ok2 := b.BestAsk()
+	if !ok1 || !ok2 {
+		return 0
+	}
+	return ask.Price - bid.Price
+}
+
+// 买一卖一的中间价，缺少一方时为另一方的价格，都没有时为0
+func (b *Book) Mid() float64 {
+	bid, ok1 := b.BestBid()
+	ask, ok2 := b.BestAsk()
+	switch {
+	case ok1 && ok2:
+		return (bid.Price + ask.Price) / 2
+	case ok1:
+		return bid.Price
+	case ok2:
+		return ask.Price
+	}
+	return 0
+}
+
+// 微观价格：买一卖一价格按对方数量加权，买盘量大时靠近卖一，缺少一方时同 Mid
+func (b *Book) Microprice() float64 {
+	bid, ok1 := b.BestBid()
+	ask, ok2 := b.BestAsk()
+	if !ok1 || !ok2 || bid.Volume+ask.Volume == 0 {
+		return b.Mid()
+	}
+	return (bid.Price*float64(ask.Volume) + ask.Price*float64(bid.Volume)) / float64(bid.Volume+ask.Volume)
+}
+
+// 前 depth 档的深度加权买卖不平衡度，第 i 档（从0开始）的权重为 1/(i+1)。
+// 结果在 -1 到 1 之间，正数表示买盘较强；depth 不大于0时使用全部档位，没有数据时为0。
+func (b *Book) Imbalance(depth int) float64 {
+	weighted := func(list []Level) float64 {
+		var sum float64
+		for i, l := range list {
+			if depth > 0 && i >= depth {
+				break
+			}
+			sum += float64(l.Volume) / float64(i+1)
+		}
+		return sum
+	}
+	bid, ask := weighted(b.Bids), weighted(b.Asks)
+	if bid+ask == 0 {
+		return 0
+	}
+	return (bid - ask) / (bid + ask)
+}
+
+// 价格不差于 price 的累计委托数量，买盘为价格不低于 price 的档位，卖盘为价格不高于 price 的档位
+func (b *Book) DepthAt(side Side, price float64) int64 {
+	var sum int64
+	for _, l := range b.Side(side) {
+		if (side == Bid && l.Price < price) || (side == Ask && l.Price > price) {
+			break
+		}
+		sum += l.Volume
+	}
+	return sum
+}
+
+// 前 n 档的累计委托数量，n 不大于0时为全部档位
+func (b *Book) Depth(side Side, n int) int64 {
+	var sum int64
+	for i, l := range b.Side(side) {
+		if n > 0 && i >= n {
+			break
+		}
+		sum += l.Volume
+	}
+	return sum
+}
+
+// 一个方向从服务器收到数据到本地收到的延时，没有服务器时间时为0
+func (b *Book) Latency(side Side) time.Duration {
+	list := b.Side(side)
+	if len(list) == 0 || list[0].SvrRecvTime.IsZero() {
+		return 0
+	}
+	return b.Received.Sub(list[0].SvrRecvTime)
+}
@@ -0,0 +1,57 @@
+package orderbook
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/hurisheng/go-futu-api/pb/qotcommon"
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+)
+
+func TestBook(t *testing.T) {
+	sec := &futuapi.Security{Market: qotcommon.QotMarket_QotMarket_HK_Security, Code: "00700"}
+	rt := &futuapi.RTOrderBook{
+		Security:       sec,
+		Bids:           []*futuapi.OrderBook{{Price: 99.9, Volume: 300}, {Price: 99.8, Volume: 200}},
+		Asks:           []*futuapi.OrderBook{{Price: 100.1, Volume: 100}, {Price: 100.2, Volume: 400}},
+		SvrRecvTimeBid: "2024-03-20 10:00:00.000",
+	}
+	received := time.Date(2024, 3, 20, 10, 0, 0, int(25*time.Millisecond), sec.Location())
+	books := NewBooks(1)
+	books.Update(rt)
+	b := New(rt, received)
+
+	if math.Abs(b.Spread()-0.2) > 1e-9 || math.Abs(b.Mid()-100) > 1e-9 {
+		t.Errorf("spread %v, mid %v", b.Spread(), b.Mid())
+	}
+	if mp := b.Microprice(); math.Abs(mp-(99.9*100+100.1*300)/400) > 1e-9 {
+		t.Errorf("microprice %v", mp)
+	}
+	if im := b.Imbalance(2); math.Abs(im-(400.0-300)/(400+300)) > 1e-9 {
+		t.Errorf("imbalance %v", im)
+	}
+	if d := b.DepthAt(Bid, 99.8); d != 500 {
+		t.Errorf("bid depth %v", d)
+	}
+	if d := b.DepthAt(Ask, 100.15); d != 100 {
+		t.Errorf("ask depth %v", d)
+	}
+	if l := b.Latency(Bid); l != 25*time.Millisecond {
+		t.Errorf("latency %v", l)
+	}
+	if l := b.Latency(Ask); l != 0 {
+		t.Errorf("ask latency %v", l)
+	}
+	if got := books.Get(sec); got == nil || <-books.Updates() != got {
+		t.Error("books not updated")
+	}
+	if books.Get(nil) != nil {
+		t.Error("book for nil security")
+	}
+	books.Remove(nil)
+	books.Remove(sec)
+	if books.Get(sec) != nil {
+		t.Error("book not removed")
+	}
+}
@@ -0,0 +1,101 @@
+package orderbook
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+)
+
+// 多只证券的最新买卖盘
+type Books struct {
+	mu    sync.RWMutex
+	books map[futuapi.Security]*Book
+	ch    chan *Book
+}
+
+// 创建买卖盘集合，buffer 大于0时每次更新后向 Updates 通道发送最新买卖盘，通道满时丢弃
+func NewBooks(buffer int) *Books {
+	b := &Books{books: make(map[futuapi.Security]*Book)}
+	if buffer > 0 {
+		b.ch = make(chan *Book, buffer)
+	}
+	return b
+}
+
+// 买卖盘更新通道，创建时 buffer 为0时为空
+func (b *Books) Updates() <-chan *Book {
+	return b.ch
+}
+
+// 用推送或请求得到的完整买卖盘替换原有数据
+func (b *Books) Update(rt *futuapi.RTOrderBook) *Book {
+	if rt == nil || rt.Security == nil {
+		return nil
+	}
+	book := New(rt, time.Now())
+	b.mu.Lock()
+	b.books[*rt.Security] = book
+	b.mu.Unlock()
+	if b.ch != nil {
+		select {
+		case b.ch <- book:
+		default:
+		}
+	}
+	return book
+}
+
+// 证券的最新买卖盘，没有数据时返回空
+func (b *Books) Get(sec *futuapi.Security) *Book {
+	if sec == nil {
+		return nil
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.books[*sec]
+}
+
+// 删除证券的买卖盘，如取消订阅后
+func (b *Books) Remove(sec *futuapi.Security) {
+	if sec == nil {
+		return
+	}
+	b.mu.Lock()
+	delete(b.books, *sec)
+	b.mu.Unlock()
+}
+
+// 通过 GetOrderBook 获取证券的买卖盘，num 为档数。接口调用遵守频率限制。
+func (b *Books) Load(ctx context.Context, api *futuapi.FutuAPI, securities []*futuapi.Security, num int32) error {
+	for _, sec := range securities {
+		if err := api.WaitRateLimit(ctx, futuapi.ProtoIDQotGetOrderBook); err != nil {
+			return err
+		}
+		rt, err := api.GetOrderBook(ctx, sec, num)
+		if err != nil {
+			return err
+		}
+		b.Update(rt)
+	}
+	return nil
+}
+
+// 从推送通道读取买卖盘，调用方需要订阅买卖盘推送。
+// ctx 结束时返回 ErrInterrupted，通道关闭时返回 ErrChannelClosed。
+func (b *Books) Run(ctx context.Context, ch <-chan *futuapi.UpdateOrderBookResp) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return futuapi.ErrInterrupted
+		case resp, ok := <-ch:
+			if !ok {
+				return futuapi.ErrChannelClosed
+			}
+			if resp.Err == nil {
+				b.Update(resp.OrderBook)
+			}
+		}
+	}
+}
@@ -0,0 +1,252 @@
+// Package pricing 在本地计算期权理论价格、隐含波动率和希腊值，
+// 支持欧式期权的 Black-Scholes 公式和美式期权的二叉树（以远期价格为中心）模型，
+// 不依赖服务器返回的延时希腊值。
+//
+// 希腊值的单位与行情软件一致：Vega 为波动率变化1个百分点的价格变化，Theta 为每个自然日的价格变化，
+// Rho 为利率变化1个百分点的价格变化。
+package pricing
+
+import (
+	"errors"
+	"math"
+)
+
+var (
+	// 价格超出模型可以达到的范围，无法求解隐含波动率
+	ErrNoSolution = errors.New("pricing: no implied volatility for price")
+	// 参数无效，如到期时间、行权价、标的价格不为正数
+	ErrInvalidParams = errors.New("pricing: invalid parameters")
+)
+
+// 定价参数
+type Params struct {
+	Spot   float64 //标的价格
+	Strike float64 //行权价
+	T      float64 //距离到期的年数
+	Rate   float64 //无风险利率，连续复利，0.03 表示3%
+	Div    float64 //股息率，连续复利
+	Vol    float64 //波动率，0.2 表示20%
+	Call   bool    //是否看涨
+}
+
+func (p Params) valid() bool {
+	return p.Spot > 0 && p.Strike > 0 && p.T > 0 && p.Vol > 0
+}
+
+// 到期时的内在价值
+func (p Params) intrinsic() float64 {
+	if p.Call {
+		return math.Max(p.Spot-p.Strike, 0)
+	}
+	return math.Max(p.Strike-p.Spot, 0)
+}
+
+// 希腊值
+type Greeks struct {
+	Delta float64
+	Gamma float64
+	Vega  float64 //波动率变化1个百分点
+	Theta float64 //每个自然日
+	Rho   float64 //利率变化1个百分点
+}
+
+// 定价模型
+type Model interface {
+	Price(p Params) float64
+	Greeks(p Params) Greeks
+}
+
+// Black-Scholes-Merton 欧式期权模型
+type BlackScholes struct{}
+
+func normCDF(x float64) float64 {
+	return 0.5 * math.Erfc(-x/math.Sqrt2)
+}
+
+func normPDF(x float64) float64 {
+	return math.Exp(-x*x/2) / math.Sqrt(2*math.Pi)
+}
+
+func d1d2(p Params) (float64, float64) {
+	sqrtT := math.Sqrt(p.T)
+	d1 := (math.Log(p.Spot/p.Strike) + (p.Rate-p.Div+p.Vol*p.Vol/2)*p.T) / (p.Vol * sqrtT)
+	return d1, d1 - p.Vol*sqrtT
+}
+
+func (BlackScholes) Price(p Params) float64 {
+	if !p.valid() {
+		return p.intrinsic()
+	}
+	d1, d2 := d1d2(p)
+	df, qf := math.Exp(-p.Rate*p.T), math.Exp(-p.Div*p.T)
+	if p.Call {
+		return p.Spot*qf*normCDF(d1) - p.Strike*df*normCDF(d2)
+	}
+	return p.Strike*df*normCDF(-d2) - p.Spot*qf*normCDF(-d1)
+}
+
+func (BlackScholes) Greeks(p Params) Greeks {
+	if !p.valid() {
+		return Greeks{}
+	}
+	d1, d2 := d1d2(p)
+	df, qf := math.Exp(-p.Rate*p.T), math.Exp(-p.Div*p.T)
+	sqrtT := math.Sqrt(p.T)
+	g := Greeks{
+		Gamma: qf * normPDF(d1) / (p.Spot * p.Vol * sqrtT),
+		Vega:  p.Spot * qf * normPDF(d1) * sqrtT / 100,
+	}
+	decay := -p.Spot * qf * normPDF(d1) * p.Vol / (2 * sqrtT)
+	if p.Call {
+		g.Delta = qf * normCDF(d1)
+		g.Theta = (decay - p.Rate*p.Strike*df*normCDF(d2) + p.Div*p.Spot*qf*normCDF(d1)) / 365
+		g.Rho = p.Strike * p.T * df * normCDF(d2) / 100
+	} else {
+		g.Delta = qf * (normCDF(d1) - 1)
+		g.Theta = (decay + p.Rate*p.Strike*df*normCDF(-d2) - p.Div*p.Spot*qf*normCDF(-d1)) / 365
+		g.Rho = -p.Strike * p.T * df * normCDF(-d2) / 100
+	}
+	return g
+}
+
+// 以远期价格为中心的二叉树模型（u、d 为 exp((r-q)dt ± σ√dt)），低波动率时风险中性概率仍在0到1之间，
+// 步数增加时收敛到 Black-Scholes。American 为 true 时允许提前行权
+type Binomial struct {
+	Steps    int //步数，0 为默认值200
+	American bool
+}
+
+func (b Binomial) steps() int {
+	if b.Steps <= 0 {
+		return 200
+	}
+	return b.Steps
+}
+
+func (b Binomial) Price(p Params) float64 {
+	if !p.valid() {
+		return p.intrinsic()
+	}
+	n := b.steps()
+	dt := p.T / float64(n)
+	drift := (p.Rate - p.Div) * dt
+	u := math.Exp(drift + p.Vol*math.Sqrt(dt))
+	d := math.Exp(drift - p.Vol*math.Sqrt(dt))
+	q := (math.Exp(drift) - d) / (u - d)
+	disc := math.Exp(-p.Rate * dt)
+	payoff := func(s float64) float64 {
+		if p.Call {
+			return math.Max(s-p.Strike, 0)
+		}
+		return math.Max(p.Strike-s, 0)
+	}
+	// 节点价格 Spot*u^i*d^(step-i)，逐个乘 u/d 得到，向前一步时除以 d
+	prices := make([]float64, n+1)
+	values := make([]float64, n+1)
+	prices[0] = p.Spot * math.Pow(d, float64(n))
+	for i := 0; i <= n; i++ {
+		if i > 0 {
+			prices[i] = prices[i-1] * u / d
+		}
+		values[i] = payoff(prices[i])
+	}
+	for step := n - 1; step >= 0; step-- {
+		for i := 0; i <= step; i++ {
+			v := disc * (q*values[i+1] + (1-q)*values[i])
+			if b.American {
+				prices[i] /= d
+				v = math.Max(v, payoff(prices[i]))
+			}
+			values[i] = v
+		}
+	}
+	return values[0]
+}
+
+// 有限差分计算希腊值
+func (b Binomial) Greeks(p Params) Greeks {
+	return finiteGreeks(b, p)
+}
+
+// 对任意模型用中心差分计算希腊值
+func finiteGreeks(m Model, p Params) Greeks {
+	if !p.valid() {
+		return Greeks{}
+	}
+	at := func(f func(*Params)) float64 {
+		c := p
+		f(&c)
+		return m.Price(c)
+	}
+	hs := p.Spot * 0.01
+	up := at(func(c *Params) { c.Spot += hs })
+	down := at(func(c *Params) { c.Spot -= hs })
+	mid := m.Price(p)
+	g := Greeks{
+		Delta: (up - down) / (2 * hs),
+		Gamma: (up - 2*mid + down) / (hs * hs),
+		Vega:  (at(func(c *Params) { c.Vol += 0.005 }) - at(func(c *Params) { c.Vol -= 0.005 })) / 0.01 / 100,
+		Rho:   (at(func(c *Params) { c.Rate += 0.0005 }) - at(func(c *Params) { c.Rate -= 0.0005 })) / 0.001 / 100,
+	}
+	day := 1.0 / 365
+	if p.T > day {
+		g.Theta = at(func(c *Params) { c.T -= day }) - mid
+	} else {
+		g.Theta = p.intrinsic() - mid
+	}
+	return g
+}
+
+// 隐含波动率的搜索范围和精度
+const (
+	minVol = 1e-4
+	maxVol = 5.0
+	volTol = 1e-8
+)
+
+// 根据期权价格求隐含波动率，p.Vol 被忽略。
+// 先用 Newton 法（Black-Scholes 的 Vega），不收敛时用二分法，适用于任意模型。
+func ImpliedVol(m Model, price float64, p Params) (float64, error) {
+	if p.Spot <= 0 || p.Strike <= 0 || p.T <= 0 || price <= 0 {
+		return 0, ErrInvalidParams
+	}
+	f := func(vol float64) float64 {
+		c := p
+		c.Vol = vol
+		return m.Price(c) - price
+	}
+	lo, hi := minVol, maxVol
+	flo, fhi := f(lo), f(hi)
+	if flo > 0 || fhi < 0 {
+		return 0, ErrNoSolution
+	}
+	if _, ok := m.(BlackScholes); ok {
+		vol := 0.3
+		for i := 0; i < 50; i++ {
+			c := p
+			c.Vol = vol
+			diff := f(vol)
+			if math.Abs(diff) < volTol {
+				return vol, nil
+			}
+			vega := BlackScholes{}.Greeks(c).Vega * 100
+			if vega < 1e-10 {
+				break
+			}
+			next := vol - diff/vega
+			if next <= lo || next >= hi {
+				break
+			}
+			vol = next
+		}
+	}
+	for i := 0; i < 200 && hi-lo > volTol; i++ {
+		mid := (lo + hi) / 2
+		if f(mid) > 0 {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	return (lo + hi) / 2, nil
+}
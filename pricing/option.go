@@ -0,0 +1,205 @@
+package pricing
+
+import (
+	"errors"
+	"time"
+
+	"github.com/hurisheng/go-futu-api/pb/qotcommon"
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+)
+
+var (
+	// 没有快照或买卖价，无法得到期权价格
+	ErrNoQuote = errors.New("pricing: no quote for option")
+	// 期权已到期
+	ErrExpired = errors.New("pricing: option expired")
+)
+
+// 默认的到期时间，行权日当天16:00（期权所在市场的时区）
+const DefaultExpiryTime = 16 * time.Hour
+
+// 期权定价配置
+type Config struct {
+	Rate       float64       //无风险利率，连续复利
+	Div        float64       //标的股息率，连续复利
+	Steps      int           //美式期权二叉树步数，0 为默认值
+	ExpiryTime time.Duration //到期时间在行权日内的偏移，0 为 DefaultExpiryTime
+}
+
+func (c *Config) expiryTime() time.Duration {
+	if c == nil || c.ExpiryTime <= 0 {
+		return DefaultExpiryTime
+	}
+	return c.ExpiryTime
+}
+
+// 定价结果
+type Result struct {
+	Price        float64 //期权市场价格，买卖价的中间价，没有买卖价时为最新价
+	Theo         float64 //按隐含波动率计算的理论价格
+	IV           float64 //隐含波动率，0.2 表示20%，无解时为0
+	Greeks       Greeks  //每股希腊值
+	ContractSize float64 //每份合约的股数
+	Params       Params  //定价参数，Vol 为隐含波动率
+	American     bool    //是否按美式期权定价
+}
+
+// 每份合约的希腊值，即每股希腊值乘以合约股数
+func (r *Result) ContractGreeks() Greeks {
+	return r.Greeks.Scale(r.ContractSize)
+}
+
+// 希腊值乘以数量
+func (g Greeks) Scale(n float64) Greeks {
+	return Greeks{
+		Delta: g.Delta * n,
+		Gamma: g.Gamma * n,
+		Vega:  g.Vega * n,
+		Theta: g.Theta * n,
+		Rho:   g.Rho * n,
+	}
+}
+
+// 期权的市场价格：买卖价都有效时取中间价，否则取最新价
+func MidPrice(s *futuapi.Snapshot) float64 {
+	if s == nil || s.Basic == nil {
+		return 0
+	}
+	b := s.Basic
+	if b.BidPrice > 0 && b.AskPrice >= b.BidPrice {
+		return (b.BidPrice + b.AskPrice) / 2
+	}
+	return b.CurPrice
+}
+
+// 期权的到期时间，行权日为期权所在市场的日期
+func Expiry(q *futuapi.OptionQuote, cfg *Config) (time.Time, error) {
+	t, err := time.ParseInLocation("2006-01-02", q.Expiry, q.Security().Location())
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.Add(cfg.expiryTime()), nil
+}
+
+// 每份合约的股数，优先使用静态信息中的每手数量，其次为快照中的合约数
+func ContractSize(q *futuapi.OptionQuote) float64 {
+	if q.Info != nil && q.Info.Basic != nil && q.Info.Basic.LotSize > 0 {
+		return float64(q.Info.Basic.LotSize)
+	}
+	if o := q.Option(); o != nil {
+		if o.ContractSizeFloat > 0 {
+			return o.ContractSizeFloat
+		}
+		return float64(o.ContractSize)
+	}
+	return 0
+}
+
+// 是否按美式期权定价：以快照中的行权类型为准，未知时指数期权为欧式，其他为美式
+func IsAmerican(q *futuapi.OptionQuote) bool {
+	if o := q.Option(); o != nil {
+		switch o.OptionAreaType {
+		case qotcommon.OptionAreaType_OptionAreaType_American, qotcommon.OptionAreaType_OptionAreaType_Bermuda:
+			return true
+		case qotcommon.OptionAreaType_OptionAreaType_European:
+			return false
+		}
+	}
+	if q.Info != nil && q.Info.OptionExData != nil && q.Info.OptionExData.IndexOptType != qotcommon.IndexOptionType_IndexOptionType_Unknown {
+		return false
+	}
+	return true
+}
+
+// 期权使用的定价模型，美式期权为二叉树，欧式期权为 Black-Scholes
+func ModelFor(q *futuapi.OptionQuote, cfg *Config) Model {
+	if IsAmerican(q) {
+		b := Binomial{American: true}
+		if cfg != nil {
+			b.Steps = cfg.Steps
+		}
+		return b
+	}
+	return BlackScholes{}
+}
+
+// 期权在 now 时刻的定价参数，Vol 为0
+func ParamsFor(q *futuapi.OptionQuote, spot float64, now time.Time, cfg *Config) (Params, error) {
+	expiry, err := Expiry(q, cfg)
+	if err != nil {
+		return Params{}, err
+	}
+	if !expiry.After(now) {
+		return Params{}, ErrExpired
+	}
+	p := Params{
+		Spot:   spot,
+		Strike: q.Strike,
+		T:      expiry.Sub(now).Hours() / 24 / 365,
+		Call:   q.Type == qotcommon.OptionType_OptionType_Call,
+	}
+	if cfg != nil {
+		p.Rate, p.Div = cfg.Rate, cfg.Div
+	}
+	if p.Spot <= 0 || p.Strike <= 0 {
+		return p, ErrInvalidParams
+	}
+	return p, nil
+}
+
+// 根据快照中的买卖价计算期权的隐含波动率和希腊值，spot 为标的价格。
+// 隐含波动率无解时（如价格低于内在价值）返回 ErrNoSolution，同时返回价格和定价参数。
+func Evaluate(q *futuapi.OptionQuote, spot float64, now time.Time, cfg *Config) (*Result, error) {
+	price := MidPrice(q.Snapshot)
+	if price <= 0 {
+		return nil, ErrNoQuote
+	}
+	p, err := ParamsFor(q, spot, now, cfg)
+	if err != nil {
+		return nil, err
+	}
+	m := ModelFor(q, cfg)
+	r := &Result{
+		Price:        price,
+		ContractSize: ContractSize(q),
+		Params:       p,
+	}
+	_, r.American = m.(Binomial)
+	vol, err := ImpliedVol(m, price, p)
+	if err != nil {
+		return r, err
+	}
+	r.IV = vol
+	r.Params.Vol = vol
+	r.Theo = m.Price(r.Params)
+	r.Greeks = m.Greeks(r.Params)
+	return r, nil
+}
+
+// 期权链中使用的定价结果
+func (r *Result) Model() *futuapi.OptionModel {
+	return &futuapi.OptionModel{
+		Price: r.Price,
+		Theo:  r.Theo,
+		IV:    r.IV,
+		Delta: r.Greeks.Delta,
+		Gamma: r.Greeks.Gamma,
+		Vega:  r.Greeks.Vega,
+		Theta: r.Greeks.Theta,
+		Rho:   r.Greeks.Rho,
+	}
+}
+
+// 计算期权表中所有期权的隐含波动率和希腊值，结果同时写入各期权的 Model 字段，
+// 无法计算的期权 Model 为空，也不在返回的结果中
+func EvaluateTable(t *futuapi.OptionTable, spot float64, now time.Time, cfg *Config) map[futuapi.OptionKey]*Result {
+	results := make(map[futuapi.OptionKey]*Result, len(t.Quotes))
+	for k, q := range t.Quotes {
+		q.Model = nil
+		if r, err := Evaluate(q, spot, now, cfg); err == nil {
+			results[k] = r
+			q.Model = r.Model()
+		}
+	}
+	return results
+}
@@ -0,0 +1,97 @@
+package pricing
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/hurisheng/go-futu-api/pb/qotcommon"
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+)
+
+func near(a, b, tol float64) bool {
+	return math.Abs(a-b) <= tol
+}
+
+func TestBlackScholes(t *testing.T) {
+	p := Params{Spot: 100, Strike: 100, T: 1, Rate: 0.05, Vol: 0.2, Call: true}
+	call := BlackScholes{}.Price(p)
+	if !near(call, 10.4506, 1e-4) {
+		t.Errorf("call = %v", call)
+	}
+	p.Call = false
+	put := BlackScholes{}.Price(p)
+	if parity := call - put - (100 - 100*math.Exp(-0.05)); !near(parity, 0, 1e-9) {
+		t.Errorf("put-call parity off by %v", parity)
+	}
+	g := BlackScholes{}.Greeks(p)
+	fg := finiteGreeks(BlackScholes{}, p)
+	if !near(g.Delta, fg.Delta, 1e-4) || !near(g.Gamma, fg.Gamma, 1e-4) || !near(g.Vega, fg.Vega, 1e-4) ||
+		!near(g.Rho, fg.Rho, 1e-4) || !near(g.Theta, fg.Theta, 1e-3) {
+		t.Errorf("greeks %+v, finite difference %+v", g, fg)
+	}
+}
+
+func TestBinomial(t *testing.T) {
+	p := Params{Spot: 100, Strike: 110, T: 0.5, Rate: 0.05, Vol: 0.3}
+	if bs, bin := (BlackScholes{}).Price(p), (Binomial{Steps: 500}).Price(p); !near(bs, bin, 0.02) {
+		t.Errorf("european binomial %v, black-scholes %v", bin, bs)
+	}
+	if eu, am := (Binomial{}).Price(p), (Binomial{American: true}).Price(p); am <= eu || am < p.intrinsic() {
+		t.Errorf("american put %v, european put %v", am, eu)
+	}
+}
+
+func TestImpliedVol(t *testing.T) {
+	for _, m := range []Model{BlackScholes{}, Binomial{American: true}} {
+		p := Params{Spot: 50, Strike: 55, T: 0.25, Rate: 0.02, Vol: 0.35}
+		vol, err := ImpliedVol(m, m.Price(p), p)
+		if err != nil || !near(vol, 0.35, 1e-5) {
+			t.Errorf("%T: vol = %v, %v", m, vol, err)
+		}
+		atm := Params{Spot: 100, Strike: 100, T: 0.25, Rate: 0.05, Call: true}
+		if vol, err := ImpliedVol(m, 5, atm); err != nil || vol < 0.2 || vol > 0.35 {
+			t.Errorf("%T: atm vol = %v, %v", m, vol, err)
+		}
+		if _, err := ImpliedVol(m, 1, p); err != ErrNoSolution {
+			t.Errorf("%T: below intrinsic value: %v", m, err)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	sec := &futuapi.Security{Market: qotcommon.QotMarket_QotMarket_US_Security, Code: "AAPL240621C190000"}
+	q := &futuapi.OptionQuote{
+		OptionKey: futuapi.OptionKey{Expiry: "2024-06-21", Strike: 190, Type: qotcommon.OptionType_OptionType_Call},
+		Info:      &futuapi.SecurityStaticInfo{Basic: &futuapi.SecurityStaticBasic{Security: sec, LotSize: 100}},
+	}
+	now := time.Date(2024, 3, 21, 10, 0, 0, 0, sec.Location())
+	if _, err := Evaluate(q, 185, now, nil); err != ErrNoQuote {
+		t.Errorf("no snapshot: %v", err)
+	}
+	q.Snapshot = &futuapi.Snapshot{Basic: &futuapi.SnapshotBasicData{Security: sec, BidPrice: 7.9, AskPrice: 8.1, CurPrice: 8.3}}
+	r, err := Evaluate(q, 185, now, &Config{Rate: 0.05})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Price != 8 || !r.American || r.ContractSize != 100 || !near(r.Theo, 8, 1e-6) || r.IV <= 0 {
+		t.Errorf("result %+v", r)
+	}
+	if d := r.ContractGreeks().Delta; !near(d, r.Greeks.Delta*100, 1e-9) {
+		t.Errorf("contract delta = %v", d)
+	}
+	if _, err := Evaluate(q, 185, now.AddDate(1, 0, 0), nil); err != ErrExpired {
+		t.Errorf("expired: %v", err)
+	}
+
+	// 结果写回期权表
+	table := &futuapi.OptionTable{Quotes: map[futuapi.OptionKey]*futuapi.OptionQuote{q.OptionKey: q}}
+	results := EvaluateTable(table, 185, now, &Config{Rate: 0.05})
+	if m := table.Get(q.Expiry, q.Strike, q.Type).Model; m == nil || results[q.OptionKey] == nil || m.IV != results[q.OptionKey].IV || m.Delta != r.Greeks.Delta {
+		t.Errorf("model %+v", m)
+	}
+	EvaluateTable(table, 185, now.AddDate(1, 0, 0), nil)
+	if q.Model != nil {
+		t.Errorf("stale model %+v", q.Model)
+	}
+}
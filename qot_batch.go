@@ -0,0 +1,248 @@
+package futuapi
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// 各接口每次请求最多的证券数量
+const (
+	SnapshotBatchSize   = 400
+	QuoteBatchSize      = 400
+	OwnerPlateBatchSize = 200
+	FutureInfoBatchSize = 200
+)
+
+// 分批请求参数
+type BatchOptions struct {
+	Size        int //每批最多的证券数量，0 为接口的上限
+	Concurrency int //同时请求的批次数，0 为1
+}
+
+// 一个批次的错误
+type BatchError struct {
+	Index      int         //批次序号
+	Securities []*Security //该批次的证券
+	Err        error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("batch %d (%d securities): %v", e.Index, len(e.Securities), e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// 部分批次失败时返回的错误，按批次序号排列，成功批次的结果仍然返回
+type BatchErrors []*BatchError
+
+func (e BatchErrors) Error() string {
+	list := make([]string, len(e))
+	for i, err := range e {
+		list[i] = err.Error()
+	}
+	return strings.Join(list, "; ")
+}
+
+// 把证券列表按 size 切分，并发请求，每次请求前遵守协议的频率限制。
+// fn 处理一个批次并保存结果，返回各批次的错误。
+func (api *FutuAPI) batch(ctx context.Context, proto uint32, securities []*Security, size int, opts *BatchOptions,
+	fn func(ctx context.Context, chunk []*Security) error) error {
+	conc := 1
+	if opts != nil {
+		if opts.Size > 0 && opts.Size < size {
+			size = opts.Size
+		}
+		if opts.Concurrency > 0 {
+			conc = opts.Concurrency
+		}
+	}
+	var chunks [][]*Security
+	for i := 0; i < len(securities); i += size {
+		end := i + size
+		if end > len(securities) {
+			end = len(securities)
+		}
+		chunks = append(chunks, securities[i:end])
+	}
+	errs := make([]error, len(chunks))
+	sem := make(chan struct{}, conc)
+	var wg sync.WaitGroup
+	for i, chunk := range chunks {
+		select {
+		case <-ctx.Done():
+			errs[i] = ErrInterrupted
+			continue
+		case sem <- struct{}{}:
+		}
+		wg.Add(1)
+		go func(i int, chunk []*Security) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := api.WaitRateLimit(ctx, proto); err != nil {
+				errs[i] = err
+				return
+			}
+			errs[i] = fn(ctx, chunk)
+		}(i, chunk)
+	}
+	wg.Wait()
+	var list BatchErrors
+	for i, err := range errs {
+		if err != nil {
+			list = append(list, &BatchError{Index: i, Securities: chunks[i], Err: err})
+		}
+	}
+	if len(list) > 0 {
+		return list
+	}
+	return nil
+}
+
+// 记录证券在输入中的位置，用于按输入顺序排列结果，重复的证券按出现顺序对应
+type batchOrder struct {
+	pos map[Security][]int
+}
+
+func newBatchOrder(securities []*Security) *batchOrder {
+	o := &batchOrder{pos: make(map[Security][]int)}
+	for i, s := range securities {
+		if s != nil {
+			o.pos[*s] = append(o.pos[*s], i)
+		}
+	}
+	return o
+}
+
+// 结果在输入中的位置，不在输入中时返回 -1
+func (o *batchOrder) index(sec *Security) int {
+	if sec == nil {
+		return -1
+	}
+	list := o.pos[*sec]
+	if len(list) == 0 {
+		return -1
+	}
+	o.pos[*sec] = list[1:]
+	return list[0]
+}
+
+// 一个批次返回的一条结果
+type batchItem struct {
+	sec *Security
+	v   interface{}
+}
+
+// 分批请求并按输入位置保存结果，fetch 请求一个批次并返回结果及其证券。
+// 返回的列表与输入等长，请求失败、没有数据或证券不在输入中的位置为空。
+func (api *FutuAPI) batchItems(ctx context.Context, proto uint32, securities []*Security, size int, opts *BatchOptions,
+	fetch func(ctx context.Context, chunk []*Security) ([]batchItem, error)) ([]interface{}, error) {
+	out := make([]interface{}, len(securities))
+	var mu sync.Mutex
+	order := newBatchOrder(securities)
+	err := api.batch(ctx, proto, securities, size, opts, func(ctx context.Context, chunk []*Security) error {
+		items, err := fetch(ctx, chunk)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for _, item := range items {
+			if j := order.index(item.sec); j >= 0 {
+				out[j] = item.v
+			}
+		}
+		return nil
+	})
+	return out, err
+}
+
+// 分批获取快照，每批最多400只。结果按输入顺序排列，请求失败或没有数据的证券不在结果中，
+// 因此结果的位置与输入不一定对应，需要按结果中的证券匹配。部分批次失败时同时返回成功的结果和 BatchErrors。
+func (api *FutuAPI) GetMarketSnapshotBatch(ctx context.Context, securities []*Security, opts *BatchOptions) ([]*Snapshot, error) {
+	items, err := api.batchItems(ctx, ProtoIDQotGetSecuritySnapshot, securities, SnapshotBatchSize, opts, func(ctx context.Context, chunk []*Security) ([]batchItem, error) {
+		list, err := api.GetMarketSnapshot(ctx, chunk)
+		items := make([]batchItem, 0, len(list))
+		for _, s := range list {
+			if s != nil && s.Basic != nil {
+				items = append(items, batchItem{sec: s.Basic.Security, v: s})
+			}
+		}
+		return items, err
+	})
+	list := make([]*Snapshot, 0, len(items))
+	for _, v := range items {
+		if v != nil {
+			list = append(list, v.(*Snapshot))
+		}
+	}
+	return list, err
+}
+
+// 分批获取基本报价，每批最多400只，其他同 GetMarketSnapshotBatch
+func (api *FutuAPI) GetStockQuoteBatch(ctx context.Context, securities []*Security, opts *BatchOptions) ([]*BasicQot, error) {
+	items, err := api.batchItems(ctx, ProtoIDQotGetBasicQot, securities, QuoteBatchSize, opts, func(ctx context.Context, chunk []*Security) ([]batchItem, error) {
+		list, err := api.GetStockQuote(ctx, chunk)
+		items := make([]batchItem, 0, len(list))
+		for _, q := range list {
+			if q != nil {
+				items = append(items, batchItem{sec: q.Security, v: q})
+			}
+		}
+		return items, err
+	})
+	list := make([]*BasicQot, 0, len(items))
+	for _, v := range items {
+		if v != nil {
+			list = append(list, v.(*BasicQot))
+		}
+	}
+	return list, err
+}
+
+// 分批获取所属板块，每批最多200只，其他同 GetMarketSnapshotBatch
+func (api *FutuAPI) GetOwnerPlateBatch(ctx context.Context, securities []*Security, opts *BatchOptions) ([]*OwnerPlate, error) {
+	items, err := api.batchItems(ctx, ProtoIDQotGetOwnerPlate, securities, OwnerPlateBatchSize, opts, func(ctx context.Context, chunk []*Security) ([]batchItem, error) {
+		list, err := api.GetOwnerPlate(ctx, chunk)
+		items := make([]batchItem, 0, len(list))
+		for _, p := range list {
+			if p != nil {
+				items = append(items, batchItem{sec: p.Security, v: p})
+			}
+		}
+		return items, err
+	})
+	list := make([]*OwnerPlate, 0, len(items))
+	for _, v := range items {
+		if v != nil {
+			list = append(list, v.(*OwnerPlate))
+		}
+	}
+	return list, err
+}
+
+// 分批获取期货合约资料，每批最多200只，其他同 GetMarketSnapshotBatch
+func (api *FutuAPI) GetFutureInfoBatch(ctx context.Context, securities []*Security, opts *BatchOptions) ([]*FutureInfo, error) {
+	items, err := api.batchItems(ctx, ProtoIDQotGetFutureInfo, securities, FutureInfoBatchSize, opts, func(ctx context.Context, chunk []*Security) ([]batchItem, error) {
+		list, err := api.GetFutureInfo(ctx, chunk)
+		items := make([]batchItem, 0, len(list))
+		for _, f := range list {
+			if f != nil {
+				items = append(items, batchItem{sec: f.Security, v: f})
+			}
+		}
+		return items, err
+	})
+	list := make([]*FutureInfo, 0, len(items))
+	for _, v := range items {
+		if v != nil {
+			list = append(list, v.(*FutureInfo))
+		}
+	}
+	return list, err
+}
@@ -0,0 +1,55 @@
+package futuapi
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/hurisheng/go-futu-api/pb/qotcommon"
+)
+
+func TestBatchItems(t *testing.T) {
+	sec := func(code string) *Security {
+		return &Security{Market: qotcommon.QotMarket_QotMarket_HK_Security, Code: code}
+	}
+	// 重复的证券分别对应一条结果
+	securities := []*Security{sec("A"), sec("B"), sec("C"), sec("A"), sec("D"), sec("E"), sec("F")}
+	failed := errors.New("failed")
+	var mu sync.Mutex
+	var sizes []int
+	fetch := func(ctx context.Context, chunk []*Security) ([]batchItem, error) {
+		mu.Lock()
+		sizes = append(sizes, len(chunk))
+		mu.Unlock()
+		var items []batchItem
+		// 倒序返回，D 没有数据，F 所在批次失败，额外返回不在输入中的证券
+		for i := len(chunk) - 1; i >= 0; i-- {
+			switch chunk[i].Code {
+			case "D":
+				continue
+			case "F":
+				return nil, failed
+			}
+			items = append(items, batchItem{sec: chunk[i], v: chunk[i].Code})
+		}
+		return append(items, batchItem{sec: sec("X"), v: "X"}, batchItem{}), nil
+	}
+	api := NewFutuAPI()
+	items, err := api.batchItems(context.Background(), 0, securities, 400, &BatchOptions{Size: 2, Concurrency: 2}, fetch)
+
+	if want := []interface{}{"A", "B", "C", "A", nil, "E", nil}; !reflect.DeepEqual(items, want) {
+		t.Errorf("items %v, want %v", items, want)
+	}
+	if len(sizes) != 4 {
+		t.Errorf("batches %v", sizes)
+	}
+	var errs BatchErrors
+	if !errors.As(err, &errs) || len(errs) != 1 {
+		t.Fatalf("err %v", err)
+	}
+	if e := errs[0]; e.Index != 3 || len(e.Securities) != 1 || e.Securities[0].Code != "F" || !errors.Is(e, failed) {
+		t.Errorf("batch error %+v", e)
+	}
+}
@@ -0,0 +1,114 @@
+package futuapi
+
+import (
+	"context"
+
+	"github.com/hurisheng/go-futu-api/pb/qotcommon"
+)
+
+// 历史 K 线请求参数
+type HistoryKLineRequest struct {
+	Security  *Security           //*证券
+	Begin     string              //*开始时间字符串
+	End       string              //*结束时间字符串
+	KLType    qotcommon.KLType    //*Qot_Common.KLType,K 线类型
+	RehabType qotcommon.RehabType //*Qot_Common.RehabType,复权类型
+	MaxNum    int32               //单页最多返回的 K 线根数，0 为服务器默认
+	Fields    qotcommon.KLFields  //需要返回的 K 线字段，0 为全部字段
+	ExtTime   bool                //是否获取美股盘前盘后数据
+	NextKey   []byte              //从该请求 key 开始获取，如之前保存的 HistoryKLineIterator.NextKey，为空时从第一页开始
+}
+
+// 历史 K 线迭代器，按需逐页请求，自动跟随 NextKey 直到数据取完
+type HistoryKLineIterator struct {
+	req     HistoryKLineRequest
+	request func(ctx context.Context, key []byte) (*HistoryKLine, error)
+	page    []*KLine
+	pos     int
+	key     []byte
+	last    bool
+	cur     *KLine
+	err     error
+}
+
+// 创建历史 K 线迭代器，每页请求前遵守 RequestHistoryKLine 的频率限制
+func (api *FutuAPI) HistoryKLineIterator(req *HistoryKLineRequest) *HistoryKLineIterator {
+	it := &HistoryKLineIterator{
+		req: *req,
+		key: req.NextKey,
+	}
+	it.request = func(ctx context.Context, key []byte) (*HistoryKLine, error) {
+		if err := api.WaitRateLimit(ctx, ProtoIDQotRequestHistoryKL); err != nil {
+			return nil, err
+		}
+		r := &it.req
+		return api.RequestHistoryKLine(ctx, r.Security, r.Begin, r.End, r.KLType, r.RehabType, r.MaxNum, r.Fields, key, r.ExtTime)
+	}
+	return it
+}
+
+// 移动到下一根 K 线，没有更多数据或出错时返回 false，出错原因由 Err 返回
+func (it *HistoryKLineIterator) Next(ctx context.Context) bool {
+	if it.err != nil {
+		return false
+	}
+	for it.pos >= len(it.page) {
+		if it.last {
+			it.cur = nil
+			return false
+		}
+		if err := it.fetch(ctx); err != nil {
+			it.err = err
+			it.cur = nil
+			return false
+		}
+	}
+	it.cur = it.page[it.pos]
+	it.pos++
+	return true
+}
+
+func (it *HistoryKLineIterator) fetch(ctx context.Context) error {
+	h, err := it.request(ctx, it.key)
+	if err != nil {
+		return err
+	}
+	if h == nil {
+		it.page, it.pos, it.last = nil, 0, true
+		return nil
+	}
+	it.page, it.pos = h.KLines, 0
+	it.key = h.NextKey
+	it.last = len(h.NextKey) == 0
+	return nil
+}
+
+// 当前 K 线
+func (it *HistoryKLineIterator) KLine() *KLine {
+	return it.cur
+}
+
+// 迭代过程中的错误
+func (it *HistoryKLineIterator) Err() error {
+	return it.err
+}
+
+// 下一页的请求 key，数据已取完时为空。key 指向当前页之后的一页，当前页还有 K 线没有读取时保存该 key，
+// 从它恢复会跳过这些 K 线，因此只能在页边界记录进度：Next 返回后 NextKey 发生变化时，当前 K 线是新一页的第一根，
+// 变化前的 key 是当前页的请求 key，此前的 K 线已全部读取，保存变化前的 key 可以从当前页恢复
+func (it *HistoryKLineIterator) NextKey() []byte {
+	return it.key
+}
+
+// 获取全部历史 K 线，自动分页并汇总到一个列表
+func (api *FutuAPI) RequestAllHistoryKLine(ctx context.Context, req *HistoryKLineRequest) ([]*KLine, error) {
+	var list []*KLine
+	it := api.HistoryKLineIterator(req)
+	for it.Next(ctx) {
+		list = append(list, it.KLine())
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
@@ -0,0 +1,68 @@
+package futuapi
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// 按请求 key 返回的分页数据，第二页为空页，"fail" 返回错误
+func stubHistoryKLine(it *HistoryKLineIterator, keys *[]string) {
+	pages := map[string]*HistoryKLine{
+		"":   {KLines: []*KLine{{Time: "1"}, {Time: "2"}}, NextKey: []byte("p2")},
+		"p2": {NextKey: []byte("p3")},
+		"p3": {KLines: []*KLine{{Time: "3"}}, NextKey: []byte("fail")},
+		"p4": {KLines: []*KLine{{Time: "4"}}},
+	}
+	it.request = func(ctx context.Context, key []byte) (*HistoryKLine, error) {
+		*keys = append(*keys, string(key))
+		if string(key) == "fail" {
+			return nil, errors.New("fail")
+		}
+		return pages[string(key)], nil
+	}
+}
+
+func TestHistoryKLineIterator(t *testing.T) {
+	api := NewFutuAPI()
+	collect := func(it *HistoryKLineIterator, n int) string {
+		var s string
+		for i := 0; (n < 0 || i < n) && it.Next(context.Background()); i++ {
+			s += it.KLine().Time
+		}
+		return s
+	}
+
+	// 跳过空页，出错时停止并保留已经返回的 K 线
+	var keys []string
+	it := api.HistoryKLineIterator(&HistoryKLineRequest{})
+	stubHistoryKLine(it, &keys)
+	if got := collect(it, -1); got != "123" || it.Err() == nil || it.KLine() != nil {
+		t.Errorf("got %q, err %v", got, it.Err())
+	}
+	if len(keys) != 4 || it.Next(context.Background()) || len(keys) != 4 {
+		t.Errorf("requests %q", keys)
+	}
+
+	// 提前停止时不请求下一页，可以用 NextKey 继续
+	keys = nil
+	it = api.HistoryKLineIterator(&HistoryKLineRequest{})
+	stubHistoryKLine(it, &keys)
+	if got := collect(it, 2); got != "12" || len(keys) != 1 || string(it.NextKey()) != "p2" {
+		t.Errorf("got %q, requests %q, next key %q", got, keys, it.NextKey())
+	}
+	keys = nil
+	it = api.HistoryKLineIterator(&HistoryKLineRequest{NextKey: it.NextKey()})
+	stubHistoryKLine(it, &keys)
+	if got := collect(it, 1); got != "3" || keys[0] != "p2" {
+		t.Errorf("resumed got %q, requests %q", got, keys)
+	}
+
+	// 最后一页没有 NextKey 时结束
+	keys = nil
+	it = api.HistoryKLineIterator(&HistoryKLineRequest{NextKey: []byte("p4")})
+	stubHistoryKLine(it, &keys)
+	if got := collect(it, -1); got != "4" || it.Err() != nil || len(keys) != 1 || it.NextKey() != nil {
+		t.Errorf("got %q, err %v, requests %q", got, it.Err(), keys)
+	}
+}
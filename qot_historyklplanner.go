@@ -0,0 +1,255 @@
+package futuapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hurisheng/go-futu-api/pb/qotcommon"
+)
+
+// 历史 K 线下载任务
+type HistoryKLineTask struct {
+	Security  *Security           //*证券
+	KLType    qotcommon.KLType    //*Qot_Common.KLType,K 线类型
+	RehabType qotcommon.RehabType //*Qot_Common.RehabType,复权类型
+	Begin     string              //*开始时间字符串
+	End       string              //*结束时间字符串
+}
+
+// 任务没有指定证券
+var ErrHistoryKLineTaskSecurity = errors.New("history kline task without security")
+
+// 任务唯一标识，用于记录进度
+func (t *HistoryKLineTask) Key() string {
+	var sec Security
+	if t.Security != nil {
+		sec = *t.Security
+	}
+	return fmt.Sprintf("%d.%s/%d/%d/%s/%s", sec.Market, sec.Code, t.KLType, t.RehabType, t.Begin, t.End)
+}
+
+// 历史 K 线下载进度，字段均可导出，可直接序列化保存以便中断后继续
+type HistoryKLineProgress struct {
+	Done    map[string]bool   //已完成的任务
+	NextKey map[string][]byte //未完成任务的下一页请求 key
+}
+
+// 创建空的下载进度
+func NewHistoryKLineProgress() *HistoryKLineProgress {
+	return &HistoryKLineProgress{
+		Done:    make(map[string]bool),
+		NextKey: make(map[string][]byte),
+	}
+}
+
+// 下载计划
+type HistoryKLinePlan struct {
+	Quota    *HistoryKLQuota     //计划时的额度
+	Runnable []*HistoryKLineTask //可以下载的任务
+	Skipped  []*HistoryKLineTask //额度不足而跳过的任务
+	Done     []*HistoryKLineTask //进度中已完成的任务
+	NewQuota int32               //执行计划需要消耗的额度
+
+	fresh map[Security]bool //需要消耗额度的证券
+}
+
+// 下载结果
+type HistoryKLineReport struct {
+	Completed []*HistoryKLineTask //完成的任务
+	Skipped   []*HistoryKLineTask //额度不足而跳过的任务，包括下载过程中额度用完的任务
+	Failed    []*HistoryKLineTaskError
+}
+
+// 下载失败的任务
+type HistoryKLineTaskError struct {
+	Task *HistoryKLineTask
+	Err  error
+}
+
+func (e *HistoryKLineTaskError) Error() string {
+	return e.Task.Key() + ": " + e.Err.Error()
+}
+
+func (e *HistoryKLineTaskError) Unwrap() error {
+	return e.Err
+}
+
+// 历史 K 线下载计划器。
+// 下载前先查询额度，额度按证券计算，近 30 天内下载过的证券不重复消耗额度。
+type HistoryKLinePlanner struct {
+	quota    func(ctx context.Context) (*HistoryKLQuota, error)
+	iterator func(req *HistoryKLineRequest) *HistoryKLineIterator
+
+	Progress *HistoryKLineProgress //下载进度，为空时自动创建
+	MaxNum   int32                 //单页最多返回的 K 线根数，0 为服务器默认
+	Fields   qotcommon.KLFields    //需要返回的 K 线字段，0 为全部字段
+	ExtTime  bool                  //是否获取美股盘前盘后数据
+	// 每页数据的回调，空页不回调，返回错误时该任务失败，进度停留在该页之前
+	OnKLines func(task *HistoryKLineTask, klines []*KLine) error
+}
+
+// 创建历史 K 线下载计划器
+func (api *FutuAPI) NewHistoryKLinePlanner(progress *HistoryKLineProgress) *HistoryKLinePlanner {
+	if progress == nil {
+		progress = NewHistoryKLineProgress()
+	}
+	return &HistoryKLinePlanner{
+		quota: func(ctx context.Context) (*HistoryKLQuota, error) {
+			return api.GetHistoryKLQuota(ctx, true)
+		},
+		iterator: api.HistoryKLineIterator,
+		Progress: progress,
+	}
+}
+
+// 根据当前额度和已下载的证券生成计划，不消耗额度。任务没有指定证券时返回 ErrHistoryKLineTaskSecurity
+func (p *HistoryKLinePlanner) Plan(ctx context.Context, tasks []*HistoryKLineTask) (*HistoryKLinePlan, error) {
+	for _, t := range tasks {
+		if t == nil || t.Security == nil {
+			return nil, ErrHistoryKLineTaskSecurity
+		}
+	}
+	quota, used, err := p.usedQuota(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.init()
+	plan := HistoryKLinePlan{Quota: quota, fresh: make(map[Security]bool)}
+	remain := quota.RemainQuota
+	for _, t := range tasks {
+		if p.Progress.Done[t.Key()] {
+			plan.Done = append(plan.Done, t)
+			continue
+		}
+		if !used[*t.Security] {
+			if remain <= 0 {
+				plan.Skipped = append(plan.Skipped, t)
+				continue
+			}
+			remain--
+			plan.NewQuota++
+			used[*t.Security] = true
+			plan.fresh[*t.Security] = true
+		}
+		plan.Runnable = append(plan.Runnable, t)
+	}
+	return &plan, nil
+}
+
+// 按计划下载，额度不足的任务跳过而不是中途失败，单个任务失败不影响其他任务。
+// 需要消耗额度的任务失败时重新查询额度，额度已经用完时该任务和之后需要额度的任务都记为跳过。
+// ctx 结束时返回 ErrInterrupted，已完成的部分记录在 Progress 中，可再次调用继续。
+func (p *HistoryKLinePlanner) Run(ctx context.Context, tasks []*HistoryKLineTask) (*HistoryKLineReport, error) {
+	plan, err := p.Plan(ctx, tasks)
+	if err != nil {
+		return nil, err
+	}
+	report := HistoryKLineReport{Skipped: plan.Skipped}
+	var used map[Security]bool //额度用完后已下载过的证券
+	for _, t := range plan.Runnable {
+		if used != nil && !used[*t.Security] {
+			report.Skipped = append(report.Skipped, t)
+			continue
+		}
+		err := p.download(ctx, t)
+		if err == nil {
+			report.Completed = append(report.Completed, t)
+			continue
+		}
+		if err == ErrInterrupted {
+			return &report, err
+		}
+		if plan.fresh[*t.Security] {
+			quota, u, qerr := p.usedQuota(ctx)
+			if qerr == ErrInterrupted {
+				return &report, qerr
+			}
+			if qerr == nil && quota.RemainQuota <= 0 && !u[*t.Security] {
+				used = u
+				report.Skipped = append(report.Skipped, t)
+				continue
+			}
+		}
+		report.Failed = append(report.Failed, &HistoryKLineTaskError{Task: t, Err: err})
+	}
+	return &report, nil
+}
+
+// 查询额度和近期已下载过的证券，近期已下载过的证券不消耗额度
+func (p *HistoryKLinePlanner) usedQuota(ctx context.Context) (*HistoryKLQuota, map[Security]bool, error) {
+	quota, err := p.quota(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if quota == nil {
+		quota = &HistoryKLQuota{}
+	}
+	used := make(map[Security]bool)
+	for _, v := range quota.DetailList {
+		if v != nil && v.Security != nil {
+			used[*v.Security] = true
+		}
+	}
+	return quota, used, nil
+}
+
+func (p *HistoryKLinePlanner) init() {
+	if p.Progress == nil {
+		p.Progress = NewHistoryKLineProgress()
+	}
+	if p.Progress.Done == nil {
+		p.Progress.Done = make(map[string]bool)
+	}
+	if p.Progress.NextKey == nil {
+		p.Progress.NextKey = make(map[string][]byte)
+	}
+}
+
+// 下载一个任务，每页数据交给 OnKLines 后把下一页的请求 key 记录到进度中
+func (p *HistoryKLinePlanner) download(ctx context.Context, t *HistoryKLineTask) error {
+	k := t.Key()
+	it := p.iterator(&HistoryKLineRequest{
+		Security:  t.Security,
+		Begin:     t.Begin,
+		End:       t.End,
+		KLType:    t.KLType,
+		RehabType: t.RehabType,
+		MaxNum:    p.MaxNum,
+		Fields:    p.Fields,
+		ExtTime:   p.ExtTime,
+		NextKey:   p.Progress.NextKey[k], //从上次中断的页继续
+	})
+	var page []*KLine
+	var key []byte //当前页返回的下一页请求 key
+	flush := func() error {
+		if p.OnKLines != nil && len(page) > 0 {
+			if err := p.OnKLines(t, page); err != nil {
+				return err
+			}
+		}
+		page = nil
+		return nil
+	}
+	for it.Next(ctx) {
+		// NextKey 变化说明迭代器请求了新的一页，之前的一页已经完整
+		if next := it.NextKey(); page != nil && !bytes.Equal(next, key) {
+			if err := flush(); err != nil {
+				return err
+			}
+			p.Progress.NextKey[k] = key
+		}
+		key = it.NextKey()
+		page = append(page, it.KLine())
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+	delete(p.Progress.NextKey, k)
+	p.Progress.Done[k] = true
+	return nil
+}
@@ -0,0 +1,222 @@
+package futuapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hurisheng/go-futu-api/pb/qotcommon"
+)
+
+func testPlanTask(code string, kl qotcommon.KLType) *HistoryKLineTask {
+	return &HistoryKLineTask{
+		Security: &Security{Market: qotcommon.QotMarket_QotMarket_HK_Security, Code: code},
+		KLType:   kl,
+		Begin:    "2024-01-01",
+		End:      "2024-03-31",
+	}
+}
+
+// 额度查询和分页数据都由测试提供的计划器。每只证券有两页数据，代码以 X 开头的证券请求失败；
+// 请求失败后 remain 减为0，模拟下载过程中额度用完
+func testPlanner(remain int32, used []string, requests *[]string) *HistoryKLinePlanner {
+	api := NewFutuAPI()
+	p := api.NewHistoryKLinePlanner(nil)
+	p.quota = func(ctx context.Context) (*HistoryKLQuota, error) {
+		q := &HistoryKLQuota{RemainQuota: remain}
+		for _, code := range used {
+			q.DetailList = append(q.DetailList, &HistoryKLQuotaItem{Security: &Security{Market: qotcommon.QotMarket_QotMarket_HK_Security, Code: code}})
+		}
+		return q, nil
+	}
+	p.iterator = func(req *HistoryKLineRequest) *HistoryKLineIterator {
+		it := api.HistoryKLineIterator(req)
+		code := req.Security.Code
+		it.request = func(ctx context.Context, key []byte) (*HistoryKLine, error) {
+			*requests = append(*requests, code+":"+string(key))
+			if strings.HasPrefix(code, "X") {
+				remain = 0
+				return nil, errors.New("quota exceeded")
+			}
+			if len(key) == 0 {
+				return &HistoryKLine{KLines: []*KLine{{Time: "1"}, {Time: "2"}}, NextKey: []byte("2")}, nil
+			}
+			return &HistoryKLine{KLines: []*KLine{{Time: "3"}}}, nil
+		}
+		return it
+	}
+	return p
+}
+
+func taskCodes(tasks []*HistoryKLineTask) string {
+	var list []string
+	for _, t := range tasks {
+		list = append(list, t.Security.Code)
+	}
+	return strings.Join(list, ",")
+}
+
+func TestHistoryKLinePlan(t *testing.T) {
+	day, min := qotcommon.KLType_KLType_Day, qotcommon.KLType_KLType_1Min
+	cases := []struct {
+		name     string
+		remain   int32
+		used     []string
+		done     []*HistoryKLineTask
+		tasks    []*HistoryKLineTask
+		runnable string
+		skipped  string
+		finished string
+		newQuota int32
+	}{
+		{
+			name:     "quota per security",
+			remain:   1,
+			tasks:    []*HistoryKLineTask{testPlanTask("A", day), testPlanTask("A", min), testPlanTask("B", day)},
+			runnable: "A,A",
+			skipped:  "B",
+			newQuota: 1,
+		},
+		{
+			name:     "recently downloaded",
+			remain:   0,
+			used:     []string{"B"},
+			tasks:    []*HistoryKLineTask{testPlanTask("A", day), testPlanTask("B", day), testPlanTask("B", min)},
+			runnable: "B,B",
+			skipped:  "A",
+		},
+		{
+			name:     "done in progress",
+			remain:   5,
+			done:     []*HistoryKLineTask{testPlanTask("A", day)},
+			tasks:    []*HistoryKLineTask{testPlanTask("A", day), testPlanTask("A", min), testPlanTask("C", day)},
+			runnable: "A,C",
+			finished: "A",
+			newQuota: 2,
+		},
+	}
+	for _, c := range cases {
+		var requests []string
+		p := testPlanner(c.remain, c.used, &requests)
+		for _, t := range c.done {
+			p.Progress.Done[t.Key()] = true
+		}
+		plan, err := p.Plan(context.Background(), c.tasks)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if taskCodes(plan.Runnable) != c.runnable || taskCodes(plan.Skipped) != c.skipped ||
+			taskCodes(plan.Done) != c.finished || plan.NewQuota != c.newQuota {
+			t.Errorf("%s: runnable %s, skipped %s, done %s, new quota %d", c.name,
+				taskCodes(plan.Runnable), taskCodes(plan.Skipped), taskCodes(plan.Done), plan.NewQuota)
+		}
+		if len(requests) != 0 {
+			t.Errorf("%s: plan requested %v", c.name, requests)
+		}
+	}
+
+	var requests []string
+	p := testPlanner(1, nil, &requests)
+	if _, err := p.Plan(context.Background(), []*HistoryKLineTask{testPlanTask("A", day), {KLType: day}}); err != ErrHistoryKLineTaskSecurity {
+		t.Errorf("task without security: %v", err)
+	}
+}
+
+func TestHistoryKLineRun(t *testing.T) {
+	day := qotcommon.KLType_KLType_Day
+	failed := errors.New("disk full")
+	cases := []struct {
+		name      string
+		remain    int32
+		used      []string
+		tasks     []*HistoryKLineTask
+		nextKey   string //A 任务保存的下一页请求 key
+		onErr     bool   //A 的第二页回调出错
+		completed string
+		skipped   string
+		failed    string
+		pages     string
+		requests  string
+		progress  string //A 任务之后保存的下一页请求 key
+	}{
+		{
+			name:      "all pages",
+			remain:    2,
+			tasks:     []*HistoryKLineTask{testPlanTask("A", day), testPlanTask("B", day)},
+			completed: "A,B",
+			pages:     "[A:12 A:3 B:12 B:3]",
+			requests:  "[A: A:2 B: B:2]",
+		},
+		{
+			name:      "resume",
+			remain:    1,
+			tasks:     []*HistoryKLineTask{testPlanTask("A", day)},
+			nextKey:   "2",
+			completed: "A",
+			pages:     "[A:3]",
+			requests:  "[A:2]",
+		},
+		{
+			name:     "callback error",
+			remain:   1,
+			tasks:    []*HistoryKLineTask{testPlanTask("A", day)},
+			onErr:    true,
+			failed:   "A",
+			pages:    "[A:12 A:3]",
+			requests: "[A: A:2]",
+			progress: "2",
+		},
+		{
+			name:      "quota runs out",
+			remain:    3,
+			used:      []string{"A"},
+			tasks:     []*HistoryKLineTask{testPlanTask("A", day), testPlanTask("X", day), testPlanTask("C", day), testPlanTask("A", qotcommon.KLType_KLType_Week)},
+			completed: "A,A",
+			skipped:   "X,C",
+			pages:     "[A:12 A:3 A:12 A:3]",
+			requests:  "[A: A:2 X: A: A:2]",
+		},
+	}
+	for _, c := range cases {
+		var requests []string
+		p := testPlanner(c.remain, c.used, &requests)
+		a := c.tasks[0]
+		if c.nextKey != "" {
+			p.Progress.NextKey[a.Key()] = []byte(c.nextKey)
+		}
+		var pages []string
+		p.OnKLines = func(task *HistoryKLineTask, klines []*KLine) error {
+			s := task.Security.Code + ":"
+			for _, k := range klines {
+				s += k.Time
+			}
+			pages = append(pages, s)
+			if c.onErr && len(pages) == 2 {
+				return failed
+			}
+			return nil
+		}
+		r, err := p.Run(context.Background(), c.tasks)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		var failedTasks []*HistoryKLineTask
+		for _, e := range r.Failed {
+			failedTasks = append(failedTasks, e.Task)
+			if c.onErr && !errors.Is(e, failed) {
+				t.Errorf("%s: error %v", c.name, e)
+			}
+		}
+		if taskCodes(r.Completed) != c.completed || taskCodes(r.Skipped) != c.skipped || taskCodes(failedTasks) != c.failed {
+			t.Errorf("%s: completed %s, skipped %s, failed %s", c.name, taskCodes(r.Completed), taskCodes(r.Skipped), taskCodes(failedTasks))
+		}
+		if fmt.Sprint(pages) != c.pages || fmt.Sprint(requests) != c.requests {
+			t.Errorf("%s: pages %v, requests %v", c.name, pages, requests)
+		}
+		if got := string(p.Progress.NextKey[a.Key()]); got != c.progress || p.Progress.Done[a.Key()] != (c.completed != "") {
+			t.Errorf("%s: progress %q, done %v", c.name, got, p.Progress.Done[a.Key()])
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package futuapi
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/hurisheng/go-futu-api/pb/qotcommon"
+	"github.com/hurisheng/go-futu-api/pb/qotgetoptionchain"
+)
+
+// 期权链请求参数，含义同 GetOptionChain
+type OptionChainRequest struct {
+	Owner        *Security                        //*标的股
+	Begin        string                           //*开始日期
+	End          string                           //*结束日期
+	IndexOptType qotcommon.IndexOptionType        //指数期权类型
+	OptType      qotcommon.OptionType             //期权方向，0 为全部
+	Cond         qotgetoptionchain.OptionCondType //价内价外，0 为全部
+	Filter       *DataFilter                      //数据字段筛选
+}
+
+// 期权表的键：行权日、行权价、方向
+type OptionKey struct {
+	Expiry string               //行权日，YYYY-MM-DD
+	Strike float64              //行权价
+	Type   qotcommon.OptionType //看涨或看跌
+}
+
+// 期权的静态信息和快照
+type OptionQuote struct {
+	OptionKey
+	Info     *SecurityStaticInfo //期权链返回的静态信息
+	Snapshot *Snapshot           //快照，获取失败时为空
+	Model    *OptionModel        //本地定价结果，由 pricing.EvaluateTable 填写，没有计算或无法计算时为空
+}
+
+// 本地定价模型计算的期权价格、隐含波动率和每股希腊值，字段含义同 pricing 包
+type OptionModel struct {
+	Price float64 //定价使用的市场价格
+	Theo  float64 //理论价格
+	IV    float64 //隐含波动率，0.2 表示20%
+	Delta float64
+	Gamma float64
+	Vega  float64 //波动率变化1个百分点的价格变化
+	Theta float64 //每天的价格变化
+	Rho   float64 //利率变化1个百分点的价格变化
+}
+
+// 期权代码
+func (q *OptionQuote) Security() *Security {
+	return q.Info.Basic.Security
+}
+
+// 快照中的期权数据，包括隐含波动率和希腊值，没有快照时为空
+func (q *OptionQuote) Option() *OptionSnapshotExData {
+	if q.Snapshot == nil {
+		return nil
+	}
+	return q.Snapshot.OptionExData
+}
+
+// 期权表，按行权日、行权价、方向索引
+type OptionTable struct {
+	Owner      *Security
+	Expiries   []string //行权日，升序
+	Quotes     map[OptionKey]*OptionQuote
+	Duplicates []*OptionQuote //与 Quotes 中已有的期权键相同的其他期权，如除权调整后的期权，同样获取快照
+
+	bySecurity map[Security]*OptionQuote
+}
+
+// 查找期权，不存在时返回空
+func (t *OptionTable) Get(expiry string, strike float64, optType qotcommon.OptionType) *OptionQuote {
+	return t.Quotes[OptionKey{Expiry: expiry, Strike: strike, Type: optType}]
+}
+
+// 行权日的所有行权价，升序
+func (t *OptionTable) Strikes(expiry string) []float64 {
+	seen := make(map[float64]bool)
+	var list []float64
+	for k := range t.Quotes {
+		if k.Expiry == expiry && !seen[k.Strike] {
+			seen[k.Strike] = true
+			list = append(list, k.Strike)
+		}
+	}
+	sort.Float64s(list)
+	return list
+}
+
+// 行权日的所有期权，按行权价升序，同一行权价看涨在前
+func (t *OptionTable) Chain(expiry string) []*OptionQuote {
+	var list []*OptionQuote
+	for k, q := range t.Quotes {
+		if k.Expiry == expiry {
+			list = append(list, q)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Strike != list[j].Strike {
+			return list[i].Strike < list[j].Strike
+		}
+		return list[i].Type < list[j].Type
+	})
+	return list
+}
+
+// GetOptionChain 单次请求开始日期和结束日期的最大间隔天数
+const optionChainMaxDays = 30
+
+// 获取期权链并按批次获取每个期权的快照，合并为按行权日、行权价、方向索引的期权表。
+// 开始日期和结束日期的间隔超过30天时分段获取期权链。
+// 接口调用遵守频率限制；部分快照批次失败时返回已获取的结果和 BatchErrors，失败的期权 Snapshot 为空。
+func (api *FutuAPI) OptionChainWithQuotes(ctx context.Context, req *OptionChainRequest, opts *BatchOptions) (*OptionTable, error) {
+	ranges, err := splitDateRange(req.Begin, req.End, optionChainMaxDays)
+	if err != nil {
+		return nil, err
+	}
+	table := newOptionTable(req.Owner)
+	var securities []*Security
+	for _, r := range ranges {
+		if err := api.WaitRateLimit(ctx, ProtoIDQotGetOptionChain); err != nil {
+			return nil, err
+		}
+		chains, err := api.GetOptionChain(ctx, req.Owner, r[0], r[1], req.IndexOptType, req.OptType, req.Cond, req.Filter)
+		if err != nil {
+			return nil, err
+		}
+		securities = append(securities, table.merge(chains)...)
+	}
+
+	snapshots, err := api.GetMarketSnapshotBatch(ctx, securities, opts)
+	table.setSnapshots(snapshots)
+	return table, err
+}
+
+func newOptionTable(owner *Security) *OptionTable {
+	return &OptionTable{Owner: owner, Quotes: make(map[OptionKey]*OptionQuote), bySecurity: make(map[Security]*OptionQuote)}
+}
+
+// 把期权链合并到期权表，重复的期权代码只加入一次，返回新加入的期权代码
+func (t *OptionTable) merge(chains []*OptionChain) []*Security {
+	var securities []*Security
+	add := func(expiry string, info *SecurityStaticInfo) {
+		if info == nil || info.Basic == nil || info.Basic.Security == nil || info.OptionExData == nil {
+			return
+		}
+		if t.bySecurity[*info.Basic.Security] != nil {
+			return
+		}
+		q := &OptionQuote{
+			OptionKey: OptionKey{Expiry: expiry, Strike: info.OptionExData.StrikePrice, Type: info.OptionExData.Type},
+			Info:      info,
+		}
+		if t.Quotes[q.OptionKey] == nil {
+			t.Quotes[q.OptionKey] = q
+		} else {
+			t.Duplicates = append(t.Duplicates, q)
+		}
+		t.bySecurity[*info.Basic.Security] = q
+		securities = append(securities, info.Basic.Security)
+	}
+	for _, c := range chains {
+		if c == nil {
+			continue
+		}
+		expiry := c.StrikeTime
+		if len(expiry) > len("2006-01-02") {
+			expiry = expiry[:len("2006-01-02")]
+		}
+		i := sort.SearchStrings(t.Expiries, expiry)
+		if i == len(t.Expiries) || t.Expiries[i] != expiry {
+			t.Expiries = append(t.Expiries, "")
+			copy(t.Expiries[i+1:], t.Expiries[i:])
+			t.Expiries[i] = expiry
+		}
+		for _, item := range c.Option {
+			if item != nil {
+				add(expiry, item.Call)
+				add(expiry, item.Put)
+			}
+		}
+	}
+	return securities
+}
+
+// 把快照填入对应的期权
+func (t *OptionTable) setSnapshots(snapshots []*Snapshot) {
+	for _, s := range snapshots {
+		if s == nil || s.Basic == nil || s.Basic.Security == nil {
+			continue
+		}
+		if q := t.bySecurity[*s.Basic.Security]; q != nil {
+			q.Snapshot = s
+		}
+	}
+}
+
+// 把日期区间 [begin, end] 拆分为开始和结束间隔不超过 days 天的连续区间，日期格式为 YYYY-MM-DD
+func splitDateRange(begin string, end string, days int) ([][2]string, error) {
+	const layout = "2006-01-02"
+	b, err := time.Parse(layout, begin)
+	if err != nil {
+		return nil, err
+	}
+	e, err := time.Parse(layout, end)
+	if err != nil {
+		return nil, err
+	}
+	if !e.After(b) {
+		return [][2]string{{begin, end}}, nil
+	}
+	var ranges [][2]string
+	for !b.After(e) {
+		to := b.AddDate(0, 0, days)
+		if to.After(e) {
+			to = e
+		}
+		ranges = append(ranges, [2]string{b.Format(layout), to.Format(layout)})
+		b = to.AddDate(0, 0, 1)
+	}
+	return ranges, nil
+}
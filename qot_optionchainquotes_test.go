@@ -0,0 +1,70 @@
+package futuapi
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hurisheng/go-futu-api/pb/qotcommon"
+)
+
+func TestSplitDateRange(t *testing.T) {
+	got, err := splitDateRange("2024-01-01", "2024-03-15", 30)
+	want := [][2]string{{"2024-01-01", "2024-01-31"}, {"2024-02-01", "2024-03-02"}, {"2024-03-03", "2024-03-15"}}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("split = %v, %v", got, err)
+	}
+	if got, err := splitDateRange("2024-01-01", "2024-01-31", 30); err != nil || len(got) != 1 {
+		t.Errorf("short range = %v, %v", got, err)
+	}
+	if _, err := splitDateRange("2024/01/01", "2024-01-31", 30); err == nil {
+		t.Error("expected format error")
+	}
+}
+
+func TestOptionTableMerge(t *testing.T) {
+	us := qotcommon.QotMarket_QotMarket_US_Security
+	owner := &Security{Market: us, Code: "XYZ"}
+	info := func(code string, strike float64, typ qotcommon.OptionType) *SecurityStaticInfo {
+		return &SecurityStaticInfo{
+			Basic:        &SecurityStaticBasic{Security: &Security{Market: us, Code: code}},
+			OptionExData: &OptionStaticExData{Type: typ, Owner: owner, StrikePrice: strike},
+		}
+	}
+	call, put := qotcommon.OptionType_OptionType_Call, qotcommon.OptionType_OptionType_Put
+	table := newOptionTable(owner)
+	first := table.merge([]*OptionChain{
+		{StrikeTime: "2024-02-16 00:00:00", Option: []*OptionItem{
+			{Call: info("XYZ240216C100000", 100, call), Put: info("XYZ240216P100000", 100, put)},
+		}},
+	})
+	// 分段获取时可能重复返回同一期权；除权调整后的期权与原期权键相同
+	second := table.merge([]*OptionChain{
+		{StrikeTime: "2024-01-19", Option: []*OptionItem{{Call: info("XYZ240119C95000", 95, call)}, nil}},
+		{StrikeTime: "2024-02-16", Option: []*OptionItem{
+			{Call: info("XYZ240216C100000", 100, call)},
+			{Call: info("XYZ1240216C100000", 100, call)},
+		}},
+		nil,
+	})
+	if len(first) != 2 || len(second) != 2 {
+		t.Fatalf("added %v, %v", first, second)
+	}
+	if !reflect.DeepEqual(table.Expiries, []string{"2024-01-19", "2024-02-16"}) {
+		t.Errorf("expiries %v", table.Expiries)
+	}
+	q := table.Get("2024-02-16", 100, call)
+	if len(table.Quotes) != 3 || q == nil || q.Security().Code != "XYZ240216C100000" {
+		t.Fatalf("quotes %v", table.Quotes)
+	}
+	if len(table.Duplicates) != 1 || table.Duplicates[0].Security().Code != "XYZ1240216C100000" {
+		t.Errorf("duplicates %v", table.Duplicates)
+	}
+
+	snap := func(sec *Security) *Snapshot {
+		return &Snapshot{Basic: &SnapshotBasicData{Security: sec}}
+	}
+	table.setSnapshots([]*Snapshot{snap(q.Security()), snap(table.Duplicates[0].Security()), snap(owner), nil})
+	if q.Snapshot == nil || table.Duplicates[0].Snapshot == nil || table.Get("2024-01-19", 95, call).Snapshot != nil {
+		t.Errorf("snapshots not merged")
+	}
+}
@@ -0,0 +1,51 @@
+package futuapi
+
+import "context"
+
+// 按开始位置分页的迭代状态，条件选股和窝轮筛选的迭代器共用
+type offsetPager struct {
+	size  int32 //每页数量
+	n     int   //当前页的结果数量
+	pos   int   //下一个结果在当前页中的位置
+	begin int32 //当前页的开始位置
+	total int32
+	last  bool
+	err   error
+	// 从 begin 开始请求一页并保存结果，返回结果数量和是否最后一页
+	load func(ctx context.Context, begin int32, size int32) (int, bool, error)
+}
+
+func newOffsetPager(begin int32, size int32, max int32, load func(ctx context.Context, begin int32, size int32) (int, bool, error)) offsetPager {
+	if size <= 0 || size > max {
+		size = max
+	}
+	return offsetPager{size: size, begin: begin, total: -1, load: load}
+}
+
+// 移动到下一个结果，返回结果在当前页中的位置。没有更多数据或出错时返回 false。
+// 服务器标记最后一页，或返回的数量少于每页数量时结束，不再请求下一页。
+func (p *offsetPager) next(ctx context.Context) (int, bool) {
+	if p.err != nil {
+		return 0, false
+	}
+	for p.pos >= p.n {
+		if p.last {
+			return 0, false
+		}
+		begin := p.begin + int32(p.n)
+		n, last, err := p.load(ctx, begin, p.size)
+		if err != nil {
+			p.err = err
+			return 0, false
+		}
+		p.begin, p.n, p.pos = begin, n, 0
+		p.last = last || n < int(p.size)
+	}
+	p.pos++
+	return p.pos - 1, true
+}
+
+// 下一个未读取结果的位置
+func (p *offsetPager) cursor() int32 {
+	return p.begin + int32(p.pos)
+}
@@ -0,0 +1,99 @@
+package futuapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestStockFilterIterator(t *testing.T) {
+	// 共7条结果，服务器不标记最后一页，按返回数量少于每页数量结束
+	const total = 7
+	var begins []int32
+	request := func(ctx context.Context, begin int32, num int32) (*StockFilterResult, error) {
+		begins = append(begins, begin)
+		r := &StockFilterResult{AllCount: total}
+		for i := begin; i < begin+num && i < total; i++ {
+			r.DataList = append(r.DataList, &StockData{Name: fmt.Sprint(i)})
+		}
+		return r, nil
+	}
+	api := NewFutuAPI()
+	it := api.StockFilterIterator(&StockFilterRequest{PageSize: 3})
+	it.request = request
+	if it.Total() != -1 {
+		t.Errorf("total before request %d", it.Total())
+	}
+	var got string
+	for it.Next(context.Background()) {
+		got += it.StockData().Name
+		if got == "0123" && it.Cursor() != 4 {
+			t.Errorf("cursor %d", it.Cursor())
+		}
+	}
+	if got != "0123456" || it.Err() != nil || it.Total() != total || it.Cursor() != total {
+		t.Errorf("got %q, err %v, total %d, cursor %d", got, it.Err(), it.Total(), it.Cursor())
+	}
+	if fmt.Sprint(begins) != "[0 3 6]" {
+		t.Errorf("requests %v", begins)
+	}
+
+	// 整页结束时再请求一页，空页结束；从保存的位置继续
+	begins = nil
+	it = api.StockFilterIterator(&StockFilterRequest{Begin: 1, PageSize: 3})
+	it.request = request
+	got = ""
+	for it.Next(context.Background()) {
+		got += it.StockData().Name
+	}
+	if got != "123456" || fmt.Sprint(begins) != "[1 4 7]" {
+		t.Errorf("got %q, requests %v", got, begins)
+	}
+}
+
+func TestWarrantIterator(t *testing.T) {
+	failed := errors.New("failed")
+	var begins []int32
+	api := NewFutuAPI()
+	it := api.WarrantIterator(&WarrantRequest{PageSize: 500})
+	it.request = func(ctx context.Context, begin int32, num int32) (*Warrant, error) {
+		begins = append(begins, begin)
+		if num != WarrantPageSize {
+			t.Errorf("page size %d", num)
+		}
+		if begin > 0 {
+			return nil, failed
+		}
+		w := &Warrant{AllCount: 500}
+		for i := 0; i < WarrantPageSize; i++ {
+			w.WarrantList = append(w.WarrantList, &WarrantData{})
+		}
+		return w, nil
+	}
+	n := 0
+	for it.Next(context.Background()) {
+		n++
+	}
+	if n != WarrantPageSize || it.Err() != failed || it.Warrant() != nil || it.Cursor() != WarrantPageSize {
+		t.Errorf("read %d, err %v, cursor %d", n, it.Err(), it.Cursor())
+	}
+	if it.Next(context.Background()) || len(begins) != 2 {
+		t.Errorf("requests %v", begins)
+	}
+
+	// 服务器标记最后一页时不再请求
+	begins = nil
+	it = api.WarrantIterator(&WarrantRequest{PageSize: 2})
+	it.request = func(ctx context.Context, begin int32, num int32) (*Warrant, error) {
+		begins = append(begins, begin)
+		return &Warrant{WarrantList: []*WarrantData{{}, {}}, LastPage: begin == 2}, nil
+	}
+	n = 0
+	for it.Next(context.Background()) {
+		n++
+	}
+	if n != 4 || it.Err() != nil || fmt.Sprint(begins) != "[0 2]" {
+		t.Errorf("read %d, err %v, requests %v", n, it.Err(), begins)
+	}
+}
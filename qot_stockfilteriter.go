@@ -0,0 +1,99 @@
+package futuapi
+
+import (
+	"context"
+
+	"github.com/hurisheng/go-futu-api/pb/qotcommon"
+)
+
+// 条件选股每页最多返回的数量
+const StockFilterPageSize = 200
+
+// 条件选股请求参数
+type StockFilterRequest struct {
+	Market   qotcommon.QotMarket //*市场
+	Filter   *StockFilter        //筛选条件
+	Begin    int32               //开始位置，用于从保存的 Cursor 继续
+	PageSize int32               //每页数量，0 为最大值200
+}
+
+// 条件选股迭代器，按需逐页请求直到取完所有结果
+type StockFilterIterator struct {
+	offsetPager
+	req     StockFilterRequest
+	request func(ctx context.Context, begin int32, num int32) (*StockFilterResult, error)
+	page    []*StockData
+	cur     *StockData
+}
+
+// 创建条件选股迭代器，每页请求前遵守 GetStockFilter 的频率限制
+func (api *FutuAPI) StockFilterIterator(req *StockFilterRequest) *StockFilterIterator {
+	it := &StockFilterIterator{req: *req}
+	r := &it.req
+	it.request = func(ctx context.Context, begin int32, num int32) (*StockFilterResult, error) {
+		if err := api.WaitRateLimit(ctx, ProtoIDQotStockFilter); err != nil {
+			return nil, err
+		}
+		return api.GetStockFilter(ctx, r.Market, begin, num, r.Filter)
+	}
+	it.offsetPager = newOffsetPager(r.Begin, r.PageSize, StockFilterPageSize, it.fetch)
+	return it
+}
+
+// 移动到下一个结果，没有更多数据或出错时返回 false，出错原因由 Err 返回
+func (it *StockFilterIterator) Next(ctx context.Context) bool {
+	i, ok := it.next(ctx)
+	if !ok {
+		it.cur = nil
+		return false
+	}
+	it.cur = it.page[i]
+	return true
+}
+
+func (it *StockFilterIterator) fetch(ctx context.Context, begin int32, num int32) (int, bool, error) {
+	res, err := it.request(ctx, begin, num)
+	if err != nil {
+		return 0, false, err
+	}
+	if res == nil {
+		it.page = nil
+		return 0, true, nil
+	}
+	it.page, it.total = res.DataList, res.AllCount
+	return len(res.DataList), res.LastPage, nil
+}
+
+// 当前结果
+func (it *StockFilterIterator) StockData() *StockData {
+	return it.cur
+}
+
+// 迭代过程中的错误
+func (it *StockFilterIterator) Err() error {
+	return it.err
+}
+
+// 下一个未读取结果的位置，可以保存后设置为 StockFilterRequest.Begin 继续。
+// 两次请求之间数据可能变化，继续时的结果与一次取完不一定完全相同。
+func (it *StockFilterIterator) Cursor() int32 {
+	return it.cursor()
+}
+
+// 符合条件的总数，还没有请求时为 -1
+func (it *StockFilterIterator) Total() int32 {
+	return it.total
+}
+
+// 获取全部条件选股结果
+func (api *FutuAPI) GetAllStockFilter(ctx context.Context, req *StockFilterRequest) ([]*StockData, error) {
+	var list []*StockData
+	it := api.StockFilterIterator(req)
+	for it.Next(ctx) {
+		list = append(list, it.StockData())
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
@@ -0,0 +1,100 @@
+package futuapi
+
+import (
+	"context"
+
+	"github.com/hurisheng/go-futu-api/pb/qotcommon"
+)
+
+// 窝轮筛选每页最多返回的数量
+const WarrantPageSize = 200
+
+// 窝轮筛选请求参数
+type WarrantRequest struct {
+	SortField qotcommon.SortField //*排序字段
+	Ascend    bool                //*是否升序
+	Filter    *WarrantFilter      //筛选条件
+	Begin     int32               //开始位置，用于从保存的 Cursor 继续
+	PageSize  int32               //每页数量，0 为最大值200
+}
+
+// 窝轮筛选迭代器，按需逐页请求直到取完所有结果
+type WarrantIterator struct {
+	offsetPager
+	req     WarrantRequest
+	request func(ctx context.Context, begin int32, num int32) (*Warrant, error)
+	page    []*WarrantData
+	cur     *WarrantData
+}
+
+// 创建窝轮筛选迭代器，每页请求前遵守 GetWarrant 的频率限制
+func (api *FutuAPI) WarrantIterator(req *WarrantRequest) *WarrantIterator {
+	it := &WarrantIterator{req: *req}
+	r := &it.req
+	it.request = func(ctx context.Context, begin int32, num int32) (*Warrant, error) {
+		if err := api.WaitRateLimit(ctx, ProtoIDQotGetWarrant); err != nil {
+			return nil, err
+		}
+		return api.GetWarrant(ctx, begin, num, r.SortField, r.Ascend, r.Filter)
+	}
+	it.offsetPager = newOffsetPager(r.Begin, r.PageSize, WarrantPageSize, it.fetch)
+	return it
+}
+
+// 移动到下一个结果，没有更多数据或出错时返回 false，出错原因由 Err 返回
+func (it *WarrantIterator) Next(ctx context.Context) bool {
+	i, ok := it.next(ctx)
+	if !ok {
+		it.cur = nil
+		return false
+	}
+	it.cur = it.page[i]
+	return true
+}
+
+func (it *WarrantIterator) fetch(ctx context.Context, begin int32, num int32) (int, bool, error) {
+	res, err := it.request(ctx, begin, num)
+	if err != nil {
+		return 0, false, err
+	}
+	if res == nil {
+		it.page = nil
+		return 0, true, nil
+	}
+	it.page, it.total = res.WarrantList, res.AllCount
+	return len(res.WarrantList), res.LastPage, nil
+}
+
+// 当前窝轮
+func (it *WarrantIterator) Warrant() *WarrantData {
+	return it.cur
+}
+
+// 迭代过程中的错误
+func (it *WarrantIterator) Err() error {
+	return it.err
+}
+
+// 下一个未读取结果的位置，可以保存后设置为 WarrantRequest.Begin 继续。
+// 两次请求之间数据可能变化，继续时的结果与一次取完不一定完全相同。
+func (it *WarrantIterator) Cursor() int32 {
+	return it.cursor()
+}
+
+// 符合条件的总数，还没有请求时为 -1
+func (it *WarrantIterator) Total() int32 {
+	return it.total
+}
+
+// 获取全部窝轮筛选结果
+func (api *FutuAPI) GetAllWarrant(ctx context.Context, req *WarrantRequest) ([]*WarrantData, error) {
+	var list []*WarrantData
+	it := api.WarrantIterator(req)
+	for it.Next(ctx) {
+		list = append(list, it.Warrant())
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
@@ -0,0 +1,104 @@
+package futuapi
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// 富途 OpenAPI 对部分协议有频率限制，默认按官方文档取每 30 秒内的最大请求次数
+var defaultRateLimits = map[uint32]int{
+	ProtoIDQotRequestHistoryKL:       60,
+	ProtoIDQotRequestRehab:           60,
+	ProtoIDQotGetSecuritySnapshot:    60,
+	ProtoIDQotGetPlateSet:            10,
+	ProtoIDQotGetPlateSecurity:       10,
+	ProtoIDQotGetOwnerPlate:          10,
+	ProtoIDQotGetOptionChain:         10,
+	ProtoIDQotGetWarrant:             60,
+	ProtoIDQotGetCapitalFlow:         30,
+	ProtoIDQotGetCapitalDistribution: 30,
+	ProtoIDQotStockFilter:            10,
+	ProtoIDQotGetFutureInfo:          30,
+	ProtoIDQotRequestTradeDate:       30,
+	ProtoIDQotGetMarketState:         10,
+}
+
+const defaultRatePeriod = 30 * time.Second
+
+// 滑动窗口限频器，period 时间内最多允许 n 次请求
+type rateLimit struct {
+	n      int
+	period time.Duration
+
+	mu    sync.Mutex
+	times []time.Time
+}
+
+func newRateLimit(n int, period time.Duration) *rateLimit {
+	return &rateLimit{n: n, period: period}
+}
+
+// 等待直到可以发送请求，ctx 结束时返回 ErrInterrupted
+func (l *rateLimit) wait(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		// 丢弃窗口外的请求记录
+		i := 0
+		for i < len(l.times) && now.Sub(l.times[i]) >= l.period {
+			i++
+		}
+		l.times = l.times[i:]
+		if len(l.times) < l.n {
+			l.times = append(l.times, now)
+			l.mu.Unlock()
+			return nil
+		}
+		d := l.times[0].Add(l.period).Sub(now)
+		l.mu.Unlock()
+
+		t := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ErrInterrupted
+		case <-t.C:
+		}
+	}
+}
+
+// 设置协议的频率限制，period 时间内最多 n 次请求，n 小于等于0表示不限制。
+// 仅对SDK内部的分页、分批等辅助方法生效，直接调用的接口不受影响。
+func (api *FutuAPI) SetRateLimit(proto uint32, n int, period time.Duration) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	if api.limits == nil {
+		api.limits = make(map[uint32]*rateLimit)
+	}
+	if n <= 0 {
+		api.limits[proto] = nil
+		return
+	}
+	api.limits[proto] = newRateLimit(n, period)
+}
+
+// 按协议的频率限制等待，用于在SDK外部批量调用接口时遵守频率限制
+func (api *FutuAPI) WaitRateLimit(ctx context.Context, proto uint32) error {
+	api.mu.Lock()
+	if api.limits == nil {
+		api.limits = make(map[uint32]*rateLimit)
+	}
+	l, ok := api.limits[proto]
+	if !ok {
+		if n := defaultRateLimits[proto]; n > 0 {
+			l = newRateLimit(n, defaultRatePeriod)
+		}
+		api.limits[proto] = l
+	}
+	api.mu.Unlock()
+	if l == nil {
+		return nil
+	}
+	return l.wait(ctx)
+}
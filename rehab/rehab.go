@@ -0,0 +1,125 @@
+// Package rehab 用 GetRehab 返回的复权因子在本地对 K 线复权。
+//
+// 本地只需要保存不复权的 K 线，前复权、后复权都可以随时计算，不必再消耗历史 K 线额度。
+// 富途的复权公式为：
+//
+//	前复权价格 = 不复权价格 × 前复权因子A + 前复权因子B（除权除息日之前的 K 线）
+//	后复权价格 = 不复权价格 × 后复权因子A + 后复权因子B（除权除息日及之后的 K 线）
+//
+// 多次公司行动时依次叠加。只调整价格字段，成交量、成交额等保持不变。
+package rehab
+
+import (
+	"math"
+	"sort"
+
+	"github.com/hurisheng/go-futu-api/pb/qotcommon"
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+)
+
+// 价格变换 p*a+b
+type affine struct {
+	a, b float64
+}
+
+func (f affine) apply(p float64) float64 {
+	return p*f.a + f.b
+}
+
+// 逆变换，因子 A 为0时无法还原，返回 NaN
+func (f affine) invert(p float64) float64 {
+	if f.a == 0 {
+		return math.NaN()
+	}
+	return (p - f.b) / f.a
+}
+
+// 把不复权的 K 线转换为 to 指定的复权类型，返回新的列表，不修改输入
+func Adjust(list []*futuapi.KLine, rehabs []*futuapi.Rehab, to qotcommon.RehabType) []*futuapi.KLine {
+	return Convert(list, rehabs, qotcommon.RehabType_RehabType_None, to)
+}
+
+// 把 from 指定复权类型的 K 线还原为不复权，返回新的列表，不修改输入。
+// 复权因子 A 为0时无法还原，对应 K 线的价格为 NaN
+func Unadjust(list []*futuapi.KLine, rehabs []*futuapi.Rehab, from qotcommon.RehabType) []*futuapi.KLine {
+	return Convert(list, rehabs, from, qotcommon.RehabType_RehabType_None)
+}
+
+// 在复权类型之间转换，返回新的列表，不修改输入。from 不是不复权时同 Unadjust，无法还原的价格为 NaN
+func Convert(list []*futuapi.KLine, rehabs []*futuapi.Rehab, from qotcommon.RehabType, to qotcommon.RehabType) []*futuapi.KLine {
+	events := sorted(rehabs)
+	out := make([]*futuapi.KLine, len(list))
+	for i, k := range list {
+		if k == nil {
+			continue
+		}
+		c := *k
+		if !c.IsBlank && from != to {
+			day := date(c.Time)
+			if from != qotcommon.RehabType_RehabType_None {
+				maps := factors(events, day, from)
+				for j := len(maps) - 1; j >= 0; j-- {
+					transform(&c, maps[j].invert)
+				}
+			}
+			if to != qotcommon.RehabType_RehabType_None {
+				for _, f := range factors(events, day, to) {
+					transform(&c, f.apply)
+				}
+			}
+		}
+		out[i] = &c
+	}
+	return out
+}
+
+// 按除权除息日排序
+func sorted(rehabs []*futuapi.Rehab) []*futuapi.Rehab {
+	events := make([]*futuapi.Rehab, 0, len(rehabs))
+	for _, r := range rehabs {
+		if r != nil {
+			events = append(events, r)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return date(events[i].Time) < date(events[j].Time)
+	})
+	return events
+}
+
+// 指定日期的 K 线需要依次应用的变换。
+// 前复权：之后的每次公司行动，按时间顺序；后复权：当天及之前的每次公司行动，按时间倒序。
+func factors(events []*futuapi.Rehab, day string, t qotcommon.RehabType) []affine {
+	var maps []affine
+	switch t {
+	case qotcommon.RehabType_RehabType_Forward:
+		for _, e := range events {
+			if date(e.Time) > day {
+				maps = append(maps, affine{a: e.FwdFactorA, b: e.FwdFactorB})
+			}
+		}
+	case qotcommon.RehabType_RehabType_Backward:
+		for i := len(events) - 1; i >= 0; i-- {
+			if e := events[i]; date(e.Time) <= day {
+				maps = append(maps, affine{a: e.BwdFactorA, b: e.BwdFactorB})
+			}
+		}
+	}
+	return maps
+}
+
+func transform(k *futuapi.KLine, f func(float64) float64) {
+	k.OpenPrice = f(k.OpenPrice)
+	k.HighPrice = f(k.HighPrice)
+	k.LowPrice = f(k.LowPrice)
+	k.ClosePrice = f(k.ClosePrice)
+	k.LastClosePrice = f(k.LastClosePrice)
+}
+
+// 时间字符串的日期部分，格式为 YYYY-MM-DD，可以直接按字符串比较
+func date(s string) string {
+	if len(s) > 10 {
+		return s[:10]
+	}
+	return s
+}
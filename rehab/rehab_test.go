@@ -0,0 +1,105 @@
+package rehab
+
+import (
+	"math"
+	"testing"
+
+	"github.com/hurisheng/go-futu-api/pb/qotcommon"
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+)
+
+func testRehabs() []*futuapi.Rehab {
+	// 1拆2之后每股派息0.5，故意乱序
+	return []*futuapi.Rehab{
+		{Time: "2024-02-10", SplitBase: 1, SplitErt: 2, FwdFactorA: 0.5, BwdFactorA: 2},
+		{Time: "2024-01-10", Dividend: 0.5, FwdFactorA: 1, FwdFactorB: -0.5, BwdFactorA: 1, BwdFactorB: 0.5},
+	}
+}
+
+func testKLines() []*futuapi.KLine {
+	return []*futuapi.KLine{
+		{Time: "2024-01-05 00:00:00", OpenPrice: 19, HighPrice: 21, LowPrice: 18, ClosePrice: 20, LastClosePrice: 19, Volume: 100},
+		{Time: "2024-01-10 00:00:00", OpenPrice: 19.5, HighPrice: 20, LowPrice: 19, ClosePrice: 19.5, LastClosePrice: 20},
+		{Time: "2024-01-20 00:00:00", IsBlank: true},
+		{Time: "2024-02-10 00:00:00", OpenPrice: 10, HighPrice: 11, LowPrice: 9, ClosePrice: 10, LastClosePrice: 19.5},
+		nil,
+	}
+}
+
+func TestAdjust(t *testing.T) {
+	list := testKLines()
+	fwd := Adjust(list, testRehabs(), qotcommon.RehabType_RehabType_Forward)
+	bwd := Adjust(list, testRehabs(), qotcommon.RehabType_RehabType_Backward)
+	cases := []struct {
+		k    *futuapi.KLine
+		want float64
+	}{
+		{fwd[0], (20 - 0.5) * 0.5},
+		{fwd[1], 19.5 * 0.5},
+		{fwd[3], 10},
+		{bwd[0], 20},
+		{bwd[1], 19.5 + 0.5},
+		{bwd[3], 10*2 + 0.5},
+	}
+	for i, c := range cases {
+		if math.Abs(c.k.ClosePrice-c.want) > 1e-9 {
+			t.Errorf("case %d: close %v, want %v", i, c.k.ClosePrice, c.want)
+		}
+	}
+	if fwd[0].Volume != 100 || fwd[2].ClosePrice != 0 || fwd[4] != nil {
+		t.Errorf("unexpected %+v %+v %+v", fwd[0], fwd[2], fwd[4])
+	}
+	if list[0].ClosePrice != 20 {
+		t.Errorf("input modified: %+v", list[0])
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	list := testKLines()
+	rehabs := testRehabs()
+	types := []qotcommon.RehabType{qotcommon.RehabType_RehabType_Forward, qotcommon.RehabType_RehabType_Backward}
+	for _, typ := range types {
+		equal(t, "unadjust "+typ.String(), Unadjust(Adjust(list, rehabs, typ), rehabs, typ), list)
+	}
+	for _, from := range types {
+		for _, to := range types {
+			got := Convert(Adjust(list, rehabs, from), rehabs, from, to)
+			equal(t, "convert "+from.String()+" "+to.String(), got, Adjust(list, rehabs, to))
+		}
+	}
+}
+
+func TestUnadjustZeroFactor(t *testing.T) {
+	rehabs := []*futuapi.Rehab{{Time: "2024-02-10"}}
+	got := Unadjust(testKLines(), rehabs, qotcommon.RehabType_RehabType_Forward)
+	if !math.IsNaN(got[0].ClosePrice) {
+		t.Errorf("close %v, want NaN", got[0].ClosePrice)
+	}
+	if got[3].ClosePrice != 10 {
+		t.Errorf("close %v after the event", got[3].ClosePrice)
+	}
+}
+
+func equal(t *testing.T, name string, got, want []*futuapi.KLine) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: %d K lines, want %d", name, len(got), len(want))
+	}
+	for i := range got {
+		if (got[i] == nil) != (want[i] == nil) {
+			t.Errorf("%s: K line %d %+v, want %+v", name, i, got[i], want[i])
+			continue
+		}
+		if got[i] == nil {
+			continue
+		}
+		g, w := got[i], want[i]
+		for _, p := range [][2]float64{{g.OpenPrice, w.OpenPrice}, {g.HighPrice, w.HighPrice}, {g.LowPrice, w.LowPrice},
+			{g.ClosePrice, w.ClosePrice}, {g.LastClosePrice, w.LastClosePrice}} {
+			if math.Abs(p[0]-p[1]) > 1e-9 {
+				t.Errorf("%s: K line %d %+v, want %+v", name, i, g, w)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package resample
+
+import (
+	"sort"
+	"time"
+
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+)
+
+// 时间轴的生成方式
+type TimelineMode int
+
+const (
+	TimelineUnion     TimelineMode = iota //所有序列时间的并集
+	TimelineIntersect                     //所有序列都有非空数据的时间
+)
+
+// 缺失数据的填充方式
+type FillMode int
+
+const (
+	FillNone    FillMode = iota //缺失处为 nil
+	FillForward                 //用上一根的收盘价填充，成交量为0
+	FillBlank                   //填充只有时间信息的空内容点
+)
+
+// 对齐参数
+type AlignOptions struct {
+	Timeline TimelineMode
+	Fill     FillMode
+	// 输入中的空内容点视为缺失，按 Fill 填充；否则原样保留
+	BlankAsMissing bool
+	// 填充点时间字符串使用的时区，为空时使用 UTC
+	Location *time.Location
+}
+
+// 对齐结果
+type Aligned struct {
+	Timestamps []float64          //共同时间轴
+	Series     [][]*futuapi.KLine //与输入顺序一致，每个序列长度与时间轴相同
+}
+
+// 把多只证券按时间升序排列的 K 线对齐到同一时间轴，时间以 Timestamp 为准
+func Align(series [][]*futuapi.KLine, opts *AlignOptions) *Aligned {
+	if opts == nil {
+		opts = &AlignOptions{}
+	}
+	loc := opts.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	// 每个序列按时间建立索引，并统计每个时间有非空数据的序列个数
+	index := make([]map[float64]*futuapi.KLine, len(series))
+	seen := make(map[float64]bool)
+	filled := make(map[float64]int)
+	for i, s := range series {
+		index[i] = make(map[float64]*futuapi.KLine, len(s))
+		for _, k := range s {
+			if k == nil {
+				continue
+			}
+			seen[k.Timestamp] = true
+			if k.IsBlank && opts.BlankAsMissing {
+				continue
+			}
+			if _, ok := index[i][k.Timestamp]; !ok && !k.IsBlank {
+				filled[k.Timestamp]++
+			}
+			index[i][k.Timestamp] = k
+		}
+	}
+	var timeline []float64
+	for ts := range seen {
+		if opts.Timeline == TimelineIntersect && filled[ts] < len(series) {
+			continue
+		}
+		timeline = append(timeline, ts)
+	}
+	sort.Float64s(timeline)
+
+	a := Aligned{Timestamps: timeline, Series: make([][]*futuapi.KLine, len(series))}
+	for i := range series {
+		list := make([]*futuapi.KLine, len(timeline))
+		var prev *futuapi.KLine
+		for j, ts := range timeline {
+			k := index[i][ts]
+			if k != nil {
+				list[j] = k
+				if !k.IsBlank {
+					prev = k
+				}
+				continue
+			}
+			list[j] = fill(prev, ts, opts.Fill, loc)
+		}
+		a.Series[i] = list
+	}
+	return &a
+}
+
+func fill(prev *futuapi.KLine, ts float64, mode FillMode, loc *time.Location) *futuapi.KLine {
+	t := KLineTime(&futuapi.KLine{Timestamp: ts}, loc).Format(timeLayout)
+	switch mode {
+	case FillForward:
+		if prev == nil {
+			return nil
+		}
+		return &futuapi.KLine{
+			Time:           t,
+			HighPrice:      prev.ClosePrice,
+			OpenPrice:      prev.ClosePrice,
+			LowPrice:       prev.ClosePrice,
+			ClosePrice:     prev.ClosePrice,
+			LastClosePrice: prev.ClosePrice,
+			PE:             prev.PE,
+			Timestamp:      ts,
+		}
+	case FillBlank:
+		return &futuapi.KLine{Time: t, IsBlank: true, Timestamp: ts}
+	default:
+		return nil
+	}
+}
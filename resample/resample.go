@@ -0,0 +1,200 @@
+// Package resample 把细周期的 K 线合成为粗周期，例如1分钟合成7分钟、日线合成周线，
+// 并可以把多只证券的 K 线对齐到同一时间轴。
+package resample
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+	"github.com/woxinyoumeng/go-futu-api/bar"
+)
+
+var (
+	ErrInvalidPeriod = errors.New("resample: invalid period")
+)
+
+const timeLayout = "2006-01-02 15:04:05"
+
+type periodKind int
+
+const (
+	kindIntraday periodKind = iota
+	kindDays
+	kindWeek
+	kindMonth
+	kindQuarter
+	kindYear
+)
+
+// 合成的目标周期
+type Period struct {
+	kind periodKind
+	n    int
+	d    time.Duration
+	days []time.Time //交易日历，为空时从输入推断交易日
+}
+
+// 日内周期，按交易时段切分
+func Intraday(d time.Duration) Period {
+	return Period{kind: kindIntraday, d: d}
+}
+
+// N 分钟
+func Minutes(n int) Period {
+	return Intraday(time.Duration(n) * time.Minute)
+}
+
+// N 个交易日，交易日从输入中的非空 K 线推断。输入缺少某个交易日（如停牌）时，
+// 分组会与交易所日历错开，需要严格按日历分组时使用 TradingDays。
+func Days(n int) Period {
+	return Period{kind: kindDays, n: n}
+}
+
+// 按交易日历每 N 个交易日一组，days 为交易日（如 RequestTradeDate 或 calendar 包的结果），
+// 从第一个交易日开始分组，停牌等没有数据的交易日也计入组内天数。
+func TradingDays(n int, days []time.Time) Period {
+	return Period{kind: kindDays, n: n, days: days}
+}
+
+// 自然周
+func Week() Period {
+	return Period{kind: kindWeek}
+}
+
+// 自然月
+func Month() Period {
+	return Period{kind: kindMonth}
+}
+
+// 自然季度
+func Quarter() Period {
+	return Period{kind: kindQuarter}
+}
+
+// 自然年
+func Year() Period {
+	return Period{kind: kindYear}
+}
+
+// 把按时间升序排列的 K 线合成为目标周期。
+// 日内周期按 opts 的交易时段切分，K 线时间表示区间结束时间（与富途分钟 K 线一致）；
+// 日线以上周期按交易日分组，时间为组内最后一根非空 K 线的交易日，空内容点不改变时间。
+// 全部为空内容点的组合成为空内容点。opts 为空时全天连续交易、使用 UTC。
+func Resample(list []*futuapi.KLine, p Period, opts *bar.Options) ([]*futuapi.KLine, error) {
+	if opts == nil {
+		opts = &bar.Options{}
+	}
+	loc := opts.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	if (p.kind == kindIntraday && p.d <= 0) || (p.kind == kindDays && p.n <= 0) {
+		return nil, ErrInvalidPeriod
+	}
+	var calendar []string
+	for _, d := range p.days {
+		calendar = append(calendar, d.In(loc).Format("2006-01-02"))
+	}
+	sort.Strings(calendar)
+	var (
+		out   []*futuapi.KLine
+		cur   *futuapi.KLine
+		key   int64
+		days  int
+		first = true
+	)
+	for _, k := range list {
+		if k == nil {
+			continue
+		}
+		t := KLineTime(k, loc)
+		var (
+			g     int64
+			label time.Time
+		)
+		switch p.kind {
+		case kindIntraday:
+			// 分钟 K 线的时间为结束时间，减去1纳秒得到区间内的时间
+			_, end := opts.Bucket(t.Add(-time.Nanosecond), p.d)
+			g, label = end.UnixNano(), end
+		case kindDays:
+			if calendar != nil {
+				// 非交易日的空内容点归入之后最近的交易日
+				i := sort.SearchStrings(calendar, t.Format("2006-01-02"))
+				g, label = int64(i/p.n), t
+				break
+			}
+			if !k.IsBlank {
+				days++
+			}
+			g, label = int64((days+p.n-1)/p.n), t
+		default:
+			g, label = calendarKey(t, p.kind), t
+		}
+		if first || g != key {
+			if cur != nil {
+				out = append(out, cur)
+			}
+			cur = &futuapi.KLine{IsBlank: true}
+			key, first = g, false
+		}
+		mergeKLine(cur, k)
+		if !k.IsBlank || cur.IsBlank {
+			cur.Time = label.In(loc).Format(timeLayout)
+			cur.Timestamp = float64(label.Unix())
+		}
+	}
+	if cur != nil {
+		out = append(out, cur)
+	}
+	return out, nil
+}
+
+func calendarKey(t time.Time, kind periodKind) int64 {
+	switch kind {
+	case kindWeek:
+		y, w := t.ISOWeek()
+		return int64(y*100 + w)
+	case kindMonth:
+		return int64(t.Year()*100 + int(t.Month()))
+	case kindQuarter:
+		return int64(t.Year()*100 + (int(t.Month())-1)/3)
+	default:
+		return int64(t.Year())
+	}
+}
+
+// 把 k 合并到 dst
+func mergeKLine(dst *futuapi.KLine, k *futuapi.KLine) {
+	if k.IsBlank {
+		return
+	}
+	if dst.IsBlank {
+		dst.IsBlank = false
+		dst.OpenPrice = k.OpenPrice
+		dst.HighPrice = k.HighPrice
+		dst.LowPrice = k.LowPrice
+		dst.LastClosePrice = k.LastClosePrice
+	}
+	if k.HighPrice > dst.HighPrice {
+		dst.HighPrice = k.HighPrice
+	}
+	if k.LowPrice < dst.LowPrice {
+		dst.LowPrice = k.LowPrice
+	}
+	dst.ClosePrice = k.ClosePrice
+	dst.Volume += k.Volume
+	dst.Turnover += k.Turnover
+	dst.TurnoverRate += k.TurnoverRate
+	dst.PE = k.PE
+	if dst.LastClosePrice != 0 {
+		dst.ChangeRate = (dst.ClosePrice/dst.LastClosePrice - 1) * 100
+	}
+}
+
+// K 线的时间，优先使用时间戳，没有时间戳时按 loc 解析时间字符串
+func KLineTime(k *futuapi.KLine, loc *time.Location) time.Time {
+	return k.TimeIn(loc)
+}
@@ -0,0 +1,121 @@
+package resample
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hurisheng/go-futu-api/pb/qotcommon"
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+	"github.com/woxinyoumeng/go-futu-api/bar"
+)
+
+func TestResampleMinutes(t *testing.T) {
+	opts := bar.MarketOptions(qotcommon.QotMarket_QotMarket_HK_Security)
+	day := time.Date(2021, 1, 4, 0, 0, 0, 0, opts.Location)
+	var list []*futuapi.KLine
+	// 上午最后10分钟和下午前5分钟的1分钟 K 线，时间为结束时间
+	for _, m := range []int{11*60 + 51, 11*60 + 52, 11*60 + 53, 11*60 + 54, 11*60 + 55, 11*60 + 56, 11*60 + 57, 11*60 + 58, 11*60 + 59, 12 * 60, 13*60 + 1, 13*60 + 2} {
+		ts := day.Add(time.Duration(m) * time.Minute)
+		list = append(list, &futuapi.KLine{Timestamp: float64(ts.Unix()), OpenPrice: float64(m), HighPrice: float64(m), LowPrice: float64(m), ClosePrice: float64(m), Volume: 1})
+	}
+	out, err := Resample(list, Minutes(7), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 9:30 起每7分钟一根：11:50-11:57，11:57-12:00（时段末尾不足7分钟），13:00-13:07
+	want := []string{"2021-01-04 11:57:00", "2021-01-04 12:00:00", "2021-01-04 13:07:00"}
+	vols := []int64{7, 3, 2}
+	if len(out) != len(want) {
+		t.Fatalf("len = %v, want %v: %+v", len(out), len(want), out)
+	}
+	for i, k := range out {
+		if k.Time != want[i] || k.Volume != vols[i] {
+			t.Errorf("bar %v = %v vol %v, want %v vol %v", i, k.Time, k.Volume, want[i], vols[i])
+		}
+	}
+}
+
+func TestResampleWeek(t *testing.T) {
+	var list []*futuapi.KLine
+	for _, d := range []int{4, 5, 6, 7, 8, 11, 12} {
+		ts := time.Date(2021, 1, d, 0, 0, 0, 0, time.UTC)
+		list = append(list, &futuapi.KLine{Timestamp: float64(ts.Unix()), OpenPrice: float64(d), HighPrice: float64(d), LowPrice: float64(d), ClosePrice: float64(d), IsBlank: d == 6})
+	}
+	out, err := Resample(list, Week(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 2 {
+		t.Fatalf("len = %v", len(out))
+	}
+	if k := out[0]; k.OpenPrice != 4 || k.ClosePrice != 8 || k.Time != "2021-01-08 00:00:00" {
+		t.Errorf("week 1 = %+v", k)
+	}
+	if k := out[1]; k.OpenPrice != 11 || k.ClosePrice != 12 {
+		t.Errorf("week 2 = %+v", k)
+	}
+}
+
+func TestAlign(t *testing.T) {
+	a := []*futuapi.KLine{{Timestamp: 1, ClosePrice: 10}, {Timestamp: 3, ClosePrice: 30}}
+	b := []*futuapi.KLine{{Timestamp: 1, ClosePrice: 1}, {Timestamp: 2, ClosePrice: 2}, {Timestamp: 3, IsBlank: true}}
+	r := Align([][]*futuapi.KLine{a, b}, &AlignOptions{Fill: FillForward, BlankAsMissing: true})
+	if len(r.Timestamps) != 3 {
+		t.Fatalf("timeline = %v", r.Timestamps)
+	}
+	if k := r.Series[0][1]; k == nil || k.ClosePrice != 10 || k.Volume != 0 {
+		t.Errorf("forward fill = %+v", k)
+	}
+	if k := r.Series[1][2]; k == nil || k.ClosePrice != 2 || k.IsBlank {
+		t.Errorf("blank fill = %+v", k)
+	}
+	r = Align([][]*futuapi.KLine{a, b}, &AlignOptions{Timeline: TimelineIntersect})
+	if len(r.Timestamps) != 1 || r.Timestamps[0] != 1 {
+		t.Errorf("intersect = %v", r.Timestamps)
+	}
+}
+
+func TestResampleBlankLabel(t *testing.T) {
+	var list []*futuapi.KLine
+	// 1月9、10日为周末的空内容点，周线和2日线的时间仍为最后一个交易日
+	for _, d := range []int{7, 8, 9, 10} {
+		ts := time.Date(2021, 1, d, 0, 0, 0, 0, time.UTC)
+		list = append(list, &futuapi.KLine{Timestamp: float64(ts.Unix()), ClosePrice: float64(d), IsBlank: d > 8})
+	}
+	for _, p := range []Period{Week(), Days(2)} {
+		out, err := Resample(list, p, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(out) != 1 || out[0].Time != "2021-01-08 00:00:00" || out[0].ClosePrice != 8 {
+			t.Errorf("%+v: %+v", p, out[0])
+		}
+	}
+}
+
+func TestResampleTradingDays(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2021, 1, d, 0, 0, 0, 0, time.UTC) }
+	var calendar []time.Time
+	for _, d := range []int{4, 5, 6, 7, 8, 11} {
+		calendar = append(calendar, day(d))
+	}
+	// 6日停牌没有数据，仍按日历计入第二组
+	var list []*futuapi.KLine
+	for _, d := range []int{4, 5, 7, 8, 11} {
+		list = append(list, &futuapi.KLine{Timestamp: float64(day(d).Unix()), ClosePrice: float64(d), Volume: 1})
+	}
+	out, err := Resample(list, TradingDays(2, calendar), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"2021-01-05 00:00:00", "2021-01-07 00:00:00", "2021-01-11 00:00:00"}
+	vols := []int64{2, 1, 2}
+	if len(out) != len(want) {
+		t.Fatalf("len = %v: %+v", len(out), out)
+	}
+	for i, k := range out {
+		if k.Time != want[i] || k.Volume != vols[i] {
+			t.Errorf("group %v = %v vol %v, want %v vol %v", i, k.Time, k.Volume, want[i], vols[i])
+		}
+	}
+}
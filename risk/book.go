@@ -0,0 +1,321 @@
+// Package risk 汇总账户持仓的希腊值，按标的和总体计算 Delta、Gamma、Vega、Theta，
+// 并对标的价格和波动率冲击进行情景损益分析。持仓随成交推送、价格随基本行情推送实时更新。
+package risk
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/hurisheng/go-futu-api/pb/qotcommon"
+	"github.com/hurisheng/go-futu-api/pb/trdcommon"
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+	"github.com/woxinyoumeng/go-futu-api/pricing"
+)
+
+// 账户持仓簿，保存持仓数量、期权静态信息和最新价格
+type Book struct {
+	mu      sync.RWMutex
+	header  *futuapi.TrdHeader
+	cfg     *pricing.Config
+	qty     map[futuapi.Security]float64 //持仓数量，空仓为负数，期权单位是"张"
+	static  map[futuapi.Security]*futuapi.SecurityStaticInfo
+	options map[futuapi.Security]*futuapi.OptionQuote
+	prices  map[futuapi.Security]float64
+	ivs     map[futuapi.Security]float64 //行情推送中的隐含波动率，本地无法求解时使用
+	mults   map[futuapi.Security]float64 //期货合约乘数
+	fills   map[fillKey]float64          //已计入持仓的成交数量，卖出为负数
+	updated time.Time
+}
+
+type fillKey struct {
+	id  uint64
+	sec futuapi.Security
+}
+
+// 创建持仓簿，header 为账户，只处理该账户的成交推送
+func New(header *futuapi.TrdHeader, cfg *pricing.Config) *Book {
+	return &Book{
+		header:  header,
+		cfg:     cfg,
+		qty:     make(map[futuapi.Security]float64),
+		static:  make(map[futuapi.Security]*futuapi.SecurityStaticInfo),
+		options: make(map[futuapi.Security]*futuapi.OptionQuote),
+		prices:  make(map[futuapi.Security]float64),
+		ivs:     make(map[futuapi.Security]float64),
+		mults:   make(map[futuapi.Security]float64),
+		fills:   make(map[fillKey]float64),
+		updated: time.Now(),
+	}
+}
+
+// 替换全部持仓，之前计入的成交已包含在新持仓中，重新记录成交
+func (b *Book) SetPositions(list []*futuapi.Position) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.qty = make(map[futuapi.Security]float64, len(list))
+	b.fills = make(map[fillKey]float64)
+	for _, p := range list {
+		if p == nil || p.Qty == 0 {
+			continue
+		}
+		qty := p.Qty
+		if p.PositionSide == trdcommon.PositionSide_PositionSide_Short {
+			qty = -qty
+		}
+		b.qty[*p.Security()] += qty
+	}
+	b.updated = time.Now()
+}
+
+// 设置证券静态信息，期权根据静态信息中的行权价、行权日和每手数量定价
+func (b *Book) SetStatic(list []*futuapi.SecurityStaticInfo) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, info := range list {
+		if info == nil || info.Basic == nil || info.Basic.Security == nil {
+			continue
+		}
+		sec := *info.Basic.Security
+		b.static[sec] = info
+		if o := info.OptionExData; o != nil {
+			expiry := o.StrikeTime
+			if len(expiry) > len("2006-01-02") {
+				expiry = expiry[:len("2006-01-02")]
+			}
+			b.options[sec] = &futuapi.OptionQuote{
+				OptionKey: futuapi.OptionKey{Expiry: expiry, Strike: o.StrikePrice, Type: o.Type},
+				Info:      info,
+			}
+		}
+	}
+}
+
+// 根据期货合约资料设置合约乘数
+func (b *Book) SetFutureInfo(list []*futuapi.FutureInfo) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, info := range list {
+		if info == nil || info.Security == nil || info.ContractSize <= 0 {
+			continue
+		}
+		b.mults[*info.Security] = info.ContractSize
+	}
+}
+
+// 设置期货的合约乘数，覆盖合约资料中的合约规模
+func (b *Book) SetMultiplier(sec *futuapi.Security, mult float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.mults[*sec] = mult
+}
+
+// 是否是缺少合约乘数的期货
+func (b *Book) missingMult(sec futuapi.Security) bool {
+	info := b.static[sec]
+	return info != nil && info.Basic.SecType == qotcommon.SecurityType_SecurityType_Future && b.mults[sec] <= 0
+}
+
+// 根据快照设置价格，期权快照同时用于确定行权类型
+func (b *Book) SetSnapshots(list []*futuapi.Snapshot) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, s := range list {
+		if s == nil || s.Basic == nil || s.Basic.Security == nil {
+			continue
+		}
+		sec := *s.Basic.Security
+		if q := b.options[sec]; q != nil {
+			q.Snapshot = s
+			if p := pricing.MidPrice(s); p > 0 {
+				b.prices[sec] = p
+			}
+			if s.OptionExData != nil && s.OptionExData.ImpliedVolatility > 0 {
+				b.ivs[sec] = s.OptionExData.ImpliedVolatility / 100
+			}
+		} else if s.Basic.CurPrice > 0 {
+			b.prices[sec] = s.Basic.CurPrice
+		}
+	}
+	b.updated = time.Now()
+}
+
+// 处理基本行情，更新证券的最新价
+func (b *Book) UpdateQuotes(qots []*futuapi.BasicQot) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, q := range qots {
+		if q == nil || q.Security == nil || q.CurPrice <= 0 {
+			continue
+		}
+		b.prices[*q.Security] = q.CurPrice
+		if q.OptionExData != nil && q.OptionExData.ImpliedVolatility > 0 {
+			b.ivs[*q.Security] = q.OptionExData.ImpliedVolatility / 100
+		}
+	}
+	b.updated = time.Now()
+}
+
+// 根据成交更新持仓数量，其他账户的成交忽略。同一成交号重复推送或被更改时只计入与上次的差额，
+// 成交被取消时冲回已计入的数量。返回成交证券是否缺少静态信息或期货合约乘数。
+func (b *Book) ApplyFill(header *futuapi.TrdHeader, fill *futuapi.OrderFill) bool {
+	if fill == nil || (b.header != nil && header != nil && header.AccID != b.header.AccID) {
+		return false
+	}
+	qty := fill.Qty
+	switch fill.TrdSide {
+	case trdcommon.TrdSide_TrdSide_Buy, trdcommon.TrdSide_TrdSide_BuyBack:
+	case trdcommon.TrdSide_TrdSide_Sell, trdcommon.TrdSide_TrdSide_SellShort:
+		qty = -qty
+	default:
+		return false
+	}
+	if fill.Status == trdcommon.OrderFillStatus_OrderFillStatus_Cancelled {
+		qty = 0
+	}
+	sec := *fill.Security()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	key := fillKey{id: fill.FillID, sec: sec}
+	diff := qty - b.fills[key]
+	if qty == 0 {
+		delete(b.fills, key)
+	} else {
+		b.fills[key] = qty
+	}
+	if diff != 0 {
+		b.qty[sec] += diff
+		if b.qty[sec] == 0 {
+			delete(b.qty, sec)
+		}
+	}
+	if fill.Price > 0 && qty != 0 {
+		b.prices[sec] = fill.Price
+	}
+	b.updated = time.Now()
+	return qty != 0 && (b.static[sec] == nil || b.missingMult(sec))
+}
+
+// 最后更新时间
+func (b *Book) Updated() time.Time {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.updated
+}
+
+// 持仓证券和期权标的，用于订阅基本行情推送
+func (b *Book) Securities() []*futuapi.Security {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	seen := make(map[futuapi.Security]bool)
+	var list []*futuapi.Security
+	add := func(sec futuapi.Security) {
+		if !seen[sec] {
+			seen[sec] = true
+			list = append(list, &sec)
+		}
+	}
+	for sec := range b.qty {
+		add(sec)
+		if q := b.options[sec]; q != nil && q.Info.OptionExData.Owner != nil {
+			add(*q.Info.OptionExData.Owner)
+		}
+	}
+	return list
+}
+
+// 没有静态信息的持仓证券
+func (b *Book) missingStatic() []*futuapi.Security {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	var list []*futuapi.Security
+	for sec := range b.qty {
+		if b.static[sec] == nil {
+			s := sec
+			list = append(list, &s)
+		}
+	}
+	return list
+}
+
+// 从接口获取账户持仓、持仓证券的静态信息，以及持仓证券和期权标的的快照。接口调用遵守频率限制。
+func (b *Book) Load(ctx context.Context, api *futuapi.FutuAPI) error {
+	if err := api.WaitRateLimit(ctx, futuapi.ProtoIDTrdGetPositionList); err != nil {
+		return err
+	}
+	positions, err := api.GetPositionList(ctx, b.header, nil, 0, 0, false)
+	if err != nil {
+		return err
+	}
+	b.SetPositions(positions)
+	return b.refresh(ctx, api)
+}
+
+// 缺少合约乘数的期货持仓
+func (b *Book) missingMults() []*futuapi.Security {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	var list []*futuapi.Security
+	for sec := range b.qty {
+		if b.missingMult(sec) {
+			s := sec
+			list = append(list, &s)
+		}
+	}
+	return list
+}
+
+// 获取缺少静态信息的证券的静态信息、期货的合约资料，以及持仓证券和期权标的的快照
+func (b *Book) refresh(ctx context.Context, api *futuapi.FutuAPI) error {
+	if missing := b.missingStatic(); len(missing) > 0 {
+		if err := api.WaitRateLimit(ctx, futuapi.ProtoIDQotGetStaticInfo); err != nil {
+			return err
+		}
+		infos, err := api.GetStockBasicInfo(ctx, qotcommon.QotMarket_QotMarket_Unknown, qotcommon.SecurityType_SecurityType_Unknown, missing)
+		if err != nil {
+			return err
+		}
+		b.SetStatic(infos)
+	}
+	if missing := b.missingMults(); len(missing) > 0 {
+		infos, err := api.GetFutureInfoBatch(ctx, missing, nil)
+		b.SetFutureInfo(infos)
+		if err != nil {
+			return err
+		}
+	}
+	snapshots, err := api.GetMarketSnapshotBatch(ctx, b.Securities(), nil)
+	b.SetSnapshots(snapshots)
+	return err
+}
+
+// 从推送通道读取成交和基本行情，实时更新持仓和价格；新持仓的证券会通过 api 获取静态信息和快照，api 为空时不获取。
+// 调用方需要订阅账户成交推送和持仓证券的基本行情，不需要的通道传 nil。
+// ctx 结束时返回 ErrInterrupted，通道全部关闭时返回 ErrChannelClosed。
+func (b *Book) Run(ctx context.Context, api *futuapi.FutuAPI, deals <-chan *futuapi.UpdateDealResp, qots <-chan *futuapi.UpdateBasicQotResp) error {
+	for deals != nil || qots != nil {
+		select {
+		case <-ctx.Done():
+			return futuapi.ErrInterrupted
+		case resp, ok := <-deals:
+			if !ok {
+				deals = nil
+				continue
+			}
+			if resp.Err == nil && b.ApplyFill(resp.Header, resp.OrderFill) && api != nil {
+				if err := b.refresh(ctx, api); err == futuapi.ErrInterrupted {
+					return err
+				}
+			}
+		case resp, ok := <-qots:
+			if !ok {
+				qots = nil
+				continue
+			}
+			if resp.Err == nil {
+				b.UpdateQuotes(resp.BasicQot)
+			}
+		}
+	}
+	return futuapi.ErrChannelClosed
+}
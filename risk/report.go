@@ -0,0 +1,200 @@
+package risk
+
+import (
+	"sort"
+	"time"
+
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+	"github.com/woxinyoumeng/go-futu-api/pricing"
+)
+
+// 一个标的（或总体）的风险敞口，期权按每份合约的股数、期货按合约乘数折算
+type Exposure struct {
+	Owner      *futuapi.Security //标的，总体为空
+	Spot       float64           //标的价格
+	Value      float64           //持仓市值
+	Delta      float64           //相当于标的股数，总体不适用
+	DeltaValue float64           //Delta 乘以标的价格
+	Gamma      float64           //标的价格变化1时 Delta 的变化，总体不适用
+	GammaValue float64           //标的价格变化1%时 DeltaValue 的变化
+	Vega       float64           //波动率变化1个百分点的损益
+	Theta      float64           //每个自然日的损益
+}
+
+func (e *Exposure) add(o *Exposure) {
+	e.Value += o.Value
+	e.DeltaValue += o.DeltaValue
+	e.GammaValue += o.GammaValue
+	e.Vega += o.Vega
+	e.Theta += o.Theta
+}
+
+// 风险报告
+type Report struct {
+	Time      time.Time
+	Exposures []*Exposure         //按标的代码排序
+	Total     Exposure            //所有标的合计
+	Missing   []*futuapi.Security //缺少静态信息、价格、期货合约乘数或无法求出隐含波动率的持仓，不计入敞口
+}
+
+// 一个持仓的定价数据
+type leg struct {
+	owner  futuapi.Security
+	spot   float64
+	qty    float64 //持仓数量乘以每份合约的股数或期货合约乘数
+	price  float64
+	option bool
+	model  pricing.Model
+	params pricing.Params //期权定价参数，Vol 为隐含波动率
+}
+
+// 当前持仓的定价数据，按标的分组
+func (b *Book) legs(now time.Time) (map[futuapi.Security][]*leg, []*futuapi.Security) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	groups := make(map[futuapi.Security][]*leg)
+	var missing []*futuapi.Security
+	for sec, qty := range b.qty {
+		sec := sec
+		l := b.leg(sec, qty, now)
+		if l == nil {
+			missing = append(missing, &sec)
+			continue
+		}
+		groups[l.owner] = append(groups[l.owner], l)
+	}
+	sort.Slice(missing, func(i, j int) bool { return missing[i].String() < missing[j].String() })
+	return groups, missing
+}
+
+func (b *Book) leg(sec futuapi.Security, qty float64, now time.Time) *leg {
+	price := b.prices[sec]
+	if b.static[sec] == nil || price <= 0 {
+		return nil
+	}
+	if b.missingMult(sec) {
+		return nil
+	}
+	if m := b.mults[sec]; m > 0 {
+		qty *= m
+	}
+	q := b.options[sec]
+	if q == nil {
+		return &leg{owner: sec, spot: price, qty: qty, price: price}
+	}
+	owner := q.Info.OptionExData.Owner
+	if owner == nil || b.prices[*owner] <= 0 {
+		return nil
+	}
+	spot := b.prices[*owner]
+	params, err := pricing.ParamsFor(q, spot, now, b.cfg)
+	if err != nil {
+		return nil
+	}
+	model := pricing.ModelFor(q, b.cfg)
+	vol, err := pricing.ImpliedVol(model, price, params)
+	if err != nil {
+		if vol = b.ivs[sec]; vol <= 0 {
+			return nil
+		}
+	}
+	params.Vol = vol
+	return &leg{
+		owner:  *owner,
+		spot:   spot,
+		qty:    qty * pricing.ContractSize(q),
+		price:  price,
+		option: true,
+		model:  model,
+		params: params,
+	}
+}
+
+// 计算 now 时刻的风险敞口
+func (b *Book) Risk(now time.Time) *Report {
+	groups, missing := b.legs(now)
+	r := &Report{Time: now, Missing: missing}
+	for owner, legs := range groups {
+		owner := owner
+		e := &Exposure{Owner: &owner, Spot: legs[0].spot}
+		for _, l := range legs {
+			e.Value += l.qty * l.price
+			if !l.option {
+				e.Delta += l.qty
+				continue
+			}
+			g := l.model.Greeks(l.params).Scale(l.qty)
+			e.Delta += g.Delta
+			e.Gamma += g.Gamma
+			e.Vega += g.Vega
+			e.Theta += g.Theta
+		}
+		e.DeltaValue = e.Delta * e.Spot
+		e.GammaValue = e.Gamma * e.Spot * e.Spot / 100
+		r.Exposures = append(r.Exposures, e)
+		r.Total.add(e)
+	}
+	sort.Slice(r.Exposures, func(i, j int) bool { return r.Exposures[i].Owner.String() < r.Exposures[j].Owner.String() })
+	return r
+}
+
+// 情景分析结果，PnL[i][j] 为标的价格冲击 SpotShocks[i]、波动率冲击 VolShocks[j] 时的损益
+type Scenario struct {
+	Time       time.Time
+	SpotShocks []float64           //标的价格相对变化，0.05 表示上涨5%
+	VolShocks  []float64           //波动率绝对变化，0.05 表示上升5个百分点
+	Owners     []*futuapi.Security //标的，按代码排序
+	PnL        [][][]float64       //每个标的的损益矩阵，与 Owners 对应
+	Total      [][]float64         //所有标的合计的损益矩阵
+	Missing    []*futuapi.Security //不计入的持仓
+}
+
+// 对所有标的施加相同的价格和波动率冲击，期权按原模型完全重新定价，计算相对当前价格的损益
+func (b *Book) Scenario(now time.Time, spotShocks []float64, volShocks []float64) *Scenario {
+	groups, missing := b.legs(now)
+	s := &Scenario{Time: now, SpotShocks: spotShocks, VolShocks: volShocks, Missing: missing}
+	for owner := range groups {
+		owner := owner
+		s.Owners = append(s.Owners, &owner)
+	}
+	sort.Slice(s.Owners, func(i, j int) bool { return s.Owners[i].String() < s.Owners[j].String() })
+	s.Total = matrix(len(spotShocks), len(volShocks))
+	for _, owner := range s.Owners {
+		pnl := matrix(len(spotShocks), len(volShocks))
+		for _, l := range groups[*owner] {
+			var base float64
+			if l.option {
+				base = l.model.Price(l.params)
+			}
+			for i, ds := range spotShocks {
+				for j, dv := range volShocks {
+					if !l.option {
+						pnl[i][j] += l.qty * l.price * ds
+						continue
+					}
+					p := l.params
+					p.Spot *= 1 + ds
+					if p.Vol += dv; p.Vol <= 0 {
+						p.Vol = 1e-4
+					}
+					pnl[i][j] += l.qty * (l.model.Price(p) - base)
+				}
+			}
+		}
+		for i := range pnl {
+			for j := range pnl[i] {
+				s.Total[i][j] += pnl[i][j]
+			}
+		}
+		s.PnL = append(s.PnL, pnl)
+	}
+	return s
+}
+
+func matrix(rows, cols int) [][]float64 {
+	m := make([][]float64, rows)
+	for i := range m {
+		m[i] = make([]float64, cols)
+	}
+	return m
+}
@@ -0,0 +1,135 @@
+package risk
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/hurisheng/go-futu-api/pb/qotcommon"
+	"github.com/hurisheng/go-futu-api/pb/trdcommon"
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+	"github.com/woxinyoumeng/go-futu-api/pricing"
+)
+
+func TestBook(t *testing.T) {
+	stock := &futuapi.Security{Market: qotcommon.QotMarket_QotMarket_US_Security, Code: "XYZ"}
+	call := &futuapi.Security{Market: qotcommon.QotMarket_QotMarket_US_Security, Code: "XYZ240621C100000"}
+	now := time.Date(2024, 3, 20, 10, 0, 0, 0, stock.Location())
+
+	b := New(&futuapi.TrdHeader{AccID: 1}, &pricing.Config{Rate: 0.05})
+	b.SetPositions([]*futuapi.Position{
+		{Code: "XYZ", SecMarket: trdcommon.TrdSecMarket_TrdSecMarket_US, Qty: 300},
+		{Code: call.Code, SecMarket: trdcommon.TrdSecMarket_TrdSecMarket_US, Qty: 2, PositionSide: trdcommon.PositionSide_PositionSide_Short},
+	})
+	b.SetStatic([]*futuapi.SecurityStaticInfo{
+		{Basic: &futuapi.SecurityStaticBasic{Security: stock, LotSize: 1, SecType: qotcommon.SecurityType_SecurityType_Eqty}},
+		{
+			Basic:        &futuapi.SecurityStaticBasic{Security: call, LotSize: 100, SecType: qotcommon.SecurityType_SecurityType_Drvt},
+			OptionExData: &futuapi.OptionStaticExData{Type: qotcommon.OptionType_OptionType_Call, Owner: stock, StrikeTime: "2024-06-21", StrikePrice: 100},
+		},
+	})
+	b.UpdateQuotes([]*futuapi.BasicQot{{Security: stock, CurPrice: 100}, {Security: call, CurPrice: 5}})
+
+	r := b.Risk(now)
+	if len(r.Exposures) != 1 || len(r.Missing) != 0 {
+		t.Fatalf("report %+v", r)
+	}
+	e := r.Exposures[0]
+	if *e.Owner != *stock || e.Value != 300*100-200*5 {
+		t.Errorf("exposure %+v", e)
+	}
+	// 卖出看涨期权：Delta 小于正股数量，Gamma、Vega 为负，Theta 为正
+	if e.Delta >= 300 || e.Delta <= 100 || e.Gamma >= 0 || e.Vega >= 0 || e.Theta <= 0 {
+		t.Errorf("greeks %+v", e)
+	}
+	if r.Total.DeltaValue != e.DeltaValue || r.Total.Vega != e.Vega {
+		t.Errorf("total %+v", r.Total)
+	}
+
+	s := b.Scenario(now, []float64{-0.1, 0, 0.1}, []float64{-0.05, 0, 0.05})
+	if math.Abs(s.Total[1][1]) > 1e-9 {
+		t.Errorf("unshocked pnl = %v", s.Total[1][1])
+	}
+	if s.Total[1][2] >= 0 || s.Total[2][1] <= 0 || s.Total[0][1] >= 0 {
+		t.Errorf("scenario %v", s.Total)
+	}
+
+	// 其他账户的成交忽略；买回期权后只剩正股
+	b.ApplyFill(&futuapi.TrdHeader{AccID: 2}, &futuapi.OrderFill{Code: call.Code, SecMarket: trdcommon.TrdSecMarket_TrdSecMarket_US, Qty: 2, TrdSide: trdcommon.TrdSide_TrdSide_BuyBack})
+	if missing := b.ApplyFill(&futuapi.TrdHeader{AccID: 1}, &futuapi.OrderFill{Code: call.Code, SecMarket: trdcommon.TrdSecMarket_TrdSecMarket_US, Qty: 2, TrdSide: trdcommon.TrdSide_TrdSide_BuyBack}); missing {
+		t.Error("static info reported missing")
+	}
+	if r := b.Risk(now); r.Exposures[0].Delta != 300 || r.Exposures[0].Vega != 0 {
+		t.Errorf("after fill %+v", r.Exposures[0])
+	}
+	if missing := b.ApplyFill(nil, &futuapi.OrderFill{Code: "ABC", SecMarket: trdcommon.TrdSecMarket_TrdSecMarket_US, Qty: 1, TrdSide: trdcommon.TrdSide_TrdSide_Buy}); !missing {
+		t.Error("new security not reported missing")
+	}
+	if r := b.Risk(now); len(r.Missing) != 1 || r.Missing[0].Code != "ABC" {
+		t.Errorf("missing %v", r.Missing)
+	}
+}
+
+func TestApplyFillChangedCancelled(t *testing.T) {
+	b := New(&futuapi.TrdHeader{AccID: 1}, nil)
+	sec := futuapi.Security{Market: qotcommon.QotMarket_QotMarket_US_Security, Code: "ABC"}
+	fill := func(id uint64, qty float64, side trdcommon.TrdSide, status trdcommon.OrderFillStatus) {
+		b.ApplyFill(nil, &futuapi.OrderFill{FillID: id, Code: sec.Code, SecMarket: trdcommon.TrdSecMarket_TrdSecMarket_US,
+			Qty: qty, Price: 10, TrdSide: side, Status: status})
+	}
+	buy, sell := trdcommon.TrdSide_TrdSide_Buy, trdcommon.TrdSide_TrdSide_Sell
+	ok := trdcommon.OrderFillStatus_OrderFillStatus_OK
+	fill(1, 100, buy, ok)
+	fill(1, 100, buy, ok) // 重复推送
+	fill(2, 30, sell, ok)
+	if q := b.qty[sec]; q != 70 {
+		t.Fatalf("qty %v, want 70", q)
+	}
+	// 成交被更改只计入差额
+	fill(1, 120, buy, trdcommon.OrderFillStatus_OrderFillStatus_Changed)
+	if q := b.qty[sec]; q != 90 {
+		t.Errorf("after changed fill qty %v, want 90", q)
+	}
+	// 成交被取消时冲回
+	fill(2, 30, sell, trdcommon.OrderFillStatus_OrderFillStatus_Cancelled)
+	if q := b.qty[sec]; q != 120 {
+		t.Errorf("after cancelled sell qty %v, want 120", q)
+	}
+	fill(1, 120, buy, trdcommon.OrderFillStatus_OrderFillStatus_Cancelled)
+	if q, ok := b.qty[sec]; ok {
+		t.Errorf("after cancelled buy qty %v, want none", q)
+	}
+	// 替换持仓后重新记录成交
+	fill(3, 50, buy, ok)
+	b.SetPositions([]*futuapi.Position{{Code: sec.Code, SecMarket: trdcommon.TrdSecMarket_TrdSecMarket_US, Qty: 50}})
+	if len(b.fills) != 0 {
+		t.Errorf("fills %v after SetPositions", b.fills)
+	}
+	fill(4, 10, buy, ok)
+	if q := b.qty[sec]; q != 60 {
+		t.Errorf("after SetPositions qty %v, want 60", q)
+	}
+}
+
+func TestBookFuture(t *testing.T) {
+	fut := &futuapi.Security{Market: qotcommon.QotMarket_QotMarket_HK_Security, Code: "HSI2406"}
+	b := New(nil, nil)
+	b.SetPositions([]*futuapi.Position{{Code: fut.Code, SecMarket: trdcommon.TrdSecMarket_TrdSecMarket_HK, Qty: 2}})
+	b.SetStatic([]*futuapi.SecurityStaticInfo{
+		{Basic: &futuapi.SecurityStaticBasic{Security: fut, LotSize: 1, SecType: qotcommon.SecurityType_SecurityType_Future}},
+	})
+	b.UpdateQuotes([]*futuapi.BasicQot{{Security: fut, CurPrice: 17000}})
+	// 没有合约乘数时不计入敞口
+	if r := b.Risk(time.Now()); len(r.Exposures) != 0 || len(r.Missing) != 1 || len(b.missingMults()) != 1 {
+		t.Fatalf("report %+v", r)
+	}
+	b.SetFutureInfo([]*futuapi.FutureInfo{{Security: fut, ContractSize: 50}})
+	if r := b.Risk(time.Now()); len(r.Exposures) != 1 || r.Exposures[0].Value != 2*50*17000 || r.Exposures[0].Delta != 100 {
+		t.Errorf("report %+v", r)
+	}
+	b.SetMultiplier(fut, 10)
+	s := b.Scenario(time.Now(), []float64{0.01}, []float64{0})
+	if math.Abs(s.Total[0][0]-2*10*170) > 1e-6 {
+		t.Errorf("scenario %v", s.Total)
+	}
+}
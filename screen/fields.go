@@ -0,0 +1,130 @@
+package screen
+
+import (
+	"github.com/hurisheng/go-futu-api/pb/qotstockfilter"
+)
+
+// 简单属性字段名
+var baseFields = map[string]qotstockfilter.StockField{
+	"cur_price":              qotstockfilter.StockField_StockField_CurPrice,
+	"cur_price_to_high52w":   qotstockfilter.StockField_StockField_CurPriceToHighest52WeeksRatio,
+	"cur_price_to_low52w":    qotstockfilter.StockField_StockField_CurPriceToLowest52WeeksRatio,
+	"high_price_to_high52w":  qotstockfilter.StockField_StockField_HighPriceToHighest52WeeksRatio,
+	"low_price_to_low52w":    qotstockfilter.StockField_StockField_LowPriceToLowest52WeeksRatio,
+	"volume_ratio":           qotstockfilter.StockField_StockField_VolumeRatio,
+	"bid_ask_ratio":          qotstockfilter.StockField_StockField_BidAskRatio,
+	"lot_price":              qotstockfilter.StockField_StockField_LotPrice,
+	"market_val":             qotstockfilter.StockField_StockField_MarketVal,
+	"pe_annual":              qotstockfilter.StockField_StockField_PeAnnual,
+	"pe_ttm":                 qotstockfilter.StockField_StockField_PeTTM,
+	"pb_rate":                qotstockfilter.StockField_StockField_PbRate,
+	"change_rate_5min":       qotstockfilter.StockField_StockField_ChangeRate5min,
+	"change_rate_begin_year": qotstockfilter.StockField_StockField_ChangeRateBeginYear,
+	"ps_ttm":                 qotstockfilter.StockField_StockField_PSTTM,
+	"pcf_ttm":                qotstockfilter.StockField_StockField_PCFTTM,
+	"total_share":            qotstockfilter.StockField_StockField_TotalShare,
+	"float_share":            qotstockfilter.StockField_StockField_FloatShare,
+	"float_market_val":       qotstockfilter.StockField_StockField_FloatMarketVal,
+}
+
+// 累积属性字段名，需要指定天数，如 change_rate(5)
+var accumulateFields = map[string]qotstockfilter.AccumulateField{
+	"change_rate":   qotstockfilter.AccumulateField_AccumulateField_ChangeRate,
+	"amplitude":     qotstockfilter.AccumulateField_AccumulateField_Amplitude,
+	"volume":        qotstockfilter.AccumulateField_AccumulateField_Volume,
+	"turnover":      qotstockfilter.AccumulateField_AccumulateField_Turnover,
+	"turnover_rate": qotstockfilter.AccumulateField_AccumulateField_TurnoverRate,
+}
+
+// 财务属性字段名，可以指定财报期，如 net_profit_growth(annual)
+var financialFields = map[string]qotstockfilter.FinancialField{
+	"net_profit":                         qotstockfilter.FinancialField_FinancialField_NetProfit,
+	"net_profit_growth":                  qotstockfilter.FinancialField_FinancialField_NetProfitGrowth,
+	"sum_of_business":                    qotstockfilter.FinancialField_FinancialField_SumOfBusiness,
+	"sum_of_business_growth":             qotstockfilter.FinancialField_FinancialField_SumOfBusinessGrowth,
+	"net_profit_rate":                    qotstockfilter.FinancialField_FinancialField_NetProfitRate,
+	"gross_profit_rate":                  qotstockfilter.FinancialField_FinancialField_GrossProfitRate,
+	"debt_assets_rate":                   qotstockfilter.FinancialField_FinancialField_DebtAssetsRate,
+	"return_on_equity_rate":              qotstockfilter.FinancialField_FinancialField_ReturnOnEquityRate,
+	"roic":                               qotstockfilter.FinancialField_FinancialField_ROIC,
+	"roa_ttm":                            qotstockfilter.FinancialField_FinancialField_ROATTM,
+	"ebit_ttm":                           qotstockfilter.FinancialField_FinancialField_EBITTTM,
+	"ebitda":                             qotstockfilter.FinancialField_FinancialField_EBITDA,
+	"operating_margin_ttm":               qotstockfilter.FinancialField_FinancialField_OperatingMarginTTM,
+	"ebit_margin":                        qotstockfilter.FinancialField_FinancialField_EBITMargin,
+	"ebitda_margin":                      qotstockfilter.FinancialField_FinancialField_EBITDAMargin,
+	"financial_cost_rate":                qotstockfilter.FinancialField_FinancialField_FinancialCostRate,
+	"operating_profit_ttm":               qotstockfilter.FinancialField_FinancialField_OperatingProfitTTM,
+	"shareholder_net_profit_ttm":         qotstockfilter.FinancialField_FinancialField_ShareholderNetProfitTTM,
+	"net_profit_cash_cover_ttm":          qotstockfilter.FinancialField_FinancialField_NetProfitCashCoverTTM,
+	"current_ratio":                      qotstockfilter.FinancialField_FinancialField_CurrentRatio,
+	"quick_ratio":                        qotstockfilter.FinancialField_FinancialField_QuickRatio,
+	"current_asset_ratio":                qotstockfilter.FinancialField_FinancialField_CurrentAssetRatio,
+	"current_debt_ratio":                 qotstockfilter.FinancialField_FinancialField_CurrentDebtRatio,
+	"equity_multiplier":                  qotstockfilter.FinancialField_FinancialField_EquityMultiplier,
+	"property_ratio":                     qotstockfilter.FinancialField_FinancialField_PropertyRatio,
+	"cash_and_cash_equivalents":          qotstockfilter.FinancialField_FinancialField_CashAndCashEquivalents,
+	"total_asset_turnover":               qotstockfilter.FinancialField_FinancialField_TotalAssetTurnover,
+	"fixed_asset_turnover":               qotstockfilter.FinancialField_FinancialField_FixedAssetTurnover,
+	"inventory_turnover":                 qotstockfilter.FinancialField_FinancialField_InventoryTurnover,
+	"operating_cash_flow_ttm":            qotstockfilter.FinancialField_FinancialField_OperatingCashFlowTTM,
+	"accounts_receivable":                qotstockfilter.FinancialField_FinancialField_AccountsReceivable,
+	"ebit_growth_rate":                   qotstockfilter.FinancialField_FinancialField_EBITGrowthRate,
+	"operating_profit_growth_rate":       qotstockfilter.FinancialField_FinancialField_OperatingProfitGrowthRate,
+	"total_assets_growth_rate":           qotstockfilter.FinancialField_FinancialField_TotalAssetsGrowthRate,
+	"profit_to_shareholders_growth_rate": qotstockfilter.FinancialField_FinancialField_ProfitToShareholdersGrowthRate,
+	"profit_before_tax_growth_rate":      qotstockfilter.FinancialField_FinancialField_ProfitBeforeTaxGrowthRate,
+	"eps_growth_rate":                    qotstockfilter.FinancialField_FinancialField_EPSGrowthRate,
+	"roe_growth_rate":                    qotstockfilter.FinancialField_FinancialField_ROEGrowthRate,
+	"roic_growth_rate":                   qotstockfilter.FinancialField_FinancialField_ROICGrowthRate,
+	"nocf_growth_rate":                   qotstockfilter.FinancialField_FinancialField_NOCFGrowthRate,
+	"nocf_per_share_growth_rate":         qotstockfilter.FinancialField_FinancialField_NOCFPerShareGrowthRate,
+	"operating_revenue_cash_cover":       qotstockfilter.FinancialField_FinancialField_OperatingRevenueCashCover,
+	"operating_profit_to_total_profit":   qotstockfilter.FinancialField_FinancialField_OperatingProfitToTotalProfit,
+	"basic_eps":                          qotstockfilter.FinancialField_FinancialField_BasicEPS,
+	"diluted_eps":                        qotstockfilter.FinancialField_FinancialField_DilutedEPS,
+	"nocf_per_share":                     qotstockfilter.FinancialField_FinancialField_NOCFPerShare,
+}
+
+// 常用别名
+var aliases = map[string]string{
+	"pe":  "pe_ttm",
+	"pb":  "pb_rate",
+	"ps":  "ps_ttm",
+	"pcf": "pcf_ttm",
+	"ytd": "change_rate_begin_year",
+	"roe": "return_on_equity_rate",
+	"eps": "basic_eps",
+}
+
+// 财报期
+var quarters = map[string]qotstockfilter.FinancialQuarter{
+	"annual":  qotstockfilter.FinancialQuarter_FinancialQuarter_Annual,
+	"q1":      qotstockfilter.FinancialQuarter_FinancialQuarter_FirstQuarter,
+	"interim": qotstockfilter.FinancialQuarter_FinancialQuarter_Interim,
+	"q3":      qotstockfilter.FinancialQuarter_FinancialQuarter_ThirdQuarter,
+	"recent":  qotstockfilter.FinancialQuarter_FinancialQuarter_MostRecentQuarter,
+}
+
+// 按枚举值查找名称，用于 Format
+var (
+	baseNames       = make(map[qotstockfilter.StockField]string)
+	accumulateNames = make(map[qotstockfilter.AccumulateField]string)
+	financialNames  = make(map[qotstockfilter.FinancialField]string)
+	quarterNames    = make(map[qotstockfilter.FinancialQuarter]string)
+)
+
+func init() {
+	for n, f := range baseFields {
+		baseNames[f] = n
+	}
+	for n, f := range accumulateFields {
+		accumulateNames[f] = n
+	}
+	for n, f := range financialFields {
+		financialNames[f] = n
+	}
+	for n, q := range quarters {
+		quarterNames[q] = n
+	}
+}
@@ -0,0 +1,102 @@
+package screen
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokOp     // > >= < <= =
+	tokLParen // (
+	tokRParen // )
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int // 在表达式中的字节位置，从0开始
+}
+
+func (t token) String() string {
+	if t.kind == tokEOF {
+		return "end of expression"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// 表达式错误，Pos 为出错位置（从0开始的字节偏移）
+type Error struct {
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("screen: position %d: %s", e.Pos, e.Msg)
+}
+
+func errorf(pos int, format string, args ...interface{}) *Error {
+	return &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// 分词，标识符可以包含点，用于 HK.BK1001 这样的板块代码
+func lex(src string) ([]token, error) {
+	var list []token
+	rs := []rune(src)
+	offset := func(i int) int {
+		return len(string(rs[:i]))
+	}
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			list = append(list, token{tokLParen, "(", offset(i)})
+			i++
+		case r == ')':
+			list = append(list, token{tokRParen, ")", offset(i)})
+			i++
+		case r == '>' || r == '<' || r == '=':
+			j := i + 1
+			if r != '=' && j < len(rs) && rs[j] == '=' {
+				j++
+			}
+			list = append(list, token{tokOp, string(rs[i:j]), offset(i)})
+			i = j
+		case isDigit(r) || ((r == '-' || r == '+' || r == '.') && i+1 < len(rs) && (isDigit(rs[i+1]) || rs[i+1] == '.')):
+			j := i + 1
+			for j < len(rs) && (isDigit(rs[j]) || rs[j] == '.' || rs[j] == 'e' || rs[j] == 'E' ||
+				((rs[j] == '-' || rs[j] == '+') && (rs[j-1] == 'e' || rs[j-1] == 'E'))) {
+				j++
+			}
+			list = append(list, token{tokNumber, string(rs[i:j]), offset(i)})
+			i = j
+		case r == '_' || unicode.IsLetter(r):
+			j := i + 1
+			for j < len(rs) && (rs[j] == '_' || rs[j] == '.' || unicode.IsLetter(rs[j]) || isDigit(rs[j])) {
+				j++
+			}
+			list = append(list, token{tokIdent, string(rs[i:j]), offset(i)})
+			i = j
+		default:
+			return nil, errorf(offset(i), "unexpected character %q", r)
+		}
+	}
+	list = append(list, token{tokEOF, "", len(src)})
+	return list, nil
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+// 关键字不区分大小写
+func isKeyword(t token, word string) bool {
+	return t.kind == tokIdent && strings.EqualFold(t.text, word)
+}
@@ -0,0 +1,400 @@
+// Package screen 把条件选股的文本表达式编译为 StockFilter，例如：
+//
+//	market_val > 1e10 and pe between 5 and 20 and change_rate(5) > 3
+//	and roe(annual) >= 15 and plate = HK.LIST1910 sort market_val desc
+//
+// 语法：
+//
+//	query  = [cond {"and" cond}] {"sort" ["by"] term ("asc"|"desc") | "quarter" name}
+//	cond   = term op number | term "between" number "and" number | "plate" "=" code
+//	term   = field | field "(" days ")" | field "(" quarter ")"
+//	op     = ">" | ">=" | "<" | "<=" | "="
+//
+// 简单属性直接使用字段名；累积属性需要指定天数，如 change_rate(5)；财务属性可以指定财报期
+// annual、q1、interim、q3、recent，没有指定时使用 quarter 子句的财报期，默认为 annual。
+// 接口只支持闭区间，> 与 >= 相同，< 与 <= 相同，= 表示上下限相同。同一字段的多个条件取交集。
+// 关键字、字段名不区分大小写。
+package screen
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/hurisheng/go-futu-api/pb/qotstockfilter"
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+)
+
+type fieldKind int
+
+const (
+	kindBase fieldKind = iota
+	kindAccumulate
+	kindFinancial
+)
+
+// 条件对应的字段
+type term struct {
+	kind       fieldKind
+	name       string
+	pos        int
+	base       qotstockfilter.StockField
+	accumulate qotstockfilter.AccumulateField
+	financial  qotstockfilter.FinancialField
+	days       int32
+	quarter    qotstockfilter.FinancialQuarter
+}
+
+// 同一字段、天数、财报期的条件合并为一个过滤器
+type termKey struct {
+	kind    fieldKind
+	field   int32
+	days    int32
+	quarter qotstockfilter.FinancialQuarter
+}
+
+func (t *term) key() termKey {
+	k := termKey{kind: t.kind, days: t.days, quarter: t.quarter}
+	switch t.kind {
+	case kindBase:
+		k.field = int32(t.base)
+	case kindAccumulate:
+		k.field = int32(t.accumulate)
+	case kindFinancial:
+		k.field = int32(t.financial)
+	}
+	return k
+}
+
+type condition struct {
+	term     term
+	min, max *float64
+	noFilter bool
+	sortDir  qotstockfilter.SortDir
+}
+
+type parser struct {
+	tokens []token
+	i      int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *parser) keyword(word string) bool {
+	if isKeyword(p.peek(), word) {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expectKeyword(word string) error {
+	if t := p.next(); !isKeyword(t, word) {
+		return errorf(t.pos, "expected %q, found %v", word, t)
+	}
+	return nil
+}
+
+func (p *parser) number() (float64, error) {
+	t := p.next()
+	if t.kind != tokNumber {
+		return 0, errorf(t.pos, "expected number, found %v", t)
+	}
+	v, err := strconv.ParseFloat(t.text, 64)
+	if err != nil {
+		return 0, errorf(t.pos, "invalid number %q", t.text)
+	}
+	return v, nil
+}
+
+func (p *parser) term() (term, error) {
+	t := p.next()
+	if t.kind != tokIdent {
+		return term{}, errorf(t.pos, "expected field, found %v", t)
+	}
+	name := strings.ToLower(t.text)
+	if a, ok := aliases[name]; ok {
+		name = a
+	}
+	tm := term{name: name, pos: t.pos}
+	if f, ok := baseFields[name]; ok {
+		tm.kind, tm.base = kindBase, f
+	} else if f, ok := accumulateFields[name]; ok {
+		tm.kind, tm.accumulate = kindAccumulate, f
+	} else if f, ok := financialFields[name]; ok {
+		tm.kind, tm.financial = kindFinancial, f
+	} else {
+		return term{}, errorf(t.pos, "unknown field %q", t.text)
+	}
+	if p.peek().kind != tokLParen {
+		if tm.kind == kindAccumulate {
+			return term{}, errorf(t.pos, "field %q requires days, e.g. %s(5)", name, name)
+		}
+		return tm, nil
+	}
+	p.next()
+	arg := p.next()
+	switch tm.kind {
+	case kindBase:
+		return term{}, errorf(arg.pos, "field %q takes no argument", name)
+	case kindAccumulate:
+		days, err := strconv.Atoi(arg.text)
+		if arg.kind != tokNumber || err != nil || days <= 0 {
+			return term{}, errorf(arg.pos, "expected positive number of days, found %v", arg)
+		}
+		tm.days = int32(days)
+	case kindFinancial:
+		q, ok := quarters[strings.ToLower(arg.text)]
+		if arg.kind != tokIdent || !ok {
+			return term{}, errorf(arg.pos, "expected quarter (annual, q1, interim, q3, recent), found %v", arg)
+		}
+		tm.quarter = q
+	}
+	if r := p.next(); r.kind != tokRParen {
+		return term{}, errorf(r.pos, "expected \")\", found %v", r)
+	}
+	return tm, nil
+}
+
+// 编译表达式，表达式为空时返回没有条件的 StockFilter
+func Compile(src string) (*futuapi.StockFilter, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	filter := &futuapi.StockFilter{}
+	var conds []*condition
+	var sortTerm *term
+	var sortDir qotstockfilter.SortDir
+	quarter := qotstockfilter.FinancialQuarter_FinancialQuarter_Annual
+
+	first := true
+	for p.peek().kind != tokEOF && !isKeyword(p.peek(), "sort") && !isKeyword(p.peek(), "quarter") {
+		if !first {
+			if err := p.expectKeyword("and"); err != nil {
+				return nil, err
+			}
+		}
+		first = false
+		if p.keyword("plate") {
+			if t := p.next(); t.kind != tokOp || t.text != "=" {
+				return nil, errorf(t.pos, "expected \"=\", found %v", t)
+			}
+			t := p.next()
+			sec, err := futuapi.ParseSecurity(t.text)
+			if t.kind != tokIdent || err != nil {
+				return nil, errorf(t.pos, "expected plate code such as HK.LIST1910, found %v", t)
+			}
+			if filter.Plate != nil {
+				return nil, errorf(t.pos, "plate specified more than once")
+			}
+			filter.Plate = sec
+			continue
+		}
+		tm, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		c := &condition{term: tm}
+		if p.keyword("between") {
+			lo, err := p.number()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectKeyword("and"); err != nil {
+				return nil, err
+			}
+			hi, err := p.number()
+			if err != nil {
+				return nil, err
+			}
+			c.min, c.max = &lo, &hi
+		} else {
+			op := p.next()
+			if op.kind != tokOp {
+				return nil, errorf(op.pos, "expected comparison or \"between\", found %v", op)
+			}
+			v, err := p.number()
+			if err != nil {
+				return nil, err
+			}
+			switch op.text {
+			case ">", ">=":
+				c.min = &v
+			case "<", "<=":
+				c.max = &v
+			case "=":
+				c.min, c.max = &v, &v
+			}
+		}
+		conds = append(conds, c)
+	}
+	for p.peek().kind != tokEOF {
+		switch t := p.next(); {
+		case isKeyword(t, "sort"):
+			if sortTerm != nil {
+				return nil, errorf(t.pos, "only one sort field is supported")
+			}
+			p.keyword("by")
+			tm, err := p.term()
+			if err != nil {
+				return nil, err
+			}
+			sortTerm = &tm
+			switch d := p.next(); {
+			case isKeyword(d, "asc"):
+				sortDir = qotstockfilter.SortDir_SortDir_Ascend
+			case isKeyword(d, "desc"):
+				sortDir = qotstockfilter.SortDir_SortDir_Descend
+			default:
+				return nil, errorf(d.pos, "expected \"asc\" or \"desc\", found %v", d)
+			}
+		case isKeyword(t, "quarter"):
+			q := p.next()
+			v, ok := quarters[strings.ToLower(q.text)]
+			if q.kind != tokIdent || !ok {
+				return nil, errorf(q.pos, "expected quarter (annual, q1, interim, q3, recent), found %v", q)
+			}
+			quarter = v
+		default:
+			return nil, errorf(t.pos, "unexpected %v", t)
+		}
+	}
+
+	// 财务属性使用默认财报期，合并相同字段的条件
+	merged := make(map[termKey]*condition)
+	var order []termKey
+	for _, c := range conds {
+		if c.term.kind == kindFinancial && c.term.quarter == 0 {
+			c.term.quarter = quarter
+		}
+		k := c.term.key()
+		m, ok := merged[k]
+		if !ok {
+			merged[k] = c
+			order = append(order, k)
+			continue
+		}
+		if c.min != nil && (m.min == nil || *c.min > *m.min) {
+			m.min = c.min
+		}
+		if c.max != nil && (m.max == nil || *c.max < *m.max) {
+			m.max = c.max
+		}
+	}
+	for _, k := range order {
+		if c := merged[k]; c.min != nil && c.max != nil && *c.min > *c.max {
+			return nil, errorf(c.term.pos, "empty range for %q: %v > %v", c.term.name, *c.min, *c.max)
+		}
+	}
+	if sortTerm != nil {
+		if sortTerm.kind == kindFinancial && sortTerm.quarter == 0 {
+			sortTerm.quarter = quarter
+		}
+		k := sortTerm.key()
+		c, ok := merged[k]
+		if !ok {
+			c = &condition{term: *sortTerm, noFilter: true}
+			merged[k] = c
+			order = append(order, k)
+		}
+		c.sortDir = sortDir
+	}
+
+	for _, k := range order {
+		c := merged[k]
+		switch c.term.kind {
+		case kindBase:
+			filter.BaseFilterList = append(filter.BaseFilterList, &futuapi.BaseFilter{
+				FieldName: c.term.base, FilterMin: double(c.min), FilterMax: double(c.max), IsNoFilter: c.noFilter, SortDir: c.sortDir,
+			})
+		case kindAccumulate:
+			filter.AccumulateFilterList = append(filter.AccumulateFilterList, &futuapi.AccumulateFilter{
+				FieldName: c.term.accumulate, FilterMin: double(c.min), FilterMax: double(c.max), IsNoFilter: c.noFilter, SortDir: c.sortDir, Days: c.term.days,
+			})
+		case kindFinancial:
+			filter.FinancialFilterList = append(filter.FinancialFilterList, &futuapi.FinancialFilter{
+				FiledName: c.term.financial, FilterMin: double(c.min), FilterMax: double(c.max), IsNoFilter: c.noFilter, SortDir: c.sortDir, Quarter: c.term.quarter,
+			})
+		}
+	}
+	return filter, nil
+}
+
+func double(v *float64) *futuapi.FilterDouble {
+	if v == nil {
+		return nil
+	}
+	return &futuapi.FilterDouble{Value: *v}
+}
+
+// 把 StockFilter 格式化为表达式，便于记录和审阅，Compile(Format(f)) 得到等价的条件
+func Format(f *futuapi.StockFilter) string {
+	if f == nil {
+		return ""
+	}
+	var conds []string
+	var sortBy string
+	add := func(name string, min, max *futuapi.FilterDouble, noFilter bool, dir qotstockfilter.SortDir) {
+		if !noFilter {
+			switch {
+			case min != nil && max != nil && min.Value == max.Value:
+				conds = append(conds, name+" = "+formatNumber(min.Value))
+			case min != nil && max != nil:
+				conds = append(conds, name+" between "+formatNumber(min.Value)+" and "+formatNumber(max.Value))
+			case min != nil:
+				conds = append(conds, name+" >= "+formatNumber(min.Value))
+			case max != nil:
+				conds = append(conds, name+" <= "+formatNumber(max.Value))
+			}
+		}
+		switch dir {
+		case qotstockfilter.SortDir_SortDir_Ascend:
+			sortBy = "sort " + name + " asc"
+		case qotstockfilter.SortDir_SortDir_Descend:
+			sortBy = "sort " + name + " desc"
+		}
+	}
+	for _, b := range f.BaseFilterList {
+		if b != nil {
+			add(baseNames[b.FieldName], b.FilterMin, b.FilterMax, b.IsNoFilter, b.SortDir)
+		}
+	}
+	for _, a := range f.AccumulateFilterList {
+		if a != nil {
+			name := accumulateNames[a.FieldName] + "(" + strconv.Itoa(int(a.Days)) + ")"
+			add(name, a.FilterMin, a.FilterMax, a.IsNoFilter, a.SortDir)
+		}
+	}
+	for _, fin := range f.FinancialFilterList {
+		if fin != nil {
+			name := financialNames[fin.FiledName] + "(" + quarterNames[fin.Quarter] + ")"
+			add(name, fin.FilterMin, fin.FilterMax, fin.IsNoFilter, fin.SortDir)
+		}
+	}
+	if f.Plate != nil {
+		conds = append(conds, "plate = "+f.Plate.String())
+	}
+	s := strings.Join(conds, " and ")
+	if sortBy != "" {
+		if s != "" {
+			s += " "
+		}
+		s += sortBy
+	}
+	return s
+}
+
+func formatNumber(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,78 @@
+package screen
+
+import (
+	"testing"
+
+	"github.com/hurisheng/go-futu-api/pb/qotstockfilter"
+)
+
+func TestCompile(t *testing.T) {
+	f, err := Compile("market_val > 1e10 and PE between 5 and 20 and change_rate(5) > 3 and pe < 15 " +
+		"and roe >= 15 and plate = HK.LIST1910 sort turnover_rate(1) desc quarter recent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.BaseFilterList) != 2 || len(f.AccumulateFilterList) != 2 || len(f.FinancialFilterList) != 1 {
+		t.Fatalf("unexpected filter %+v", f)
+	}
+	if pe := f.BaseFilterList[1]; pe.FieldName != qotstockfilter.StockField_StockField_PeTTM || pe.FilterMin.Value != 5 || pe.FilterMax.Value != 15 {
+		t.Errorf("pe = %+v", pe)
+	}
+	if s := f.AccumulateFilterList[1]; !s.IsNoFilter || s.SortDir != qotstockfilter.SortDir_SortDir_Descend || s.Days != 1 {
+		t.Errorf("sort = %+v", s)
+	}
+	if q := f.FinancialFilterList[0].Quarter; q != qotstockfilter.FinancialQuarter_FinancialQuarter_MostRecentQuarter {
+		t.Errorf("quarter = %v", q)
+	}
+	if f.Plate == nil || f.Plate.Code != "LIST1910" {
+		t.Errorf("plate = %v", f.Plate)
+	}
+
+	want := "market_val >= 1e+10 and pe_ttm between 5 and 15 and change_rate(5) >= 3 and " +
+		"return_on_equity_rate(recent) >= 15 and plate = HK.LIST1910 sort turnover_rate(1) desc"
+	if got := Format(f); got != want {
+		t.Errorf("Format = %q", got)
+	}
+	if g, err := Compile(want); err != nil || Format(g) != want {
+		t.Errorf("round trip = %q, %v", Format(g), err)
+	}
+}
+
+func TestCompileOps(t *testing.T) {
+	// 接口只支持闭区间，严格比较按非严格比较编译
+	for src, want := range map[string]string{
+		"pe > 5":  "pe_ttm >= 5",
+		"pe >= 5": "pe_ttm >= 5",
+		"pe < 5":  "pe_ttm <= 5",
+		"pe <= 5": "pe_ttm <= 5",
+		"pe = 5":  "pe_ttm = 5",
+	} {
+		f, err := Compile(src)
+		if err != nil {
+			t.Errorf("%q: %v", src, err)
+			continue
+		}
+		if got := Format(f); got != want {
+			t.Errorf("%q: Format = %q, want %q", src, got, want)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for src, pos := range map[string]int{
+		"foo > 1":                 0,
+		"change_rate > 1":         0,
+		"pe > 1 or pb < 2":        7,
+		"pe between 20 and 5":     0,
+		"net_profit(q2) > 1":      11,
+		"pe > 1 sort pe sideways": 15,
+		"pe > $":                  5,
+		"pe > 1, pb < 2":          6,
+	} {
+		_, err := Compile(src)
+		e, ok := err.(*Error)
+		if !ok || e.Pos != pos {
+			t.Errorf("%q: error %v, want position %v", src, err, pos)
+		}
+	}
+}
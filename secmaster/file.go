@@ -0,0 +1,70 @@
+package secmaster
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"time"
+
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+)
+
+// 文件格式版本
+const fileVersion = 1
+
+// 保存到文件的数据，证券代码按 Python SDK 格式编码
+type fileData struct {
+	Version int       `json:"version"`
+	Updated time.Time `json:"updated"`
+	Entries []*Entry  `json:"entries"`
+	Plates  []*Plate  `json:"plates"`
+}
+
+// 以 JSON 格式保存到文件，先写临时文件再替换，避免写入中断损坏原文件
+func (m *Master) Save(path string) error {
+	data := fileData{
+		Version: fileVersion,
+		Updated: m.Updated(),
+		Entries: m.Filter(func(*Entry) bool { return true }),
+		Plates:  m.Plates(),
+	}
+	b, err := json.Marshal(&data)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// 从文件读取，文件不存在时返回空的证券主数据
+func Open(path string) (*Master, error) {
+	m := New()
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var data fileData
+	if err := json.Unmarshal(b, &data); err != nil {
+		return nil, err
+	}
+	entries := make(map[futuapi.Security]*Entry, len(data.Entries))
+	for _, e := range data.Entries {
+		if e != nil && e.Info != nil && e.Info.Basic != nil && e.Info.Basic.Security != nil {
+			entries[*e.Security()] = e
+		}
+	}
+	plates := make(map[futuapi.Security]*Plate, len(data.Plates))
+	for _, p := range data.Plates {
+		if p != nil && p.Info != nil && p.Info.Plate != nil {
+			plates[*p.Info.Plate] = p
+		}
+	}
+	m.replace(data.Updated, entries, plates)
+	return m, nil
+}
@@ -0,0 +1,335 @@
+// Package secmaster 在本地缓存证券主数据：证券静态信息、板块和板块成分，
+// 支持按代码查找、按名称模糊搜索、每手数量、上市退市、窝轮和期权的标的关联，以及板块成分查询。
+// 数据通过 GetStockBasicInfo、GetPlateList、GetPlateStock、GetOwnerPlate 获取，可以保存到磁盘并定时刷新。
+package secmaster
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hurisheng/go-futu-api/pb/qotcommon"
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+)
+
+// 证券
+type Entry struct {
+	Info   *futuapi.SecurityStaticInfo //静态信息
+	Plates []*futuapi.Security         //所属板块
+}
+
+func (e *Entry) Security() *futuapi.Security {
+	return e.Info.Basic.Security
+}
+
+func (e *Entry) Name() string {
+	return e.Info.Basic.Name
+}
+
+// 每手数量，期权表示一份合约的股数
+func (e *Entry) LotSize() int32 {
+	return e.Info.Basic.LotSize
+}
+
+func (e *Entry) SecType() qotcommon.SecurityType {
+	return e.Info.Basic.SecType
+}
+
+// 是否退市
+func (e *Entry) Delisting() bool {
+	return e.Info.Basic.Delisting
+}
+
+// 上市日期，按证券所在交易所的时区
+func (e *Entry) ListTime() time.Time {
+	t, _ := futuapi.ParseTime(e.Info.Basic.ListTime, e.Security().Location())
+	return t
+}
+
+// 窝轮、期权的标的，其他证券为空
+func (e *Entry) Owner() *futuapi.Security {
+	switch {
+	case e.Info.WarrantExData != nil:
+		return e.Info.WarrantExData.Owner
+	case e.Info.OptionExData != nil:
+		return e.Info.OptionExData.Owner
+	}
+	return nil
+}
+
+// 板块
+type Plate struct {
+	Info    *futuapi.PlateInfo  //板块信息
+	Members []*futuapi.Security //成分证券
+}
+
+// 证券主数据，可并发使用
+type Master struct {
+	mu      sync.RWMutex
+	updated time.Time
+	entries map[futuapi.Security]*Entry
+	plates  map[futuapi.Security]*Plate
+	owners  map[futuapi.Security][]*futuapi.Security
+}
+
+// 创建空的证券主数据
+func New() *Master {
+	m := &Master{}
+	m.replace(time.Time{}, nil, nil)
+	return m
+}
+
+// 用新数据替换全部内容并重建索引
+func (m *Master) replace(updated time.Time, entries map[futuapi.Security]*Entry, plates map[futuapi.Security]*Plate) {
+	if entries == nil {
+		entries = make(map[futuapi.Security]*Entry)
+	}
+	if plates == nil {
+		plates = make(map[futuapi.Security]*Plate)
+	}
+	owners := make(map[futuapi.Security][]*futuapi.Security)
+	for sec, e := range entries {
+		if o := e.Owner(); o != nil {
+			c := sec
+			owners[*o] = append(owners[*o], &c)
+		}
+	}
+	for _, list := range owners {
+		sortSecurities(list)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.updated, m.entries, m.plates, m.owners = updated, entries, plates, owners
+}
+
+// 最后一次刷新的时间
+func (m *Master) Updated() time.Time {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.updated
+}
+
+// 证券数量
+func (m *Master) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.entries)
+}
+
+// 按证券查找，不存在时返回空
+func (m *Master) Get(sec *futuapi.Security) *Entry {
+	if sec == nil {
+		return nil
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.entries[*sec]
+}
+
+// 按 Python SDK 格式的代码查找，如 HK.00700
+func (m *Master) Lookup(code string) *Entry {
+	sec, err := futuapi.ParseSecurity(code)
+	if err != nil {
+		return nil
+	}
+	return m.Get(sec)
+}
+
+// 按代码在所有市场查找，不区分大小写
+func (m *Master) ByCode(code string) []*Entry {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var list []*Entry
+	for sec, e := range m.entries {
+		if strings.EqualFold(sec.Code, code) {
+			list = append(list, e)
+		}
+	}
+	sortEntries(list)
+	return list
+}
+
+// 每手数量，证券不存在时返回0
+func (m *Master) LotSize(sec *futuapi.Security) int32 {
+	if e := m.Get(sec); e != nil {
+		return e.LotSize()
+	}
+	return 0
+}
+
+// 满足条件的证券，按市场和代码排序
+func (m *Master) Filter(match func(*Entry) bool) []*Entry {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var list []*Entry
+	for _, e := range m.entries {
+		if match(e) {
+			list = append(list, e)
+		}
+	}
+	sortEntries(list)
+	return list
+}
+
+// 市场中指定类型的证券，delisting 表示是否包含已退市证券
+func (m *Master) List(market qotcommon.QotMarket, secType qotcommon.SecurityType, delisting bool) []*Entry {
+	return m.Filter(func(e *Entry) bool {
+		return e.Security().Market == market && e.SecType() == secType && (delisting || !e.Delisting())
+	})
+}
+
+// 上市日期在 [begin, end) 之间的证券
+func (m *Master) ListedBetween(begin time.Time, end time.Time) []*Entry {
+	return m.Filter(func(e *Entry) bool {
+		t := e.ListTime()
+		return !t.IsZero() && !t.Before(begin) && t.Before(end)
+	})
+}
+
+// 已退市的证券
+func (m *Master) Delisted() []*Entry {
+	return m.Filter((*Entry).Delisting)
+}
+
+// 以 owner 为标的的窝轮和期权
+func (m *Master) Derivatives(owner *futuapi.Security) []*Entry {
+	if owner == nil {
+		return nil
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	secs := m.owners[*owner]
+	list := make([]*Entry, 0, len(secs))
+	for _, sec := range secs {
+		list = append(list, m.entries[*sec])
+	}
+	return list
+}
+
+// 按板块查找
+func (m *Master) Plate(plate *futuapi.Security) *Plate {
+	if plate == nil {
+		return nil
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.plates[*plate]
+}
+
+// 所有板块，按市场和代码排序
+func (m *Master) Plates() []*Plate {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	list := make([]*Plate, 0, len(m.plates))
+	for _, p := range m.plates {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return lessSecurity(list[i].Info.Plate, list[j].Info.Plate)
+	})
+	return list
+}
+
+// 证券所属的板块
+func (m *Master) PlatesOf(sec *futuapi.Security) []*Plate {
+	e := m.Get(sec)
+	if e == nil {
+		return nil
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var list []*Plate
+	for _, p := range e.Plates {
+		if plate := m.plates[*p]; plate != nil {
+			list = append(list, plate)
+		}
+	}
+	return list
+}
+
+// 按名称或代码模糊搜索，不区分大小写，按匹配程度排序，最多返回 limit 个，limit 小于等于0表示不限制。
+// 匹配程度依次为：完全相同、前缀、包含、按顺序包含所有字符；已退市的证券排在后面。
+func (m *Master) Search(query string, limit int) []*Entry {
+	q := strings.ToLower(strings.TrimSpace(query))
+	if q == "" {
+		return nil
+	}
+	type hit struct {
+		e     *Entry
+		score int
+	}
+	var hits []hit
+	m.mu.RLock()
+	for sec, e := range m.entries {
+		score := match(q, strings.ToLower(e.Name()))
+		if s := match(q, strings.ToLower(sec.Code)); s >= 0 && (score < 0 || s < score) {
+			score = s
+		}
+		if score < 0 {
+			continue
+		}
+		if e.Delisting() {
+			score += 10
+		}
+		hits = append(hits, hit{e, score})
+	}
+	m.mu.RUnlock()
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].score != hits[j].score {
+			return hits[i].score < hits[j].score
+		}
+		return lessSecurity(hits[i].e.Security(), hits[j].e.Security())
+	})
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	list := make([]*Entry, len(hits))
+	for i, h := range hits {
+		list[i] = h.e
+	}
+	return list
+}
+
+// 匹配程度，越小越好，不匹配返回 -1
+func match(q, s string) int {
+	switch {
+	case s == q:
+		return 0
+	case strings.HasPrefix(s, q):
+		return 1
+	case strings.Contains(s, q):
+		return 2
+	}
+	rs := []rune(s)
+	i := 0
+	for _, r := range q {
+		for i < len(rs) && rs[i] != r {
+			i++
+		}
+		if i == len(rs) {
+			return -1
+		}
+		i++
+	}
+	return 3
+}
+
+func lessSecurity(a, b *futuapi.Security) bool {
+	if a.Market != b.Market {
+		return a.Market < b.Market
+	}
+	return a.Code < b.Code
+}
+
+func sortSecurities(list []*futuapi.Security) {
+	sort.Slice(list, func(i, j int) bool {
+		return lessSecurity(list[i], list[j])
+	})
+}
+
+func sortEntries(list []*Entry) {
+	sort.Slice(list, func(i, j int) bool {
+		return lessSecurity(list[i].Security(), list[j].Security())
+	})
+}
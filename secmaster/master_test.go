@@ -0,0 +1,77 @@
+package secmaster
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hurisheng/go-futu-api/pb/qotcommon"
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+)
+
+func info(code string, name string, secType qotcommon.SecurityType, owner *futuapi.Security) *futuapi.SecurityStaticInfo {
+	i := &futuapi.SecurityStaticInfo{Basic: &futuapi.SecurityStaticBasic{
+		Security: &futuapi.Security{Market: qotcommon.QotMarket_QotMarket_HK_Security, Code: code},
+		Name:     name,
+		LotSize:  100,
+		SecType:  secType,
+		ListTime: "2004-06-16",
+	}}
+	if owner != nil {
+		i.WarrantExData = &futuapi.WarrantStaticExData{Owner: owner}
+	}
+	return i
+}
+
+func TestMaster(t *testing.T) {
+	tencent := &futuapi.Security{Market: qotcommon.QotMarket_QotMarket_HK_Security, Code: "00700"}
+	plate := &futuapi.Security{Market: qotcommon.QotMarket_QotMarket_HK_Security, Code: "LIST1001"}
+	entries := map[futuapi.Security]*Entry{
+		*tencent:                                {Info: info("00700", "腾讯控股", qotcommon.SecurityType_SecurityType_Eqty, nil), Plates: []*futuapi.Security{plate}},
+		{Market: tencent.Market, Code: "12345"}: {Info: info("12345", "腾讯摩通六甲购A", qotcommon.SecurityType_SecurityType_Warrant, tencent)},
+		{Market: tencent.Market, Code: "09988"}: {Info: info("09988", "阿里巴巴-SW", qotcommon.SecurityType_SecurityType_Eqty, nil)},
+	}
+	plates := map[futuapi.Security]*Plate{
+		*plate: {Info: &futuapi.PlateInfo{Plate: plate, Name: "互联网"}, Members: []*futuapi.Security{tencent}},
+	}
+	m := New()
+	m.replace(m.Updated(), entries, plates)
+
+	check := func(m *Master) {
+		if got := m.Search("腾讯", 0); len(got) != 2 || got[0].Security().Code != "00700" {
+			t.Errorf("Search = %v", got)
+		}
+		if got := m.Search("阿巴", 0); len(got) != 1 {
+			t.Errorf("fuzzy Search = %v", got)
+		}
+		if got := m.Derivatives(tencent); len(got) != 1 || got[0].Security().Code != "12345" {
+			t.Errorf("Derivatives = %v", got)
+		}
+		if got := m.PlatesOf(tencent); len(got) != 1 || got[0].Info.Name != "互联网" {
+			t.Errorf("PlatesOf = %v", got)
+		}
+		if e := m.Lookup("HK.00700"); e == nil || e.LotSize() != 100 || e.ListTime().Year() != 2004 {
+			t.Errorf("Lookup = %v", e)
+		}
+	}
+	check(m)
+
+	dir, err := ioutil.TempDir("", "secmaster")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "master.json")
+	if err := m.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Len() != 3 {
+		t.Fatalf("Len = %v", loaded.Len())
+	}
+	check(loaded)
+}
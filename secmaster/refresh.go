@@ -0,0 +1,192 @@
+package secmaster
+
+import (
+	"context"
+	"time"
+
+	"github.com/hurisheng/go-futu-api/pb/qotcommon"
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+)
+
+// 刷新参数
+type Options struct {
+	Markets      []qotcommon.QotMarket    //市场
+	SecTypes     []qotcommon.SecurityType //证券类型
+	PlateClasses []qotcommon.PlateSetType //板块集合，为空时不获取板块
+	OwnerPlates  bool                     //是否通过 GetOwnerPlate 获取正股所属的全部板块，请求次数较多
+	OnError      func(error)              //定时刷新出错时的回调
+}
+
+// 默认参数：港股、美股、沪深市场的正股、基金、窝轮、指数、债券，以及所有板块
+func DefaultOptions() *Options {
+	return &Options{
+		Markets: []qotcommon.QotMarket{
+			qotcommon.QotMarket_QotMarket_HK_Security,
+			qotcommon.QotMarket_QotMarket_US_Security,
+			qotcommon.QotMarket_QotMarket_CNSH_Security,
+			qotcommon.QotMarket_QotMarket_CNSZ_Security,
+		},
+		SecTypes: []qotcommon.SecurityType{
+			qotcommon.SecurityType_SecurityType_Eqty,
+			qotcommon.SecurityType_SecurityType_Trust,
+			qotcommon.SecurityType_SecurityType_Warrant,
+			qotcommon.SecurityType_SecurityType_Index,
+			qotcommon.SecurityType_SecurityType_Bond,
+		},
+		PlateClasses: []qotcommon.PlateSetType{qotcommon.PlateSetType_PlateSetType_All},
+	}
+}
+
+// 从接口重新获取全部数据，成功后整体替换，失败时保留原数据。接口调用遵守频率限制。
+func (m *Master) Refresh(ctx context.Context, api *futuapi.FutuAPI, opts *Options) error {
+	if opts == nil {
+		opts = DefaultOptions()
+	}
+	entries := make(map[futuapi.Security]*Entry)
+	plates := make(map[futuapi.Security]*Plate)
+	for _, market := range opts.Markets {
+		for _, secType := range opts.SecTypes {
+			if err := api.WaitRateLimit(ctx, futuapi.ProtoIDQotGetStaticInfo); err != nil {
+				return err
+			}
+			list, err := api.GetStockBasicInfo(ctx, market, secType, nil)
+			if err != nil {
+				return err
+			}
+			for _, info := range list {
+				if info != nil && info.Basic != nil && info.Basic.Security != nil {
+					entries[*info.Basic.Security] = &Entry{Info: info}
+				}
+			}
+		}
+		for _, class := range opts.PlateClasses {
+			if err := loadPlates(ctx, api, market, class, entries, plates); err != nil {
+				return err
+			}
+		}
+	}
+	if opts.OwnerPlates {
+		if err := loadOwnerPlates(ctx, api, entries, plates); err != nil {
+			return err
+		}
+	}
+	m.replace(time.Now(), entries, plates)
+	return nil
+}
+
+// 获取板块集合下的板块及成分
+func loadPlates(ctx context.Context, api *futuapi.FutuAPI, market qotcommon.QotMarket, class qotcommon.PlateSetType,
+	entries map[futuapi.Security]*Entry, plates map[futuapi.Security]*Plate) error {
+	if err := api.WaitRateLimit(ctx, futuapi.ProtoIDQotGetPlateSet); err != nil {
+		return err
+	}
+	list, err := api.GetPlateList(ctx, market, class)
+	if err != nil {
+		return err
+	}
+	for _, info := range list {
+		if info == nil || info.Plate == nil || plates[*info.Plate] != nil {
+			continue
+		}
+		if info.PlateType == qotcommon.PlateSetType_PlateSetType_All {
+			info.PlateType = class
+		}
+		if err := api.WaitRateLimit(ctx, futuapi.ProtoIDQotGetPlateSecurity); err != nil {
+			return err
+		}
+		members, err := api.GetPlateStock(ctx, info.Plate, qotcommon.SortField_SortField_Code, true)
+		if err != nil {
+			return err
+		}
+		p := &Plate{Info: info}
+		for _, s := range members {
+			if s == nil || s.Basic == nil || s.Basic.Security == nil {
+				continue
+			}
+			sec := s.Basic.Security
+			p.Members = append(p.Members, sec)
+			if e := entries[*sec]; e != nil {
+				e.Plates = appendPlate(e.Plates, info.Plate)
+			}
+		}
+		plates[*info.Plate] = p
+	}
+	return nil
+}
+
+// 获取正股所属的板块
+func loadOwnerPlates(ctx context.Context, api *futuapi.FutuAPI, entries map[futuapi.Security]*Entry, plates map[futuapi.Security]*Plate) error {
+	var secs []*futuapi.Security
+	for sec, e := range entries {
+		if e.SecType() == qotcommon.SecurityType_SecurityType_Eqty && !e.Delisting() {
+			c := sec
+			secs = append(secs, &c)
+		}
+	}
+	sortSecurities(secs)
+	list, err := api.GetOwnerPlateBatch(ctx, secs, nil)
+	if err != nil {
+		return err
+	}
+	for _, o := range list {
+		if o == nil || o.Security == nil {
+			continue
+		}
+		e := entries[*o.Security]
+		for _, info := range o.PlateInfos {
+			if info == nil || info.Plate == nil {
+				continue
+			}
+			p := plates[*info.Plate]
+			if p == nil {
+				p = &Plate{Info: info}
+				plates[*info.Plate] = p
+			}
+			if e != nil && !containsSecurity(e.Plates, info.Plate) {
+				e.Plates = append(e.Plates, info.Plate)
+				p.Members = append(p.Members, o.Security)
+			}
+		}
+	}
+	return nil
+}
+
+func containsSecurity(list []*futuapi.Security, sec *futuapi.Security) bool {
+	for _, s := range list {
+		if *s == *sec {
+			return true
+		}
+	}
+	return false
+}
+
+func appendPlate(list []*futuapi.Security, plate *futuapi.Security) []*futuapi.Security {
+	if containsSecurity(list, plate) {
+		return list
+	}
+	return append(list, plate)
+}
+
+// 立即刷新一次，之后每隔 interval 刷新，path 不为空时每次刷新成功后保存到文件。
+// 刷新出错时调用 OnError 并在下一个周期重试，ctx 结束时返回 ErrInterrupted。
+func (m *Master) Run(ctx context.Context, api *futuapi.FutuAPI, opts *Options, interval time.Duration, path string) error {
+	if opts == nil {
+		opts = DefaultOptions()
+	}
+	timer := time.NewTicker(interval)
+	defer timer.Stop()
+	for {
+		err := m.Refresh(ctx, api, opts)
+		if err == nil && path != "" {
+			err = m.Save(path)
+		}
+		if err != nil && opts.OnError != nil && ctx.Err() == nil {
+			opts.OnError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return futuapi.ErrInterrupted
+		case <-timer.C:
+		}
+	}
+}
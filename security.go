@@ -0,0 +1,160 @@
+package futuapi
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/hurisheng/go-futu-api/pb/qotcommon"
+	"github.com/hurisheng/go-futu-api/pb/trdcommon"
+)
+
+var ErrSecurityFormat = errors.New("security format error")
+
+// 市场前缀，与 Python SDK 一致
+var marketPrefixes = []struct {
+	market qotcommon.QotMarket
+	prefix string
+}{
+	{qotcommon.QotMarket_QotMarket_HK_Security, "HK"},
+	{qotcommon.QotMarket_QotMarket_HK_Future, "HK_FUTURE"},
+	{qotcommon.QotMarket_QotMarket_US_Security, "US"},
+	{qotcommon.QotMarket_QotMarket_CNSH_Security, "SH"},
+	{qotcommon.QotMarket_QotMarket_CNSZ_Security, "SZ"},
+	{qotcommon.QotMarket_QotMarket_SG_Security, "SG"},
+	{qotcommon.QotMarket_QotMarket_JP_Security, "JP"},
+}
+
+// 解析 Python SDK 格式的证券代码，如 HK.00700、US.AAPL、SH.600519，市场前缀不区分大小写。
+// 前缀也可以是 QotMarket 的数值，用于没有前缀的市场，如 String 输出的 0.XXX
+func ParseSecurity(s string) (*Security, error) {
+	i := strings.IndexByte(s, '.')
+	if i <= 0 || i == len(s)-1 {
+		return nil, ErrSecurityFormat
+	}
+	prefix := strings.ToUpper(s[:i])
+	for _, p := range marketPrefixes {
+		if p.prefix == prefix {
+			return &Security{Market: p.market, Code: s[i+1:]}, nil
+		}
+	}
+	if n, err := strconv.ParseInt(prefix, 10, 32); err == nil {
+		return &Security{Market: qotcommon.QotMarket(n), Code: s[i+1:]}, nil
+	}
+	return nil, ErrSecurityFormat
+}
+
+// 解析多个证券代码，遇到错误时返回
+func ParseSecurities(list ...string) ([]*Security, error) {
+	securities := make([]*Security, len(list))
+	for i, s := range list {
+		sec, err := ParseSecurity(s)
+		if err != nil {
+			return nil, err
+		}
+		securities[i] = sec
+	}
+	return securities, nil
+}
+
+// Python SDK 格式的证券代码，没有前缀的市场以 QotMarket 的数值为前缀，可以由 ParseSecurity 解析
+func (s Security) String() string {
+	for _, p := range marketPrefixes {
+		if p.market == s.Market {
+			return p.prefix + "." + s.Code
+		}
+	}
+	return strconv.Itoa(int(s.Market)) + "." + s.Code
+}
+
+// 实现 encoding.TextMarshaler，JSON 中编码为 String 的格式，包括作为结构体字段和 map 键时
+func (s Security) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// 实现 encoding.TextUnmarshaler
+func (s *Security) UnmarshalText(text []byte) error {
+	sec, err := ParseSecurity(string(text))
+	if err != nil {
+		return err
+	}
+	*s = *sec
+	return nil
+}
+
+// 行情市场对应的交易证券市场
+func QotMarketToTrdSecMarket(market qotcommon.QotMarket) trdcommon.TrdSecMarket {
+	switch market {
+	case qotcommon.QotMarket_QotMarket_HK_Security, qotcommon.QotMarket_QotMarket_HK_Future:
+		return trdcommon.TrdSecMarket_TrdSecMarket_HK
+	case qotcommon.QotMarket_QotMarket_US_Security:
+		return trdcommon.TrdSecMarket_TrdSecMarket_US
+	case qotcommon.QotMarket_QotMarket_CNSH_Security:
+		return trdcommon.TrdSecMarket_TrdSecMarket_CN_SH
+	case qotcommon.QotMarket_QotMarket_CNSZ_Security:
+		return trdcommon.TrdSecMarket_TrdSecMarket_CN_SZ
+	case qotcommon.QotMarket_QotMarket_SG_Security:
+		return trdcommon.TrdSecMarket_TrdSecMarket_SG
+	case qotcommon.QotMarket_QotMarket_JP_Security:
+		return trdcommon.TrdSecMarket_TrdSecMarket_JP
+	}
+	return trdcommon.TrdSecMarket_TrdSecMarket_Unknown
+}
+
+// 交易证券市场对应的行情市场
+func TrdSecMarketToQotMarket(market trdcommon.TrdSecMarket) qotcommon.QotMarket {
+	switch market {
+	case trdcommon.TrdSecMarket_TrdSecMarket_HK:
+		return qotcommon.QotMarket_QotMarket_HK_Security
+	case trdcommon.TrdSecMarket_TrdSecMarket_US:
+		return qotcommon.QotMarket_QotMarket_US_Security
+	case trdcommon.TrdSecMarket_TrdSecMarket_CN_SH:
+		return qotcommon.QotMarket_QotMarket_CNSH_Security
+	case trdcommon.TrdSecMarket_TrdSecMarket_CN_SZ:
+		return qotcommon.QotMarket_QotMarket_CNSZ_Security
+	case trdcommon.TrdSecMarket_TrdSecMarket_SG:
+		return qotcommon.QotMarket_QotMarket_SG_Security
+	case trdcommon.TrdSecMarket_TrdSecMarket_JP:
+		return qotcommon.QotMarket_QotMarket_JP_Security
+	}
+	return qotcommon.QotMarket_QotMarket_Unknown
+}
+
+// 交易行情市场证券时使用的交易市场（账户所属市场）。
+// 沪深股票默认为A股市场，通过香港账户交易A股通时使用 TrdMarket_HKCC；新加坡、日本期货为期货市场。
+func QotMarketToTrdMarket(market qotcommon.QotMarket) trdcommon.TrdMarket {
+	switch market {
+	case qotcommon.QotMarket_QotMarket_HK_Security, qotcommon.QotMarket_QotMarket_HK_Future:
+		return trdcommon.TrdMarket_TrdMarket_HK
+	case qotcommon.QotMarket_QotMarket_US_Security:
+		return trdcommon.TrdMarket_TrdMarket_US
+	case qotcommon.QotMarket_QotMarket_CNSH_Security, qotcommon.QotMarket_QotMarket_CNSZ_Security:
+		return trdcommon.TrdMarket_TrdMarket_CN
+	case qotcommon.QotMarket_QotMarket_SG_Security, qotcommon.QotMarket_QotMarket_JP_Security:
+		return trdcommon.TrdMarket_TrdMarket_Futures
+	}
+	return trdcommon.TrdMarket_TrdMarket_Unknown
+}
+
+// 证券对应的交易证券市场，用于下单等交易接口
+func (s *Security) TrdSecMarket() trdcommon.TrdSecMarket {
+	if s == nil {
+		return trdcommon.TrdSecMarket_TrdSecMarket_Unknown
+	}
+	return QotMarketToTrdSecMarket(s.Market)
+}
+
+// 订单的证券
+func (o *Order) Security() *Security {
+	return &Security{Market: TrdSecMarketToQotMarket(o.SecMarket), Code: o.Code}
+}
+
+// 成交的证券
+func (f *OrderFill) Security() *Security {
+	return &Security{Market: TrdSecMarketToQotMarket(f.SecMarket), Code: f.Code}
+}
+
+// 持仓的证券
+func (p *Position) Security() *Security {
+	return &Security{Market: TrdSecMarketToQotMarket(p.SecMarket), Code: p.Code}
+}
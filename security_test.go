@@ -0,0 +1,41 @@
+package futuapi
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hurisheng/go-futu-api/pb/qotcommon"
+)
+
+func TestSecurityText(t *testing.T) {
+	sec, err := ParseSecurity("hk.00700")
+	if err != nil || *sec != (Security{Market: qotcommon.QotMarket_QotMarket_HK_Security, Code: "00700"}) {
+		t.Fatalf("ParseSecurity = %v, %v", sec, err)
+	}
+	if _, err := ParseSecurity("00700"); err != ErrSecurityFormat {
+		t.Errorf("expected format error, got %v", err)
+	}
+	b, err := json.Marshal(map[Security][]*Security{*sec: {{Market: qotcommon.QotMarket_QotMarket_US_Security, Code: "AAPL"}}})
+	if err != nil || string(b) != `{"HK.00700":["US.AAPL"]}` {
+		t.Fatalf("Marshal = %s, %v", b, err)
+	}
+	var m map[Security][]*Security
+	if err := json.Unmarshal(b, &m); err != nil || m[*sec][0].String() != "US.AAPL" {
+		t.Errorf("Unmarshal = %v, %v", m, err)
+	}
+}
+
+func TestSecurityUnknownMarket(t *testing.T) {
+	sec := Security{Market: qotcommon.QotMarket_QotMarket_Unknown, Code: "XYZ"}
+	b, err := json.Marshal(map[string]Security{"a": sec, "b": {Market: qotcommon.QotMarket(99), Code: "ABC"}})
+	if err != nil || string(b) != `{"a":"0.XYZ","b":"99.ABC"}` {
+		t.Fatalf("Marshal = %s, %v", b, err)
+	}
+	var m map[string]Security
+	if err := json.Unmarshal(b, &m); err != nil || m["a"] != sec || m["b"].Market != 99 {
+		t.Errorf("Unmarshal = %v, %v", m, err)
+	}
+	if _, err := ParseSecurity("X1.ABC"); err != ErrSecurityFormat {
+		t.Errorf("expected format error, got %v", err)
+	}
+}
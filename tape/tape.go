@@ -0,0 +1,302 @@
+// Package tape 统计逐笔成交：按证券和交易日计算成交量加权均价、分价成交量、
+// 按主动买卖方向的成交量、各逐笔类型的笔数，并识别大单。可以先用 GetRTTicker 的历史逐笔初始化，再接着处理推送。
+package tape
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hurisheng/go-futu-api/pb/qotcommon"
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+)
+
+// 分价成交量
+type PriceVolume struct {
+	Price  float64
+	Volume int64
+}
+
+// 一只证券一个交易日的逐笔统计
+type Stats struct {
+	Security  *futuapi.Security
+	Date      string //交易日，YYYY-MM-DD，按证券所在交易所的时区
+	First     time.Time
+	Last      time.Time
+	Count     int //逐笔笔数
+	Volume    int64
+	Turnover  float64
+	High      float64
+	Low       float64
+	LastPrice float64
+	Buy       int64                        //主动买入（外盘）成交量
+	Sell      int64                        //主动卖出（内盘）成交量
+	Neutral   int64                        //中性盘和方向未知的成交量
+	Types     map[qotcommon.TickerType]int //各逐笔类型的笔数
+	Large     []*LargeTrade                //大单
+	profile   map[float64]int64
+	pv        float64 //价格乘以成交量之和，成交额缺失时计算均价
+}
+
+// 成交量加权均价，没有成交时为0
+func (s *Stats) VWAP() float64 {
+	if s.Volume == 0 {
+		return 0
+	}
+	if s.Turnover > 0 {
+		return s.Turnover / float64(s.Volume)
+	}
+	return s.pv / float64(s.Volume)
+}
+
+// 分价成交量，按价格升序
+func (s *Stats) Profile() []PriceVolume {
+	list := make([]PriceVolume, 0, len(s.profile))
+	for p, v := range s.profile {
+		list = append(list, PriceVolume{Price: p, Volume: v})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Price < list[j].Price })
+	return list
+}
+
+// 成交量最大的价格，没有成交时为0
+func (s *Stats) POC() float64 {
+	var price float64
+	var volume int64
+	for _, pv := range s.Profile() {
+		if pv.Volume > volume {
+			price, volume = pv.Price, pv.Volume
+		}
+	}
+	return price
+}
+
+// 主动买卖成交量的差占主动成交量的比例，在 -1 到 1 之间，没有主动成交时为0
+func (s *Stats) Imbalance() float64 {
+	if s.Buy+s.Sell == 0 {
+		return 0
+	}
+	return float64(s.Buy-s.Sell) / float64(s.Buy+s.Sell)
+}
+
+func (s *Stats) clone() *Stats {
+	c := *s
+	c.Types = make(map[qotcommon.TickerType]int, len(s.Types))
+	for k, v := range s.Types {
+		c.Types[k] = v
+	}
+	c.profile = make(map[float64]int64, len(s.profile))
+	for k, v := range s.profile {
+		c.profile[k] = v
+	}
+	c.Large = append([]*LargeTrade(nil), s.Large...)
+	return &c
+}
+
+// 大单
+type LargeTrade struct {
+	Security *futuapi.Security
+	Ticker   *futuapi.Ticker
+	Time     time.Time
+}
+
+// 统计参数
+type Options struct {
+	MinVolume   int64   //成交量不小于该值为大单，0 为不按成交量判断
+	MinTurnover float64 //成交额不小于该值为大单，0 为不按成交额判断
+	Tick        float64 //分价成交量的价格间隔，0 为按成交价统计
+	Buffer      int     //大单通道的缓冲大小，0 为不发送大单
+}
+
+// 逐笔统计器，可同时处理多只证券
+type Analyzer struct {
+	mu    sync.RWMutex
+	opts  Options
+	stats map[futuapi.Security]map[string]*Stats
+	last  map[futuapi.Security]string //最新交易日
+	seq   map[futuapi.Security]int64  //已处理的最大逐笔序号
+	large chan *LargeTrade
+}
+
+// 创建逐笔统计器
+func New(opts *Options) *Analyzer {
+	a := &Analyzer{
+		stats: make(map[futuapi.Security]map[string]*Stats),
+		last:  make(map[futuapi.Security]string),
+		seq:   make(map[futuapi.Security]int64),
+	}
+	if opts != nil {
+		a.opts = *opts
+	}
+	if a.opts.Buffer > 0 {
+		a.large = make(chan *LargeTrade, a.opts.Buffer)
+	}
+	return a
+}
+
+// 大单通道，Buffer 为0时为空，通道满时丢弃
+func (a *Analyzer) LargeTrades() <-chan *LargeTrade {
+	return a.large
+}
+
+func (a *Analyzer) isLarge(t *futuapi.Ticker) bool {
+	return (a.opts.MinVolume > 0 && t.Volume >= a.opts.MinVolume) || (a.opts.MinTurnover > 0 && t.Turnover >= a.opts.MinTurnover)
+}
+
+// 处理逐笔数据，按序号排序后处理，序号不大于已处理序号的逐笔忽略，因此历史逐笔和推送可以重叠
+func (a *Analyzer) Add(rt *futuapi.RTTicker) {
+	if rt == nil || rt.Security == nil {
+		return
+	}
+	sec := *rt.Security
+	loc := rt.Security.Location()
+	var large []*LargeTrade
+	a.mu.Lock()
+	days := a.stats[sec]
+	if days == nil {
+		days = make(map[string]*Stats)
+		a.stats[sec] = days
+	}
+	// 同一批推送中的逐笔不一定按序号排列，先排序再按已处理序号去重
+	tickers := make([]*futuapi.Ticker, 0, len(rt.Tickers))
+	for _, t := range rt.Tickers {
+		if t != nil {
+			tickers = append(tickers, t)
+		}
+	}
+	sort.SliceStable(tickers, func(i, j int) bool { return tickers[i].Sequence < tickers[j].Sequence })
+	for _, t := range tickers {
+		if t.Sequence != 0 && t.Sequence <= a.seq[sec] {
+			continue
+		}
+		if t.Sequence > a.seq[sec] {
+			a.seq[sec] = t.Sequence
+		}
+		at := t.TimeIn(loc)
+		date := at.Format("2006-01-02")
+		s := days[date]
+		if s == nil {
+			s = &Stats{Security: rt.Security, Date: date, Types: make(map[qotcommon.TickerType]int), profile: make(map[float64]int64)}
+			days[date] = s
+		}
+		if date > a.last[sec] {
+			a.last[sec] = date
+		}
+		if s.Count == 0 || at.Before(s.First) {
+			s.First = at
+		}
+		if !at.Before(s.Last) {
+			s.Last = at
+			s.LastPrice = t.Price
+		}
+		if s.Count == 0 || t.Price > s.High {
+			s.High = t.Price
+		}
+		if s.Count == 0 || t.Price < s.Low {
+			s.Low = t.Price
+		}
+		s.Count++
+		s.Volume += t.Volume
+		s.Turnover += t.Turnover
+		s.pv += t.Price * float64(t.Volume)
+		switch t.Dir {
+		case qotcommon.TickerDirection_TickerDirection_Bid:
+			s.Buy += t.Volume
+		case qotcommon.TickerDirection_TickerDirection_Ask:
+			s.Sell += t.Volume
+		default:
+			s.Neutral += t.Volume
+		}
+		s.Types[t.Type]++
+		s.profile[a.bucket(t.Price)] += t.Volume
+		if a.isLarge(t) {
+			l := &LargeTrade{Security: rt.Security, Ticker: t, Time: at}
+			s.Large = append(s.Large, l)
+			large = append(large, l)
+		}
+	}
+	a.mu.Unlock()
+	if a.large != nil {
+		for _, l := range large {
+			select {
+			case a.large <- l:
+			default:
+			}
+		}
+	}
+}
+
+func (a *Analyzer) bucket(price float64) float64 {
+	if a.opts.Tick <= 0 {
+		return price
+	}
+	return math.Round(price/a.opts.Tick) * a.opts.Tick
+}
+
+// 证券最新交易日的统计，没有数据时返回空
+func (a *Analyzer) Stats(sec *futuapi.Security) *Stats {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if s := a.stats[*sec][a.last[*sec]]; s != nil {
+		return s.clone()
+	}
+	return nil
+}
+
+// 证券某一交易日的统计，没有数据时返回空
+func (a *Analyzer) Session(sec *futuapi.Security, date string) *Stats {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if s := a.stats[*sec][date]; s != nil {
+		return s.clone()
+	}
+	return nil
+}
+
+// 删除早于 date 的交易日的统计
+func (a *Analyzer) Prune(date string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, days := range a.stats {
+		for d := range days {
+			if d < date {
+				delete(days, d)
+			}
+		}
+	}
+}
+
+// 用 GetRTTicker 返回的最近 num 笔逐笔初始化。接口调用遵守频率限制。
+func (a *Analyzer) Seed(ctx context.Context, api *futuapi.FutuAPI, securities []*futuapi.Security, num int32) error {
+	for _, sec := range securities {
+		if err := api.WaitRateLimit(ctx, futuapi.ProtoIDQotGetTicker); err != nil {
+			return err
+		}
+		rt, err := api.GetRTTicker(ctx, sec, num)
+		if err != nil {
+			return err
+		}
+		a.Add(rt)
+	}
+	return nil
+}
+
+// 从推送通道读取逐笔，调用方需要订阅逐笔推送。
+// ctx 结束时返回 ErrInterrupted，通道关闭时返回 ErrChannelClosed。
+func (a *Analyzer) Run(ctx context.Context, ch <-chan *futuapi.UpdateTickerResp) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return futuapi.ErrInterrupted
+		case resp, ok := <-ch:
+			if !ok {
+				return futuapi.ErrChannelClosed
+			}
+			if resp.Err == nil {
+				a.Add(resp.Ticker)
+			}
+		}
+	}
+}
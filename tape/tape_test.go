@@ -0,0 +1,64 @@
+package tape
+
+import (
+	"math"
+	"testing"
+
+	"github.com/hurisheng/go-futu-api/pb/qotcommon"
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+)
+
+func TestAnalyzer(t *testing.T) {
+	sec := &futuapi.Security{Market: qotcommon.QotMarket_QotMarket_HK_Security, Code: "00700"}
+	tick := func(seq int64, tm string, price float64, vol int64, dir qotcommon.TickerDirection) *futuapi.Ticker {
+		return &futuapi.Ticker{Sequence: seq, Time: "2024-03-20 " + tm, Price: price, Volume: vol, Turnover: price * float64(vol), Dir: dir,
+			Type: qotcommon.TickerType_TickerType_Automatch}
+	}
+	a := New(&Options{MinVolume: 1000, Tick: 0.2, Buffer: 10})
+	a.Add(&futuapi.RTTicker{Security: sec, Tickers: []*futuapi.Ticker{
+		tick(1, "09:30:00", 300, 100, qotcommon.TickerDirection_TickerDirection_Bid),
+		tick(2, "09:30:01", 300.2, 1000, qotcommon.TickerDirection_TickerDirection_Ask),
+	}})
+	// 推送与历史重叠的逐笔只统计一次
+	a.Add(&futuapi.RTTicker{Security: sec, Tickers: []*futuapi.Ticker{
+		tick(2, "09:30:01", 300.2, 1000, qotcommon.TickerDirection_TickerDirection_Ask),
+		tick(3, "09:30:02", 300.1, 100, qotcommon.TickerDirection_TickerDirection_Neutral),
+	}})
+
+	s := a.Stats(sec)
+	if s == nil || s.Date != "2024-03-20" || s.Count != 3 || s.Volume != 1200 {
+		t.Fatalf("stats %+v", s)
+	}
+	if want := (300*100 + 300.2*1000 + 300.1*100) / 1200; math.Abs(s.VWAP()-want) > 1e-9 {
+		t.Errorf("vwap %v, want %v", s.VWAP(), want)
+	}
+	if s.Buy != 100 || s.Sell != 1000 || s.Neutral != 100 || s.Types[qotcommon.TickerType_TickerType_Automatch] != 3 {
+		t.Errorf("flow %+v", s)
+	}
+	if p := s.Profile(); len(p) != 2 || p[1].Volume != 1100 || math.Abs(s.POC()-300.2) > 1e-9 {
+		t.Errorf("profile %v", p)
+	}
+	if len(s.Large) != 1 || s.Large[0].Ticker.Sequence != 2 {
+		t.Errorf("large %v", s.Large)
+	}
+	if l := <-a.LargeTrades(); l.Ticker.Sequence != 2 {
+		t.Errorf("large trade %+v", l)
+	}
+}
+
+func TestAnalyzerUnordered(t *testing.T) {
+	sec := &futuapi.Security{Market: qotcommon.QotMarket_QotMarket_HK_Security, Code: "00700"}
+	tick := func(seq int64, tm string, price float64) *futuapi.Ticker {
+		return &futuapi.Ticker{Sequence: seq, Time: "2024-03-20 " + tm, Price: price, Volume: 100}
+	}
+	a := New(nil)
+	a.Add(&futuapi.RTTicker{Security: sec, Tickers: []*futuapi.Ticker{tick(1, "09:30:00", 300)}})
+	// 乱序的一批推送不丢弃较小序号的逐笔，重复的逐笔只统计一次
+	a.Add(&futuapi.RTTicker{Security: sec, Tickers: []*futuapi.Ticker{
+		tick(3, "09:30:02", 302), tick(2, "09:30:01", 301), nil, tick(3, "09:30:02", 302), tick(1, "09:30:00", 300),
+	}})
+	s := a.Stats(sec)
+	if s == nil || s.Count != 3 || s.Volume != 300 || s.LastPrice != 302 {
+		t.Fatalf("stats %+v", s)
+	}
+}
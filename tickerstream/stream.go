@@ -0,0 +1,222 @@
+// Package tickerstream 按 Ticker.Sequence 合并逐笔推送和 GetRTTicker 历史，
+// 去除重复并在发现缺口时自动补数，输出按序号排序、每笔只出现一次的逐笔流。
+package tickerstream
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/hurisheng/go-futu-api/pb/qotcommon"
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+)
+
+// GetRTTicker 最多返回的逐笔数
+const MaxBackfill = 1000
+
+// 补数后仍无法填补的缺口，作为输出中的 Err 发送
+type GapError struct {
+	Security *futuapi.Security
+	After    int64 //缺口前最后一笔的序号
+	Before   int64 //缺口后第一笔的序号
+}
+
+func (e *GapError) Error() string {
+	return fmt.Sprintf("tickerstream: %v gap between sequence %d and %d", e.Security, e.After, e.Before)
+}
+
+// 逐笔流参数。证券的第一批推送没有可比较的已输出序号，不会触发补数，
+// 推送之前的逐笔需要在订阅后、处理推送前调用 Resync 取回。
+type Options struct {
+	Contiguous bool  //序号是否连续，为 true 时序号不相邻即视为缺口，否则只在断线补充推送时补数
+	Backfill   int32 //补数时请求的逐笔数，0 为 MaxBackfill
+	Buffer     int   //输出通道的缓冲大小
+}
+
+// 逐笔流，可同时处理多只证券
+type Stream struct {
+	api     *futuapi.FutuAPI
+	opts    Options
+	proc    sync.Mutex                 //处理过程串行，保证输出顺序
+	mu      sync.Mutex                 //保护 last
+	last    map[futuapi.Security]int64 //已输出的最大序号
+	out     chan *futuapi.UpdateTickerResp
+	history func(ctx context.Context, sec *futuapi.Security) ([]*futuapi.Ticker, error)
+}
+
+// 创建逐笔流，api 用于补数
+func New(api *futuapi.FutuAPI, opts *Options) *Stream {
+	s := &Stream{api: api, last: make(map[futuapi.Security]int64)}
+	if opts != nil {
+		s.opts = *opts
+	}
+	if s.opts.Backfill <= 0 || s.opts.Backfill > MaxBackfill {
+		s.opts.Backfill = MaxBackfill
+	}
+	s.out = make(chan *futuapi.UpdateTickerResp, s.opts.Buffer)
+	s.history = s.fetch
+	return s
+}
+
+// 输出通道，与 UpdateTicker 的推送通道格式相同，可以直接交给 bar、tape 等组件处理。
+// 无法填补的缺口以 Err 为 *GapError 的数据发送。调用方需要及时读取，否则处理会阻塞。
+func (s *Stream) Tickers() <-chan *futuapi.UpdateTickerResp {
+	return s.out
+}
+
+// 证券已输出的最大序号，没有输出过时为0
+func (s *Stream) Last(sec *futuapi.Security) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.last[*sec]
+}
+
+// 获取证券最近的历史逐笔并输出尚未输出的部分，用于订阅后初始化或断线重连后补数。接口调用遵守频率限制。
+func (s *Stream) Resync(ctx context.Context, securities []*futuapi.Security) error {
+	s.proc.Lock()
+	defer s.proc.Unlock()
+	for _, sec := range securities {
+		history, err := s.history(ctx, sec)
+		if err != nil {
+			return err
+		}
+		after := s.Last(sec)
+		list, _ := s.fresh(history, after)
+		var gap *GapError
+		if after > 0 && len(list) > 0 && len(list) == len(history) && !s.adjacent(after, list[0].Sequence) {
+			// 历史数据最早一笔仍在已输出序号之后，中间的逐笔无法取回
+			gap = &GapError{Security: sec, After: after, Before: list[0].Sequence}
+		}
+		if err := s.emit(ctx, sec, list, gap); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 处理一批逐笔推送：去除已输出的逐笔，发现缺口时先补数，再按序号输出。
+// 补数失败时照常输出推送的逐笔，并在之前输出 *GapError。只在 ctx 结束时返回 ErrInterrupted。
+// 证券的第一批推送不补数，之前的逐笔由 Resync 取回。
+func (s *Stream) Process(ctx context.Context, rt *futuapi.RTTicker) error {
+	if rt == nil || rt.Security == nil {
+		return nil
+	}
+	sec := rt.Security
+	s.proc.Lock()
+	defer s.proc.Unlock()
+	after := s.Last(sec)
+	list, byDisConn := s.fresh(rt.Tickers, after)
+	if len(list) == 0 {
+		return nil
+	}
+	var gap *GapError
+	if after > 0 && (byDisConn || !s.adjacent(after, list[0].Sequence)) {
+		history, err := s.history(ctx, sec)
+		if err == futuapi.ErrInterrupted {
+			return err
+		}
+		var fill []*futuapi.Ticker
+		reached := false
+		for _, t := range history {
+			if t.Sequence <= after {
+				reached = true
+			} else if t.Sequence < list[0].Sequence {
+				fill = append(fill, t)
+			}
+		}
+		first := list[0].Sequence
+		if len(fill) > 0 {
+			first = fill[0].Sequence
+		}
+		if err != nil || (!reached && !s.adjacent(after, first)) {
+			// 补数失败，或历史数据没有覆盖到已输出的序号
+			gap = &GapError{Security: sec, After: after, Before: first}
+		}
+		list, _ = s.fresh(append(fill, list...), after)
+	}
+	return s.emit(ctx, sec, list, gap)
+}
+
+// 序号大于 after 的逐笔，去重后按序号排序，同时返回是否包含断线补充推送的数据
+func (s *Stream) fresh(tickers []*futuapi.Ticker, after int64) ([]*futuapi.Ticker, bool) {
+	seen := make(map[int64]bool, len(tickers))
+	var list []*futuapi.Ticker
+	byDisConn := false
+	for _, t := range tickers {
+		if t == nil || t.Sequence <= after || seen[t.Sequence] {
+			continue
+		}
+		seen[t.Sequence] = true
+		list = append(list, t)
+		if t.PushDataType == qotcommon.PushDataType_PushDataType_ByDisConn {
+			byDisConn = true
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Sequence < list[j].Sequence })
+	return list, byDisConn
+}
+
+// 两个序号之间是否没有缺口，序号不连续时无法判断，视为没有缺口
+func (s *Stream) adjacent(after int64, next int64) bool {
+	return !s.opts.Contiguous || next == after+1
+}
+
+// 获取证券最近的历史逐笔，按序号排序
+func (s *Stream) fetch(ctx context.Context, sec *futuapi.Security) ([]*futuapi.Ticker, error) {
+	if err := s.api.WaitRateLimit(ctx, futuapi.ProtoIDQotGetTicker); err != nil {
+		return nil, err
+	}
+	rt, err := s.api.GetRTTicker(ctx, sec, s.opts.Backfill)
+	if err != nil || rt == nil {
+		return nil, err
+	}
+	list, _ := s.fresh(rt.Tickers, 0)
+	return list, nil
+}
+
+// 更新已输出序号后输出缺口和逐笔，发送时不持有 mu，调用时需要持有 proc
+func (s *Stream) emit(ctx context.Context, sec *futuapi.Security, list []*futuapi.Ticker, gap *GapError) error {
+	if len(list) > 0 {
+		s.mu.Lock()
+		s.last[*sec] = list[len(list)-1].Sequence
+		s.mu.Unlock()
+	}
+	if gap != nil {
+		select {
+		case s.out <- &futuapi.UpdateTickerResp{Ticker: &futuapi.RTTicker{Security: sec}, Err: gap}:
+		case <-ctx.Done():
+			return futuapi.ErrInterrupted
+		}
+	}
+	if len(list) == 0 {
+		return nil
+	}
+	select {
+	case s.out <- &futuapi.UpdateTickerResp{Ticker: &futuapi.RTTicker{Security: sec, Tickers: list}}:
+		return nil
+	case <-ctx.Done():
+		return futuapi.ErrInterrupted
+	}
+}
+
+// 从推送通道读取逐笔并输出，调用方需要订阅逐笔推送，输出通道在返回时不关闭。
+// 补数出错时输出 *GapError 后继续运行。ctx 结束时返回 ErrInterrupted，通道关闭时返回 ErrChannelClosed。
+func (s *Stream) Run(ctx context.Context, ch <-chan *futuapi.UpdateTickerResp) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return futuapi.ErrInterrupted
+		case resp, ok := <-ch:
+			if !ok {
+				return futuapi.ErrChannelClosed
+			}
+			if resp.Err != nil {
+				continue
+			}
+			if err := s.Process(ctx, resp.Ticker); err != nil {
+				return err
+			}
+		}
+	}
+}
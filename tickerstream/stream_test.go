@@ -0,0 +1,173 @@
+package tickerstream
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hurisheng/go-futu-api/pb/qotcommon"
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+)
+
+func TestStreamDedup(t *testing.T) {
+	sec := &futuapi.Security{Market: qotcommon.QotMarket_QotMarket_HK_Security, Code: "00700"}
+	tick := func(seq int64) *futuapi.Ticker {
+		return &futuapi.Ticker{Sequence: seq, PushDataType: qotcommon.PushDataType_PushDataType_Realtime}
+	}
+	s := New(nil, &Options{Buffer: 10})
+	ctx := context.Background()
+	// 首次推送的缓存数据与之后的实时推送重叠，乱序的逐笔按序号输出
+	if err := s.Process(ctx, &futuapi.RTTicker{Security: sec, Tickers: []*futuapi.Ticker{tick(3), tick(1), tick(2), tick(2)}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Process(ctx, &futuapi.RTTicker{Security: sec, Tickers: []*futuapi.Ticker{tick(2), tick(3)}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Process(ctx, &futuapi.RTTicker{Security: sec, Tickers: []*futuapi.Ticker{tick(3), tick(5), tick(4)}}); err != nil {
+		t.Fatal(err)
+	}
+	var seqs []int64
+	for len(s.Tickers()) > 0 {
+		resp := <-s.Tickers()
+		if resp.Err != nil {
+			t.Fatalf("unexpected error %v", resp.Err)
+		}
+		for _, tk := range resp.Ticker.Tickers {
+			seqs = append(seqs, tk.Sequence)
+		}
+	}
+	if len(seqs) != 5 {
+		t.Fatalf("sequences %v", seqs)
+	}
+	for i, seq := range seqs {
+		if seq != int64(i+1) {
+			t.Fatalf("sequences %v", seqs)
+		}
+	}
+	if s.Last(sec) != 5 {
+		t.Errorf("last %d", s.Last(sec))
+	}
+}
+
+func seqs(t *testing.T, s *Stream) ([]int64, []*GapError) {
+	var list []int64
+	var gaps []*GapError
+	for len(s.Tickers()) > 0 {
+		resp := <-s.Tickers()
+		if resp.Err != nil {
+			gap, ok := resp.Err.(*GapError)
+			if !ok {
+				t.Fatalf("unexpected error %v", resp.Err)
+			}
+			gaps = append(gaps, gap)
+			continue
+		}
+		for _, tk := range resp.Ticker.Tickers {
+			list = append(list, tk.Sequence)
+		}
+	}
+	return list, gaps
+}
+
+func equal(a []int64, b ...int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestStreamBackfill(t *testing.T) {
+	sec := &futuapi.Security{Market: qotcommon.QotMarket_QotMarket_HK_Security, Code: "00700"}
+	tick := func(seq int64, typ qotcommon.PushDataType) *futuapi.Ticker {
+		return &futuapi.Ticker{Sequence: seq, PushDataType: typ}
+	}
+	realtime, byDisConn := qotcommon.PushDataType_PushDataType_Realtime, qotcommon.PushDataType_PushDataType_ByDisConn
+	var history []*futuapi.Ticker
+	var historyErr error
+	calls := 0
+	s := New(nil, &Options{Contiguous: true, Buffer: 20})
+	s.history = func(ctx context.Context, sec *futuapi.Security) ([]*futuapi.Ticker, error) {
+		calls++
+		return history, historyErr
+	}
+	ctx := context.Background()
+	push := func(tickers ...*futuapi.Ticker) {
+		if err := s.Process(ctx, &futuapi.RTTicker{Security: sec, Tickers: tickers}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	push(tick(1, realtime), tick(2, realtime))
+	if got, _ := seqs(t, s); !equal(got, 1, 2) || calls != 0 {
+		t.Fatalf("initial %v, %d history calls", got, calls)
+	}
+
+	// 断线补充推送触发补数，缺少的逐笔按序号补齐
+	for i := int64(1); i <= 6; i++ {
+		history = append(history, tick(i, realtime))
+	}
+	push(tick(6, byDisConn), tick(5, byDisConn))
+	if got, gaps := seqs(t, s); !equal(got, 3, 4, 5, 6) || len(gaps) != 0 || calls != 1 {
+		t.Errorf("after reconnect %v, gaps %v, %d history calls", got, gaps, calls)
+	}
+
+	// 序号不相邻时补数，历史数据没有覆盖到已输出的序号时输出缺口
+	history = []*futuapi.Ticker{tick(9, realtime), tick(10, realtime)}
+	push(tick(11, realtime))
+	got, gaps := seqs(t, s)
+	if !equal(got, 9, 10, 11) || len(gaps) != 1 || gaps[0].After != 6 || gaps[0].Before != 9 {
+		t.Errorf("after gap %v, gaps %+v", got, gaps)
+	}
+
+	// 补数失败时仍输出推送的逐笔，并报告缺口
+	historyErr = errors.New("timeout")
+	push(tick(14, realtime), tick(15, realtime))
+	got, gaps = seqs(t, s)
+	if !equal(got, 14, 15) || len(gaps) != 1 || gaps[0].After != 11 || gaps[0].Before != 14 || s.Last(sec) != 15 {
+		t.Errorf("after failed backfill %v, gaps %+v", got, gaps)
+	}
+}
+
+func TestStreamFirstPush(t *testing.T) {
+	sec := &futuapi.Security{Market: qotcommon.QotMarket_QotMarket_HK_Security, Code: "00700"}
+	tick := func(seq int64) *futuapi.Ticker {
+		return &futuapi.Ticker{Sequence: seq, PushDataType: qotcommon.PushDataType_PushDataType_Realtime}
+	}
+	history := []*futuapi.Ticker{tick(1), tick(2), tick(3), tick(4)}
+	for _, c := range []struct {
+		name   string
+		resync bool
+		want   []int64
+	}{
+		{"push only", false, []int64{3, 4, 5}},
+		{"resync first", true, []int64{1, 2, 3, 4, 5}},
+	} {
+		calls := 0
+		s := New(nil, &Options{Buffer: 10})
+		s.history = func(ctx context.Context, sec *futuapi.Security) ([]*futuapi.Ticker, error) {
+			calls++
+			return history, nil
+		}
+		ctx := context.Background()
+		if c.resync {
+			if err := s.Resync(ctx, []*futuapi.Security{sec}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// 第一批推送不补数，之前的逐笔只能由 Resync 取回
+		if err := s.Process(ctx, &futuapi.RTTicker{Security: sec, Tickers: []*futuapi.Ticker{tick(3), tick(4), tick(5)}}); err != nil {
+			t.Fatal(err)
+		}
+		got, gaps := seqs(t, s)
+		if !equal(got, c.want...) || len(gaps) != 0 {
+			t.Errorf("%s: %v, gaps %v", c.name, got, gaps)
+		}
+		if c.resync && calls != 1 || !c.resync && calls != 0 {
+			t.Errorf("%s: %d history calls", c.name, calls)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package futuapi
+
+import (
+	"context"
+	"errors"
+	"time"
+	_ "time/tzdata" //内置时区数据，运行环境没有 tzdata 时美股时间仍然正确处理夏令时
+
+	"github.com/hurisheng/go-futu-api/pb/qotcommon"
+	"github.com/hurisheng/go-futu-api/pb/trdcommon"
+)
+
+// 接口返回和请求参数中时间字符串的格式
+const TimeLayout = "2006-01-02 15:04:05"
+
+// 解析时间字符串支持的格式
+var timeLayouts = []string{
+	"2006-01-02 15:04:05.000",
+	TimeLayout,
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+var ErrTimeFormat = errors.New("time format error")
+
+var (
+	locationHK = loadLocation("Asia/Hong_Kong")
+	locationUS = loadLocation("America/New_York")
+	locationCN = loadLocation("Asia/Shanghai")
+	locationSG = loadLocation("Asia/Singapore")
+	locationJP = loadLocation("Asia/Tokyo")
+)
+
+// 加载时区，使用内置的时区数据，不会失败
+func loadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return loc
+}
+
+// 行情市场所在交易所的时区，未知市场为 UTC
+func MarketLocation(market qotcommon.QotMarket) *time.Location {
+	switch market {
+	case qotcommon.QotMarket_QotMarket_HK_Security, qotcommon.QotMarket_QotMarket_HK_Future:
+		return locationHK
+	case qotcommon.QotMarket_QotMarket_US_Security:
+		return locationUS
+	case qotcommon.QotMarket_QotMarket_CNSH_Security, qotcommon.QotMarket_QotMarket_CNSZ_Security:
+		return locationCN
+	case qotcommon.QotMarket_QotMarket_SG_Security:
+		return locationSG
+	case qotcommon.QotMarket_QotMarket_JP_Security:
+		return locationJP
+	}
+	return time.UTC
+}
+
+// 交易证券市场所在交易所的时区，未知市场为 UTC
+func TrdSecMarketLocation(market trdcommon.TrdSecMarket) *time.Location {
+	switch market {
+	case trdcommon.TrdSecMarket_TrdSecMarket_HK:
+		return locationHK
+	case trdcommon.TrdSecMarket_TrdSecMarket_US:
+		return locationUS
+	case trdcommon.TrdSecMarket_TrdSecMarket_CN_SH, trdcommon.TrdSecMarket_TrdSecMarket_CN_SZ:
+		return locationCN
+	case trdcommon.TrdSecMarket_TrdSecMarket_SG:
+		return locationSG
+	case trdcommon.TrdSecMarket_TrdSecMarket_JP:
+		return locationJP
+	}
+	return time.UTC
+}
+
+// 交易市场的时区，A股通按香港时间，未知市场为 UTC。
+// 期货账户可以交易香港、美国、新加坡、日本等交易所的期货，TrdMarket 不区分交易所，期货市场一律按香港时间；
+// 订单、成交等带有证券市场的数据应使用 TrdSecMarketLocation
+func TrdMarketLocation(market trdcommon.TrdMarket) *time.Location {
+	switch market {
+	case trdcommon.TrdMarket_TrdMarket_HK, trdcommon.TrdMarket_TrdMarket_HKCC, trdcommon.TrdMarket_TrdMarket_Futures:
+		return locationHK
+	case trdcommon.TrdMarket_TrdMarket_US:
+		return locationUS
+	case trdcommon.TrdMarket_TrdMarket_CN:
+		return locationCN
+	}
+	return time.UTC
+}
+
+// 证券所在交易所的时区
+func (s *Security) Location() *time.Location {
+	if s == nil {
+		return time.UTC
+	}
+	return MarketLocation(s.Market)
+}
+
+// 按时区 loc 解析时间字符串，支持带毫秒、到秒、到分钟和只有日期的格式
+func ParseTime(s string, loc *time.Location) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if len(s) != len(layout) {
+			continue
+		}
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, ErrTimeFormat
+}
+
+// 把时间转换到时区 loc 后格式化为请求参数使用的字符串，零值为空字符串
+func FormatTime(t time.Time, loc *time.Location) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.In(loc).Format(TimeLayout)
+}
+
+// 接口返回的时间，优先使用时间戳 ts，没有时间戳时按时区 loc 解析时间字符串 s，都没有时为零值
+func TimeOf(s string, ts float64, loc *time.Location) time.Time {
+	if ts > 0 {
+		sec := int64(ts)
+		return time.Unix(sec, int64((ts-float64(sec))*1e9)).In(loc)
+	}
+	t, _ := ParseTime(s, loc)
+	return t
+}
+
+// K 线时间，K 线不带证券信息，需要传入证券所在交易所的时区，如 Security.Location()
+func (k *KLine) TimeIn(loc *time.Location) time.Time {
+	return TimeOf(k.Time, k.Timestamp, loc)
+}
+
+// 分时时间
+func (t *TimeShare) TimeIn(loc *time.Location) time.Time {
+	return TimeOf(t.Time, t.Timestamp, loc)
+}
+
+// 逐笔成交时间
+func (t *Ticker) TimeIn(loc *time.Location) time.Time {
+	return TimeOf(t.Time, t.Timestamp, loc)
+}
+
+// 除权除息日
+func (r *Rehab) TimeIn(loc *time.Location) time.Time {
+	return TimeOf(r.Time, r.Timestamp, loc)
+}
+
+// 交易日
+func (d *TradeDate) TimeIn(loc *time.Location) time.Time {
+	return TimeOf(d.Time, d.Timestamp, loc)
+}
+
+// 资金流向的开始时间
+func (c *CapitalFlowItem) TimeIn(loc *time.Location) time.Time {
+	return TimeOf(c.Time, c.Timestamp, loc)
+}
+
+// 最新价的更新时间
+func (b *BasicQot) UpdatedAt() time.Time {
+	return TimeOf(b.UpdateTime, b.UpdateTimestamp, b.Security.Location())
+}
+
+// 上市日期
+func (b *BasicQot) ListedAt() time.Time {
+	return TimeOf(b.ListTime, b.ListTimestamp, b.Security.Location())
+}
+
+// 快照的更新时间
+func (b *SnapshotBasicData) UpdatedAt() time.Time {
+	return TimeOf(b.UpdateTime, b.UpdateTimestamp, b.Security.Location())
+}
+
+// 上市时间
+func (b *SnapshotBasicData) ListedAt() time.Time {
+	return TimeOf(b.ListTime, b.ListTimestamp, b.Security.Location())
+}
+
+// 订单创建时间
+func (o *Order) CreatedAt() time.Time {
+	return TimeOf(o.CreateTime, o.CreateTimestamp, TrdSecMarketLocation(o.SecMarket))
+}
+
+// 订单最后更新时间
+func (o *Order) UpdatedAt() time.Time {
+	return TimeOf(o.UpdateTime, o.UpdateTimestamp, TrdSecMarketLocation(o.SecMarket))
+}
+
+// 成交时间
+func (f *OrderFill) CreatedAt() time.Time {
+	return TimeOf(f.CreateTime, f.CreateTimestamp, TrdSecMarketLocation(f.SecMarket))
+}
+
+// 按交易市场的时区设置过滤的开始和结束时间，零值表示不设置，期货市场按香港时间，见 TrdMarketLocation
+func (c *TrdFilterConditions) SetTimeRange(begin time.Time, end time.Time, market trdcommon.TrdMarket) {
+	loc := TrdMarketLocation(market)
+	c.Begin = FormatTime(begin, loc)
+	c.End = FormatTime(end, loc)
+}
+
+// 按证券所在交易所的时区设置开始和结束时间
+func (r *HistoryKLineRequest) SetTimeRange(begin time.Time, end time.Time) {
+	loc := r.Security.Location()
+	r.Begin = FormatTime(begin, loc)
+	r.End = FormatTime(end, loc)
+}
+
+// 同 RequestHistoryKLine，开始和结束时间按证券所在交易所的时区转换
+func (api *FutuAPI) RequestHistoryKLineByTime(ctx context.Context, security *Security, begin time.Time, end time.Time, klType qotcommon.KLType, rehabType qotcommon.RehabType,
+	maxNum int32, fields qotcommon.KLFields, nextKey []byte, extTime bool) (*HistoryKLine, error) {
+	loc := security.Location()
+	return api.RequestHistoryKLine(ctx, security, FormatTime(begin, loc), FormatTime(end, loc), klType, rehabType, maxNum, fields, nextKey, extTime)
+}
@@ -0,0 +1,79 @@
+package futuapi
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hurisheng/go-futu-api/pb/qotcommon"
+)
+
+func TestParseTime(t *testing.T) {
+	hk := MarketLocation(qotcommon.QotMarket_QotMarket_HK_Security)
+	cases := []struct {
+		s    string
+		want time.Time
+	}{
+		{"2024-03-20 09:30:00.250", time.Date(2024, 3, 20, 9, 30, 0, int(250*time.Millisecond), hk)},
+		{"2024-03-20 09:30:01", time.Date(2024, 3, 20, 9, 30, 1, 0, hk)},
+		{"2024-03-20 09:30", time.Date(2024, 3, 20, 9, 30, 0, 0, hk)},
+		{"2024-03-20", time.Date(2024, 3, 20, 0, 0, 0, 0, hk)},
+	}
+	for _, c := range cases {
+		if got, err := ParseTime(c.s, hk); err != nil || !got.Equal(c.want) || got.Location() != hk {
+			t.Errorf("ParseTime(%q) = %v, %v", c.s, got, err)
+		}
+	}
+	for _, s := range []string{"", "2024/03/20", "2024-03-20T09:30:00", "2024-13-20"} {
+		if got, err := ParseTime(s, hk); err != ErrTimeFormat || !got.IsZero() {
+			t.Errorf("ParseTime(%q) = %v, %v", s, got, err)
+		}
+	}
+}
+
+func TestFormatTime(t *testing.T) {
+	hk := MarketLocation(qotcommon.QotMarket_QotMarket_HK_Security)
+	at := time.Date(2024, 3, 20, 1, 30, 0, int(250*time.Millisecond), time.UTC)
+	if s := FormatTime(at, hk); s != "2024-03-20 09:30:00" {
+		t.Errorf("FormatTime = %q", s)
+	}
+	if s := FormatTime(time.Time{}, hk); s != "" {
+		t.Errorf("FormatTime(zero) = %q", s)
+	}
+	if got, err := ParseTime(FormatTime(at, hk), hk); err != nil || !got.Equal(at.Truncate(time.Second)) {
+		t.Errorf("round trip %v, %v", got, err)
+	}
+}
+
+func TestTimeOf(t *testing.T) {
+	hk := MarketLocation(qotcommon.QotMarket_QotMarket_HK_Security)
+	want := time.Date(2024, 3, 20, 9, 30, 0, int(500*time.Millisecond), hk)
+	ts := float64(want.Unix()) + 0.5
+	// 时间戳优先于时间字符串
+	if got := TimeOf("2000-01-01 00:00:00", ts, hk); !got.Equal(want) || got.Location() != hk {
+		t.Errorf("TimeOf with timestamp = %v", got)
+	}
+	if got := TimeOf("2024-03-20 09:30:00.500", 0, hk); !got.Equal(want) {
+		t.Errorf("TimeOf with string = %v", got)
+	}
+	if got := TimeOf("bad", 0, hk); !got.IsZero() {
+		t.Errorf("TimeOf(bad) = %v", got)
+	}
+	k := &KLine{Time: "2024-03-20 09:30:00"}
+	if got := k.TimeIn(hk); !got.Equal(want.Truncate(time.Second)) {
+		t.Errorf("KLine.TimeIn = %v", got)
+	}
+}
+
+func TestMarketLocation(t *testing.T) {
+	if loc := (*Security)(nil).Location(); loc != time.UTC {
+		t.Errorf("nil security location %v", loc)
+	}
+	// 使用内置时区数据，美东夏令时期间为 UTC-4
+	us := MarketLocation(qotcommon.QotMarket_QotMarket_US_Security)
+	if _, offset := time.Date(2024, 7, 1, 12, 0, 0, 0, us).Zone(); offset != -4*3600 {
+		t.Errorf("summer offset %d", offset)
+	}
+	if _, offset := time.Date(2024, 1, 2, 12, 0, 0, 0, us).Zone(); offset != -5*3600 {
+		t.Errorf("winter offset %d", offset)
+	}
+}
@@ -0,0 +1,33 @@
+package volsurface
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+)
+
+// 以 JSON 格式保存到文件，先写临时文件再替换，避免写入中断损坏原文件
+func (s *Snapshot) Save(path string) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// 从文件读取保存的曲面
+func Load(path string) (*Snapshot, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s Snapshot
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
@@ -0,0 +1,154 @@
+// Package volsurface 根据期权链构建标的的隐含波动率曲面，
+// 可以按行权日×行权价或行权日×Delta 查询和插值，并根据买卖盘和基本行情推送实时更新。
+package volsurface
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/hurisheng/go-futu-api/pb/qotcommon"
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+)
+
+// 期权价格的来源
+type PriceSource string
+
+const (
+	SourceMid  PriceSource = "mid"  //买卖中间价，来自快照或买卖盘
+	SourceLast PriceSource = "last" //最新成交价，来自快照或基本行情，不活跃的期权可能已经过时
+)
+
+// 曲面上的一个点，同一行权价取价外期权（行权价低于标的价格取看跌，否则取看涨）
+type Point struct {
+	Security *futuapi.Security    `json:"security"`
+	Type     qotcommon.OptionType `json:"type"`
+	Strike   float64              `json:"strike"`
+	Price    float64              `json:"price"`  //计算使用的期权价格
+	Source   PriceSource          `json:"source"` //期权价格的来源
+	IV       float64              `json:"iv"`     //隐含波动率，0.2 表示20%
+	Delta    float64              `json:"delta"`  //同一行权价看涨期权的 Black-Scholes Delta，随行权价递减
+}
+
+// 一个行权日的波动率微笑，Points 按行权价升序
+type Smile struct {
+	Expiry string   `json:"expiry"` //行权日，YYYY-MM-DD
+	T      float64  `json:"t"`      //距离到期的年数
+	Points []*Point `json:"points"`
+}
+
+// 按行权价线性插值，超出范围时取最近的点，没有点时返回0
+func (s *Smile) AtStrike(strike float64) float64 {
+	return interp(s.Points, strike, func(p *Point) float64 { return p.Strike })
+}
+
+// 按看涨期权 Delta 线性插值（如 0.25 为25 Delta 看涨，0.75 相当于25 Delta 看跌），超出范围时取最近的点
+func (s *Smile) AtDelta(delta float64) float64 {
+	// Delta 随行权价递减，反转后按升序插值
+	n := len(s.Points)
+	rev := make([]*Point, n)
+	for i, p := range s.Points {
+		rev[n-1-i] = p
+	}
+	return interp(rev, delta, func(p *Point) float64 { return p.Delta })
+}
+
+// 按 key 升序的点线性插值
+func interp(points []*Point, x float64, key func(*Point) float64) float64 {
+	if len(points) == 0 {
+		return 0
+	}
+	i := sort.Search(len(points), func(i int) bool { return key(points[i]) >= x })
+	switch {
+	case i == 0:
+		return points[0].IV
+	case i == len(points):
+		return points[len(points)-1].IV
+	}
+	lo, hi := points[i-1], points[i]
+	x0, x1 := key(lo), key(hi)
+	if x1 == x0 {
+		return hi.IV
+	}
+	return lo.IV + (hi.IV-lo.IV)*(x-x0)/(x1-x0)
+}
+
+// 某一时刻的波动率曲面，Smiles 按行权日升序
+type Snapshot struct {
+	Owner  *futuapi.Security `json:"owner"`
+	Spot   float64           `json:"spot"`
+	Time   time.Time         `json:"time"`
+	Smiles []*Smile          `json:"smiles"`
+}
+
+// 行权日的波动率微笑，不存在时返回空
+func (s *Snapshot) Smile(expiry string) *Smile {
+	for _, sm := range s.Smiles {
+		if sm.Expiry == expiry {
+			return sm
+		}
+	}
+	return nil
+}
+
+// 距离到期 t 年、行权价为 strike 的隐含波动率。
+// 行权日之间按总方差（IV²×T）对时间线性插值，超出范围时取最近的行权日。
+func (s *Snapshot) IV(t float64, strike float64) float64 {
+	return s.between(t, func(sm *Smile) float64 { return sm.AtStrike(strike) })
+}
+
+// 距离到期 t 年、看涨期权 Delta 为 delta 的隐含波动率，插值方法同 IV
+func (s *Snapshot) IVByDelta(t float64, delta float64) float64 {
+	return s.between(t, func(sm *Smile) float64 { return sm.AtDelta(delta) })
+}
+
+func (s *Snapshot) between(t float64, at func(*Smile) float64) float64 {
+	var smiles []*Smile
+	for _, sm := range s.Smiles {
+		if len(sm.Points) > 0 && sm.T > 0 {
+			smiles = append(smiles, sm)
+		}
+	}
+	if len(smiles) == 0 {
+		return 0
+	}
+	i := sort.Search(len(smiles), func(i int) bool { return smiles[i].T >= t })
+	switch {
+	case i == 0:
+		return at(smiles[0])
+	case i == len(smiles):
+		return at(smiles[len(smiles)-1])
+	}
+	lo, hi := smiles[i-1], smiles[i]
+	v0, v1 := at(lo), at(hi)
+	w0, w1 := v0*v0*lo.T, v1*v1*hi.T
+	w := w0 + (w1-w0)*(t-lo.T)/(hi.T-lo.T)
+	if w <= 0 || t <= 0 {
+		return v0
+	}
+	return math.Sqrt(w / t)
+}
+
+// 行权日×行权价的波动率矩阵，行与 Smiles 对应，列与 strikes 对应
+func (s *Snapshot) Grid(strikes []float64) [][]float64 {
+	grid := make([][]float64, len(s.Smiles))
+	for i, sm := range s.Smiles {
+		grid[i] = make([]float64, len(strikes))
+		for j, k := range strikes {
+			grid[i][j] = sm.AtStrike(k)
+		}
+	}
+	return grid
+}
+
+// 行权日×Delta 的波动率矩阵，行与 Smiles 对应，列与 deltas 对应
+func (s *Snapshot) DeltaGrid(deltas []float64) [][]float64 {
+	grid := make([][]float64, len(s.Smiles))
+	for i, sm := range s.Smiles {
+		grid[i] = make([]float64, len(deltas))
+		for j, d := range deltas {
+			grid[i][j] = sm.AtDelta(d)
+		}
+	}
+	return grid
+}
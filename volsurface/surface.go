@@ -0,0 +1,238 @@
+package volsurface
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hurisheng/go-futu-api/pb/qotcommon"
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+	"github.com/woxinyoumeng/go-futu-api/pricing"
+)
+
+// 实时波动率曲面，由期权表初始化。期权价格根据期权的买卖盘推送取买卖中间价，
+// 根据基本行情推送取最新价，以数据时间较新的为准；标的价格根据标的的基本行情推送更新
+type Surface struct {
+	mu      sync.RWMutex
+	owner   *futuapi.Security
+	cfg     *pricing.Config
+	spot    float64
+	options map[futuapi.Security]*option
+	updated time.Time
+}
+
+type option struct {
+	quote  *futuapi.OptionQuote
+	price  float64
+	source PriceSource
+	at     time.Time //价格的数据时间
+}
+
+// 由期权表创建波动率曲面，期权价格取快照的买卖中间价，spot 为标的价格
+func New(table *futuapi.OptionTable, spot float64, cfg *pricing.Config) *Surface {
+	s := &Surface{
+		owner:   table.Owner,
+		cfg:     cfg,
+		spot:    spot,
+		options: make(map[futuapi.Security]*option, len(table.Quotes)),
+		updated: time.Now(),
+	}
+	for _, q := range table.Quotes {
+		o := &option{quote: q, price: pricing.MidPrice(q.Snapshot), source: SourceLast}
+		if b := q.Snapshot; b != nil && b.Basic != nil {
+			if b.Basic.BidPrice > 0 && b.Basic.AskPrice >= b.Basic.BidPrice {
+				o.source = SourceMid
+			}
+			o.at = b.Basic.UpdatedAt()
+		}
+		s.options[*q.Security()] = o
+	}
+	return s
+}
+
+// 曲面使用的期权代码，用于订阅买卖盘和基本行情推送
+func (s *Surface) Securities() []*futuapi.Security {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	list := make([]*futuapi.Security, 0, len(s.options))
+	for _, o := range s.options {
+		list = append(list, o.quote.Security())
+	}
+	return list
+}
+
+// 设置标的价格
+func (s *Surface) SetSpot(spot float64) {
+	s.mu.Lock()
+	s.spot = spot
+	s.updated = time.Now()
+	s.mu.Unlock()
+}
+
+// 处理基本行情，标的更新标的价格；期权的行情时间不早于当前价格的数据时间时使用最新价，其他证券忽略。
+// 行情没有更新时间时按收到的时间
+func (s *Surface) Update(qots []*futuapi.BasicQot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, q := range qots {
+		if q == nil || q.Security == nil || q.CurPrice <= 0 {
+			continue
+		}
+		if s.owner != nil && *q.Security == *s.owner {
+			s.spot = q.CurPrice
+		} else if o := s.options[*q.Security]; o != nil {
+			at := q.UpdatedAt()
+			if at.IsZero() {
+				at = time.Now()
+			}
+			if at.Before(o.at) {
+				continue
+			}
+			o.price, o.source, o.at = q.CurPrice, SourceLast, at
+		} else {
+			continue
+		}
+		s.updated = time.Now()
+	}
+}
+
+// 处理期权的买卖盘，买一卖一都有效，且数据时间不早于当前价格的数据时间时更新买卖中间价，其他证券忽略。
+// 数据时间取服务器收到买卖盘的较晚时间，没有时按收到的时间
+func (s *Surface) UpdateOrderBook(rt *futuapi.RTOrderBook) {
+	if rt == nil || rt.Security == nil || len(rt.Bids) == 0 || len(rt.Asks) == 0 || rt.Bids[0] == nil || rt.Asks[0] == nil {
+		return
+	}
+	bid, ask := rt.Bids[0].Price, rt.Asks[0].Price
+	if bid <= 0 || ask < bid {
+		return
+	}
+	loc := rt.Security.Location()
+	at := futuapi.TimeOf(rt.SvrRecvTimeBid, rt.SvrRecvTimeBidTimestamp, loc)
+	if t := futuapi.TimeOf(rt.SvrRecvTimeAsk, rt.SvrRecvTimeAskTimestamp, loc); t.After(at) {
+		at = t
+	}
+	if at.IsZero() {
+		at = time.Now()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if o := s.options[*rt.Security]; o != nil && !at.Before(o.at) {
+		o.price, o.source, o.at = (bid+ask)/2, SourceMid, at
+		s.updated = time.Now()
+	}
+}
+
+// 最后更新时间
+func (s *Surface) Updated() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.updated
+}
+
+// 从推送通道读取基本行情和买卖盘更新曲面，不需要的通道传 nil，调用方需要订阅标的的基本行情和期权的买卖盘。
+// ctx 结束时返回 ErrInterrupted，通道全部关闭时返回 ErrChannelClosed。
+func (s *Surface) Run(ctx context.Context, qots <-chan *futuapi.UpdateBasicQotResp, books <-chan *futuapi.UpdateOrderBookResp) error {
+	for qots != nil || books != nil {
+		select {
+		case <-ctx.Done():
+			return futuapi.ErrInterrupted
+		case resp, ok := <-qots:
+			if !ok {
+				qots = nil
+				continue
+			}
+			if resp.Err == nil {
+				s.Update(resp.BasicQot)
+			}
+		case resp, ok := <-books:
+			if !ok {
+				books = nil
+				continue
+			}
+			if resp.Err == nil {
+				s.UpdateOrderBook(resp.OrderBook)
+			}
+		}
+	}
+	return futuapi.ErrChannelClosed
+}
+
+// 计算 now 时刻的波动率曲面，无法求出隐含波动率的期权不在曲面中
+func (s *Surface) Snapshot(now time.Time) *Snapshot {
+	s.mu.RLock()
+	spot := s.spot
+	quotes := make([]option, 0, len(s.options))
+	for _, o := range s.options {
+		quotes = append(quotes, *o)
+	}
+	s.mu.RUnlock()
+
+	snap := &Snapshot{Owner: s.owner, Spot: spot, Time: now}
+	// 每个行权日、行权价只保留价外期权，价外期权无解时使用另一方向
+	type slot struct {
+		otm, itm *Point
+	}
+	smiles := make(map[string]*Smile)
+	slots := make(map[string]map[float64]*slot)
+	for _, o := range quotes {
+		p, t, ok := s.point(o, spot, now)
+		if !ok {
+			continue
+		}
+		exp := o.quote.Expiry
+		if smiles[exp] == nil {
+			smiles[exp] = &Smile{Expiry: exp, T: t}
+			slots[exp] = make(map[float64]*slot)
+		}
+		sl := slots[exp][p.Strike]
+		if sl == nil {
+			sl = &slot{}
+			slots[exp][p.Strike] = sl
+		}
+		otm := (p.Type == qotcommon.OptionType_OptionType_Put) == (p.Strike < spot)
+		if otm {
+			sl.otm = p
+		} else {
+			sl.itm = p
+		}
+	}
+	for exp, sm := range smiles {
+		for _, sl := range slots[exp] {
+			if sl.otm != nil {
+				sm.Points = append(sm.Points, sl.otm)
+			} else {
+				sm.Points = append(sm.Points, sl.itm)
+			}
+		}
+		sort.Slice(sm.Points, func(i, j int) bool { return sm.Points[i].Strike < sm.Points[j].Strike })
+		snap.Smiles = append(snap.Smiles, sm)
+	}
+	sort.Slice(snap.Smiles, func(i, j int) bool { return snap.Smiles[i].Expiry < snap.Smiles[j].Expiry })
+	return snap
+}
+
+func (s *Surface) point(o option, spot float64, now time.Time) (*Point, float64, bool) {
+	if o.price <= 0 {
+		return nil, 0, false
+	}
+	params, err := pricing.ParamsFor(o.quote, spot, now, s.cfg)
+	if err != nil {
+		return nil, 0, false
+	}
+	vol, err := pricing.ImpliedVol(pricing.ModelFor(o.quote, s.cfg), o.price, params)
+	if err != nil {
+		return nil, 0, false
+	}
+	params.Vol = vol
+	params.Call = true
+	return &Point{
+		Security: o.quote.Security(),
+		Type:     o.quote.Type,
+		Strike:   o.quote.Strike,
+		Price:    o.price,
+		Source:   o.source,
+		IV:       vol,
+		Delta:    pricing.BlackScholes{}.Greeks(params).Delta,
+	}, params.T, true
+}
@@ -0,0 +1,140 @@
+package volsurface
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hurisheng/go-futu-api/pb/qotcommon"
+	futuapi "github.com/woxinyoumeng/go-futu-api"
+	"github.com/woxinyoumeng/go-futu-api/pricing"
+)
+
+func testTable(now time.Time, spot float64, vol func(t, strike float64) float64) *futuapi.OptionTable {
+	owner := &futuapi.Security{Market: qotcommon.QotMarket_QotMarket_US_Security, Code: "XYZ"}
+	table := &futuapi.OptionTable{Owner: owner, Quotes: make(map[futuapi.OptionKey]*futuapi.OptionQuote)}
+	for _, expiry := range []string{"2024-04-19", "2024-06-21"} {
+		table.Expiries = append(table.Expiries, expiry)
+		for _, strike := range []float64{90, 100, 110} {
+			for _, typ := range []qotcommon.OptionType{qotcommon.OptionType_OptionType_Call, qotcommon.OptionType_OptionType_Put} {
+				sec := &futuapi.Security{Market: owner.Market, Code: fmt.Sprintf("XYZ%s%d%v", expiry, typ, strike)}
+				q := &futuapi.OptionQuote{
+					OptionKey: futuapi.OptionKey{Expiry: expiry, Strike: strike, Type: typ},
+					Info:      &futuapi.SecurityStaticInfo{Basic: &futuapi.SecurityStaticBasic{Security: sec, LotSize: 100}},
+				}
+				p, _ := pricing.ParamsFor(q, spot, now, nil)
+				p.Vol = vol(p.T, strike)
+				price := pricing.BlackScholes{}.Price(p)
+				q.Snapshot = &futuapi.Snapshot{
+					Basic:        &futuapi.SnapshotBasicData{Security: sec, BidPrice: price, AskPrice: price},
+					OptionExData: &futuapi.OptionSnapshotExData{OptionAreaType: qotcommon.OptionAreaType_OptionAreaType_European},
+				}
+				table.Quotes[q.OptionKey] = q
+			}
+		}
+	}
+	return table
+}
+
+func TestSurface(t *testing.T) {
+	now := time.Date(2024, 3, 20, 10, 0, 0, 0, futuapi.MarketLocation(qotcommon.QotMarket_QotMarket_US_Security))
+	smile := func(_, strike float64) float64 { return 0.2 + 0.001*math.Abs(strike-100) }
+	table := testTable(now, 100, smile)
+	s := New(table, 100, nil)
+	snap := s.Snapshot(now)
+	if len(snap.Smiles) != 2 || len(snap.Smiles[0].Points) != 3 {
+		t.Fatalf("snapshot %+v", snap)
+	}
+	near := snap.Smiles[0]
+	if p := near.Points[0]; p.Type != qotcommon.OptionType_OptionType_Put || math.Abs(p.IV-0.21) > 1e-6 {
+		t.Errorf("90 strike point %+v", p)
+	}
+	if iv := near.AtStrike(95); math.Abs(iv-0.205) > 1e-6 {
+		t.Errorf("AtStrike(95) = %v", iv)
+	}
+	if iv := near.AtDelta(near.Points[1].Delta); math.Abs(iv-0.2) > 1e-6 {
+		t.Errorf("AtDelta = %v", iv)
+	}
+	if iv := snap.IV((near.T+snap.Smiles[1].T)/2, 110); math.Abs(iv-0.21) > 1e-6 {
+		t.Errorf("IV between expiries = %v", iv)
+	}
+
+	s.Update([]*futuapi.BasicQot{{Security: table.Owner, CurPrice: 105}})
+	if snap := s.Snapshot(now); snap.Spot != 105 || snap.Smiles[0].Points[1].Type != qotcommon.OptionType_OptionType_Put {
+		t.Errorf("after spot update %+v", snap.Smiles[0].Points[1])
+	}
+
+	dir, err := ioutil.TempDir("", "volsurface")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "surface.json")
+	if err := snap.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *loaded.Owner != *snap.Owner || len(loaded.Smiles) != 2 || loaded.IV(0.1, 95) != snap.IV(0.1, 95) {
+		t.Errorf("loaded %+v", loaded)
+	}
+}
+
+func TestSurfacePriceSource(t *testing.T) {
+	now := time.Date(2024, 3, 20, 10, 0, 0, 0, futuapi.MarketLocation(qotcommon.QotMarket_QotMarket_US_Security))
+	table := testTable(now, 100, func(_, _ float64) float64 { return 0.2 })
+	q := table.Get("2024-04-19", 110, qotcommon.OptionType_OptionType_Call)
+	mid := q.Snapshot.Basic.BidPrice
+	q.Snapshot.Basic.UpdateTime = "2024-03-20 10:00:00"
+	// 没有买卖价的期权使用最新价
+	illiquid := table.Get("2024-04-19", 90, qotcommon.OptionType_OptionType_Put)
+	illiquid.Snapshot.Basic.BidPrice, illiquid.Snapshot.Basic.AskPrice, illiquid.Snapshot.Basic.CurPrice = 0, 0, 0.5
+	s := New(table, 100, nil)
+	point := func(strike float64) *Point {
+		for _, p := range s.Snapshot(now).Smiles[0].Points {
+			if p.Strike == strike {
+				return p
+			}
+		}
+		return nil
+	}
+	if p := point(110); p == nil || p.Price != mid || p.Source != SourceMid {
+		t.Errorf("snapshot point %+v", p)
+	}
+	if p := point(90); p == nil || p.Price != 0.5 || p.Source != SourceLast {
+		t.Errorf("illiquid point %+v", p)
+	}
+
+	steps := []struct {
+		name   string
+		qot    string //基本行情的更新时间，为空时处理买卖盘
+		book   string //买卖盘的服务器接收时间
+		price  float64
+		want   float64
+		source PriceSource
+	}{
+		{name: "older quote", qot: "2024-03-20 09:59:59", price: mid * 2, want: mid, source: SourceMid},
+		{name: "newer quote replaces snapshot", qot: "2024-03-20 10:00:05", price: mid * 2, want: mid * 2, source: SourceLast},
+		{name: "newer order book", book: "2024-03-20 10:00:10.000", price: mid + 0.1, want: mid + 0.1, source: SourceMid},
+		{name: "quote older than book", qot: "2024-03-20 10:00:07", price: mid * 3, want: mid + 0.1, source: SourceMid},
+		{name: "book older than quote", qot: "2024-03-20 10:00:12", price: mid * 3, want: mid * 3, source: SourceLast},
+		{name: "stale order book", book: "2024-03-20 10:00:11.000", price: mid, want: mid * 3, source: SourceLast},
+	}
+	for _, c := range steps {
+		if c.qot != "" {
+			s.Update([]*futuapi.BasicQot{{Security: q.Security(), CurPrice: c.price, UpdateTime: c.qot}})
+		} else {
+			s.UpdateOrderBook(&futuapi.RTOrderBook{Security: q.Security(), SvrRecvTimeBid: c.book,
+				Bids: []*futuapi.OrderBook{{Price: c.price - 0.1}}, Asks: []*futuapi.OrderBook{{Price: c.price + 0.1}}})
+		}
+		if p := point(110); p == nil || math.Abs(p.Price-c.want) > 1e-9 || p.Source != c.source {
+			t.Errorf("%s: point %+v", c.name, p)
+		}
+	}
+}